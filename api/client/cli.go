@@ -39,7 +39,8 @@ func (cli *DockerCli) getMethod(name string) (func(...string) error, bool) {
 	if len(name) == 0 {
 		return nil, false
 	}
-	methodName := "Cmd" + strings.ToUpper(name[:1]) + strings.ToLower(name[1:])
+	// storage-selftest is served by CmdStorageSelftest
+	methodName := "Cmd" + strings.Replace(strings.Title(strings.ToLower(name)), "-", "", -1)
 	method := reflect.ValueOf(cli).MethodByName(methodName)
 	if !method.IsValid() {
 		return nil, false
@@ -85,6 +86,11 @@ func NewDockerCli(in io.ReadCloser, out, err io.Writer, proto, addr string, tlsC
 		scheme     = "http"
 	)
 
+	// the ssh transport already encrypts the connection
+	if proto == "ssh" {
+		tlsConfig = nil
+	}
+
 	if tlsConfig != nil {
 		scheme = "https"
 	}
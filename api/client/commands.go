@@ -29,6 +29,7 @@ import (
 	"github.com/docker/docker/nat"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/log"
+	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/filters"
 	"github.com/docker/docker/pkg/signal"
@@ -59,6 +60,7 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"build", "Build an image from a Dockerfile"},
 		{"commit", "Create a new image from a container's changes"},
 		{"cp", "Copy files/folders from a container's filesystem to the host path"},
+		{"device", "Allow or deny the access of a running container to a device"},
 		{"diff", "Inspect changes on a container's filesystem"},
 		{"events", "Get real time events from the server"},
 		{"export", "Stream the contents of a container as a tar archive"},
@@ -67,11 +69,15 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"import", "Create a new filesystem image from the contents of a tarball"},
 		{"info", "Display system-wide information"},
 		{"inspect", "Return low-level information on a container"},
+		{"io", "Display the I/O of a running container by block device"},
 		{"kill", "Kill a running container"},
+		{"limit", "Change the resource limits of one or more running containers"},
 		{"load", "Load an image from a tar archive"},
+		{"lock", "Take the advisory lock of a volume of a running container"},
 		{"login", "Register or log in to a Docker registry server"},
 		{"logout", "Log out from a Docker registry server"},
 		{"logs", "Fetch the logs of a container"},
+		{"onbuild", "List or change the ONBUILD triggers of an image"},
 		{"port", "Lookup the public-facing port that is NAT-ed to PRIVATE_PORT"},
 		{"pause", "Pause all processes within a container"},
 		{"ps", "List containers"},
@@ -85,13 +91,19 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"search", "Search for an image on the Docker Hub"},
 		{"start", "Start a stopped container"},
 		{"stop", "Stop a running container"},
+		{"storage-selftest", "Test and benchmark the storage driver of the daemon"},
 		{"tag", "Tag an image into a repository"},
+		{"throttling", "Display how often the CPU quota of a running container throttled it"},
 		{"top", "Lookup the running processes of a container"},
+		{"trace", "Trace the processes of a running container with strace or perf"},
+		{"transfer", "Send an image to another Docker daemon without a registry"},
+		{"unlock", "Release the advisory lock of a volume of a container"},
 		{"unpause", "Unpause a paused container"},
 		{"version", "Show the Docker version information"},
 		{"wait", "Block until a container stops, then print its exit code"},
+		{"watch", "Stream the changes of the files of a running container"},
 	} {
-		help += fmt.Sprintf("    %-10.10s%s\n", command[0], command[1])
+		help += fmt.Sprintf("    %-18.18s%s\n", command[0], command[1])
 	}
 	fmt.Fprintf(cli.err, "%s\n", help)
 	return nil
@@ -104,6 +116,12 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 	noCache := cmd.Bool([]string{"#no-cache", "-no-cache"}, false, "Do not use cache when building the image")
 	rm := cmd.Bool([]string{"#rm", "-rm"}, true, "Remove intermediate containers after a successful build")
 	forceRm := cmd.Bool([]string{"-force-rm"}, false, "Always remove intermediate containers, even after unsuccessful builds")
+	flSecrets := opts.NewListOpts(nil)
+	cmd.Var(&flSecrets, []string{"-secret"}, "Mount a file at /run/secrets/NAME in the RUN instructions without storing it in the image (format: [NAME=]PATH)")
+	sshAgent := cmd.Bool([]string{"-ssh"}, false, "Forward the SSH agent of SSH_AUTH_SOCK to the RUN instructions, the Docker daemon must run on the same host")
+	flOutput := cmd.String([]string{"-output"}, "", "Write the filesystem of the image instead of keeping it (type=tar,dest=FILE or type=local,dest=DIR[,src=PATH])")
+	parallel := cmd.Int([]string{"-parallel"}, 1, "Number of the images of a Dockerfile with several FROM instructions built at the same time")
+	network := cmd.String([]string{"-network"}, "bridge", "Set the networking mode of the RUN instructions\n'bridge': creates a new network stack for each RUN instruction\n'none': no networking\n'host': use the host network stack")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
@@ -112,6 +130,43 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		return nil
 	}
 
+	var output *buildOutput
+	if *flOutput != "" {
+		var err error
+		if output, err = parseBuildOutput(*flOutput); err != nil {
+			return err
+		}
+	}
+
+	secrets := make(map[string][]byte)
+	for _, secret := range flSecrets.GetAll() {
+		name, file := filepath.Base(secret), secret
+		if parts := strings.SplitN(secret, "=", 2); len(parts) == 2 {
+			name, file = parts[0], parts[1]
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("Error reading the secret %s: %s", name, err)
+		}
+		secrets[name] = data
+	}
+
+	var sshAgentSock string
+	if *sshAgent {
+		if sshAgentSock = os.Getenv("SSH_AUTH_SOCK"); sshAgentSock == "" {
+			return fmt.Errorf("Cannot forward the SSH agent, SSH_AUTH_SOCK is not set")
+		}
+		// the daemon mounts the socket, it must be on its host
+		if cli.proto != "unix" {
+			return fmt.Errorf("Cannot forward the SSH agent to a daemon listening on %s://%s", cli.proto, cli.addr)
+		}
+		abs, err := filepath.Abs(sshAgentSock)
+		if err != nil {
+			return err
+		}
+		sshAgentSock = abs
+	}
+
 	var (
 		context  archive.Archive
 		isRemote bool
@@ -209,7 +264,12 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		}
 	}
 
-	v.Set("t", *tag)
+	imageName := *tag
+	if output != nil && imageName == "" {
+		// the image is only kept until its filesystem is written
+		imageName = "build-output-" + utils.GenerateRandomID()[:12]
+	}
+	v.Set("t", imageName)
 
 	if *suppressOutput {
 		v.Set("q", "1")
@@ -230,6 +290,14 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		v.Set("forcerm", "1")
 	}
 
+	if sshAgentSock != "" {
+		v.Set("sshagent", sshAgentSock)
+	}
+	v.Set("network", *network)
+	if *parallel > 1 {
+		v.Set("parallel", strconv.Itoa(*parallel))
+	}
+
 	cli.LoadConfigFile()
 
 	headers := http.Header(make(map[string][]string))
@@ -239,10 +307,23 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 	}
 	headers.Add("X-Registry-Config", base64.URLEncoding.EncodeToString(buf))
 
+	if len(secrets) > 0 {
+		buf, err := json.Marshal(secrets)
+		if err != nil {
+			return err
+		}
+		headers.Add("X-Build-Secrets", base64.URLEncoding.EncodeToString(buf))
+	}
+
 	if context != nil {
 		headers.Set("Content-Type", "application/tar")
 	}
-	err = cli.stream("POST", fmt.Sprintf("/build?%s", v.Encode()), body, cli.out, headers)
+	out := cli.out
+	if output != nil && output.dest == "-" {
+		// the tar archive of the image goes to STDOUT
+		out = cli.err
+	}
+	err = cli.stream("POST", fmt.Sprintf("/build?%s", v.Encode()), body, out, headers)
 	if jerr, ok := err.(*utils.JSONError); ok {
 		// If no error code is set, default to 1
 		if jerr.Code == 0 {
@@ -250,9 +331,115 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		}
 		return &utils.StatusError{Status: jerr.Message, StatusCode: jerr.Code}
 	}
+	if err != nil || output == nil {
+		return err
+	}
+
+	err = cli.writeBuildOutput(imageName, output)
+	if *tag == "" {
+		if _, _, rmErr := readBody(cli.call("DELETE", "/images/"+imageName, nil, false)); rmErr != nil && err == nil {
+			err = rmErr
+		}
+	}
 	return err
 }
 
+// buildOutput is where docker build --output writes the filesystem of the
+// image it built.
+type buildOutput struct {
+	typ  string // "tar" or "local"
+	dest string // the tar file, - for STDOUT, or the directory
+	src  string // the path of the image copied into the directory
+}
+
+func parseBuildOutput(s string) (*buildOutput, error) {
+	output := &buildOutput{src: "/"}
+	for _, field := range strings.Split(s, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid output %s, expected type=tar,dest=FILE or type=local,dest=DIR[,src=PATH]", s)
+		}
+		switch parts[0] {
+		case "type":
+			output.typ = parts[1]
+		case "dest":
+			output.dest = parts[1]
+		case "src":
+			output.src = parts[1]
+		default:
+			return nil, fmt.Errorf("Invalid output option %s", parts[0])
+		}
+	}
+	switch output.typ {
+	case "tar", "local":
+	default:
+		return nil, fmt.Errorf("Invalid output type %q, must be tar or local", output.typ)
+	}
+	if output.dest == "" {
+		return nil, fmt.Errorf("The output of type %s needs a dest", output.typ)
+	}
+	if output.typ == "tar" && output.src != "/" {
+		return nil, fmt.Errorf("The output of type tar is the whole filesystem, it takes no src")
+	}
+	if output.typ == "local" && output.dest == "-" {
+		return nil, fmt.Errorf("The output of type local needs a directory")
+	}
+	return output, nil
+}
+
+// writeBuildOutput writes the filesystem of image to output, through a
+// container which is never started.
+func (cli *DockerCli) writeBuildOutput(image string, output *buildOutput) error {
+	// the image may have no command of its own
+	config := &runconfig.Config{Image: image, Cmd: []string{"/bin/true"}}
+	stream, _, err := cli.call("POST", "/containers/create", config, false)
+	if err != nil {
+		return err
+	}
+	var createResult engine.Env
+	if err := createResult.Decode(stream); err != nil {
+		return err
+	}
+	id := createResult.Get("Id")
+	defer readBody(cli.call("DELETE", "/containers/"+id, nil, false))
+
+	if output.typ == "tar" {
+		dest := cli.out
+		if output.dest != "-" {
+			f, err := os.Create(output.dest)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			dest = f
+		}
+		return cli.stream("GET", "/containers/"+id+"/export", nil, dest, nil)
+	}
+
+	var statusCode int
+	if path.Clean(output.src) == "/" {
+		// a copy of / would be a directory named after the mountpoint
+		stream, statusCode, err = cli.call("GET", "/containers/"+id+"/export", nil, false)
+	} else {
+		var copyData engine.Env
+		copyData.Set("Resource", output.src)
+		stream, statusCode, err = cli.call("POST", "/containers/"+id+"/copy", copyData, false)
+	}
+	if stream != nil {
+		defer stream.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if statusCode != 200 {
+		return fmt.Errorf("Cannot copy %s of the image: status %d", output.src, statusCode)
+	}
+	if err := os.MkdirAll(output.dest, 0755); err != nil {
+		return err
+	}
+	return archive.Untar(stream, output.dest, &archive.TarOptions{NoLchown: true})
+}
+
 // 'docker login': login / register a user to registry service.
 func (cli *DockerCli) CmdLogin(args ...string) error {
 	cmd := cli.Subcmd("login", "[OPTIONS] [SERVER]", "Register or log in to a Docker registry server, if no server is specified \""+registry.IndexServerAddress()+"\" is the default.")
@@ -448,6 +635,9 @@ func (cli *DockerCli) CmdVersion(args ...string) error {
 	}
 	fmt.Fprintf(cli.out, "Go version (server): %s\n", remoteVersion.Get("GoVersion"))
 	fmt.Fprintf(cli.out, "Git commit (server): %s\n", remoteVersion.Get("GitCommit"))
+	if features := remoteVersion.GetList("Features"); remoteVersion.GetBool("Experimental") || len(features) > 0 {
+		fmt.Fprintf(cli.out, "Experimental features (server): %s\n", strings.Join(features, ", "))
+	}
 	return nil
 }
 
@@ -492,8 +682,33 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 	fmt.Fprintf(cli.out, "Execution Driver: %s\n", remoteInfo.Get("ExecutionDriver"))
 	fmt.Fprintf(cli.out, "Kernel Version: %s\n", remoteInfo.Get("KernelVersion"))
 	fmt.Fprintf(cli.out, "Operating System: %s\n", remoteInfo.Get("OperatingSystem"))
+	var startupTime float64
+	if err := remoteInfo.GetJson("StartupTime", &startupTime); err == nil && startupTime > 0 {
+		fmt.Fprintf(cli.out, "Startup Time: %.2fs\n", startupTime)
+	}
+	if remoteInfo.GetBool("Experimental") {
+		fmt.Fprintf(cli.out, "Experimental: true\n")
+	}
+	if features := remoteInfo.GetList("Features"); len(features) > 0 {
+		fmt.Fprintf(cli.out, "Feature Gates: %s\n", strings.Join(features, ", "))
+	}
 
 	if remoteInfo.GetBool("Debug") || os.Getenv("DEBUG") != "" {
+		var steps []struct {
+			Name     string
+			Seconds  float64
+			Deferred bool
+		}
+		if err := remoteInfo.GetJson("StartupSteps", &steps); err == nil && len(steps) > 0 {
+			fmt.Fprintf(cli.out, "Startup Steps:\n")
+			for _, step := range steps {
+				deferred := ""
+				if step.Deferred {
+					deferred = " (deferred)"
+				}
+				fmt.Fprintf(cli.out, " %s: %.2fs%s\n", step.Name, step.Seconds, deferred)
+			}
+		}
 		fmt.Fprintf(cli.out, "Debug mode (server): %v\n", remoteInfo.GetBool("Debug"))
 		fmt.Fprintf(cli.out, "Debug mode (client): %v\n", os.Getenv("DEBUG") != "")
 		fmt.Fprintf(cli.out, "Fds: %d\n", remoteInfo.GetInt("NFd"))
@@ -555,6 +770,63 @@ func (cli *DockerCli) CmdStop(args ...string) error {
 	return encounteredError
 }
 
+func (cli *DockerCli) CmdStorageSelftest(args ...string) error {
+	cmd := cli.Subcmd("storage-selftest", "[OPTIONS]", "Test and benchmark the storage driver of the daemon with throwaway layers")
+	flLayers := cmd.Int([]string{"-layers"}, 10, "Number of layers to create on top of each other")
+	flConcurrency := cmd.Int([]string{"-concurrency"}, 8, "Number of concurrent mounts of the layers")
+	flSize := cmd.String([]string{"-size"}, "64m", "Data to write into the top layer (format: <number><optional unit>, where unit = b, k, m or g)")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+	size, err := units.RAMInBytes(*flSize)
+	if err != nil {
+		return err
+	}
+
+	v := url.Values{}
+	v.Set("layers", strconv.Itoa(*flLayers))
+	v.Set("concurrency", strconv.Itoa(*flConcurrency))
+	v.Set("size", strconv.FormatInt(size, 10))
+
+	body, _, err := readBody(cli.call("POST", "/storage/selftest?"+v.Encode(), nil, false))
+	if err != nil {
+		return err
+	}
+	report := &engine.Env{}
+	if err := report.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	var results []struct {
+		Operation string
+		Count     int
+		Duration  time.Duration
+		Bytes     int64
+	}
+	if err := report.GetJson("Results", &results); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "Storage Driver: %s\n", report.Get("Driver"))
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tCOUNT\tTOTAL\tAVERAGE\tTHROUGHPUT")
+	for _, result := range results {
+		throughput := ""
+		if result.Bytes > 0 && result.Duration > 0 {
+			throughput = units.HumanSize(int64(float64(result.Bytes)/result.Duration.Seconds())) + "/s"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", result.Operation, result.Count, result.Duration, result.Duration/time.Duration(result.Count), throughput)
+	}
+	w.Flush()
+	for _, warning := range report.GetList("Warnings") {
+		fmt.Fprintf(cli.err, "WARNING: %s\n", warning)
+	}
+	return nil
+}
+
 func (cli *DockerCli) CmdRestart(args ...string) error {
 	cmd := cli.Subcmd("restart", "[OPTIONS] CONTAINER [CONTAINER...]", "Restart a running container")
 	nSeconds := cmd.Int([]string{"t", "-time"}, 10, "Number of seconds to try to stop for before killing the container. Once killed it will then be restarted. Default is 10 seconds.")
@@ -616,6 +888,7 @@ func (cli *DockerCli) CmdStart(args ...string) error {
 		cmd       = cli.Subcmd("start", "CONTAINER [CONTAINER...]", "Restart a stopped container")
 		attach    = cmd.Bool([]string{"a", "-attach"}, false, "Attach container's STDOUT and STDERR and forward all signals to the process")
 		openStdin = cmd.Bool([]string{"i", "-interactive"}, false, "Attach container's STDIN")
+		waitReady = cmd.Bool([]string{"-wait"}, false, "Wait for the containers started with --notify to be ready")
 	)
 
 	if err := cmd.Parse(args); err != nil {
@@ -669,6 +942,9 @@ func (cli *DockerCli) CmdStart(args ...string) error {
 	var encounteredError error
 	for _, name := range cmd.Args() {
 		_, _, err := readBody(cli.call("POST", "/containers/"+name+"/start", nil, false))
+		if err == nil && *waitReady {
+			_, _, err = readBody(cli.call("POST", "/containers/"+name+"/wait?condition=ready", nil, false))
+		}
 		if err != nil {
 			if !*attach || !*openStdin {
 				fmt.Fprintf(cli.err, "%s\n", err)
@@ -766,13 +1042,40 @@ func (cli *DockerCli) CmdInspect(args ...string) error {
 		}
 	}
 
+	// the containers are inspected in a single request, with only the
+	// fields the template reads
+	v := url.Values{}
+	for _, name := range cmd.Args() {
+		v.Add("name", name)
+	}
+	if tmpl != nil {
+		if fields, ok := templateFields(tmpl); ok {
+			v["fields"] = fields
+		}
+	}
+	body, _, err := readBody(cli.call("GET", "/containers/inspect?"+v.Encode(), nil, false))
+	if err != nil {
+		return err
+	}
+	var containers []json.RawMessage
+	if err := json.Unmarshal(body, &containers); err != nil {
+		return err
+	}
+	if len(containers) != cmd.NArg() {
+		return fmt.Errorf("Error: expected %d containers to be inspected, got %d", cmd.NArg(), len(containers))
+	}
+
 	indented := new(bytes.Buffer)
 	indented.WriteByte('[')
 	status := 0
 
-	for _, name := range cmd.Args() {
-		obj, _, err := readBody(cli.call("GET", "/containers/"+name+"/json", nil, false))
-		if err != nil {
+	for i, name := range cmd.Args() {
+		obj := []byte(containers[i])
+		var inspectErr struct{ Error string }
+		if err := json.Unmarshal(obj, &inspectErr); err != nil {
+			return err
+		}
+		if inspectErr.Error != "" {
 			obj, _, err = readBody(cli.call("GET", "/images/"+name+"/json", nil, false))
 			if err != nil {
 				if strings.Contains(err.Error(), "No such") {
@@ -860,6 +1163,115 @@ func (cli *DockerCli) CmdTop(args ...string) error {
 	return nil
 }
 
+func (cli *DockerCli) CmdIo(args ...string) error {
+	cmd := cli.Subcmd("io", "CONTAINER", "Display the I/O of a running container by block device, along with its rw layer and volumes on each device")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/containers/"+cmd.Arg(0)+"/io", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tREAD\tWRITE\tREAD OPS\tWRITE OPS\tUSED BY")
+	for _, out := range outs.Data {
+		usedBy := out.GetList("Volumes")
+		if out.GetBool("RootFs") {
+			usedBy = append([]string{"rw layer"}, usedBy...)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n",
+			out.Get("Device"),
+			units.HumanSize(out.GetInt64("Read")),
+			units.HumanSize(out.GetInt64("Write")),
+			out.GetInt64("ReadOps"),
+			out.GetInt64("WriteOps"),
+			strings.Join(usedBy, ", "))
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) CmdThrottling(args ...string) error {
+	cmd := cli.Subcmd("throttling", "CONTAINER", "Display how often the CPU quota of a running container throttled it")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	stream, _, err := cli.call("GET", "/containers/"+cmd.Arg(0)+"/throttling", nil, false)
+	if err != nil {
+		return err
+	}
+	var out engine.Env
+	if err := out.Decode(stream); err != nil {
+		return err
+	}
+
+	var (
+		periods   = out.GetInt64("Periods")
+		throttled = out.GetInt64("ThrottledPeriods")
+		ratio     float64
+		quota     = "unlimited"
+	)
+	if periods > 0 {
+		ratio = float64(throttled) * 100 / float64(periods)
+	}
+	if q, p := out.GetInt64("Quota"), out.GetInt64("Period"); p == 0 {
+		quota = "n/a"
+	} else if q > 0 {
+		quota = fmt.Sprintf("%dus per %dus", q, p)
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "PERIODS\tTHROTTLED PERIODS\tTHROTTLED TIME\tCPU QUOTA")
+	fmt.Fprintf(w, "%d\t%d (%.1f%%)\t%s\t%s\n", periods, throttled, ratio, time.Duration(out.GetInt64("ThrottledTime")), quota)
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) CmdTrace(args ...string) error {
+	cmd := cli.Subcmd("trace", "[OPTIONS] CONTAINER", "Trace the processes of a running container with strace or perf from the toolbox image of the daemon, and write the output as a tar archive (streamed to STDOUT by default)")
+	tool := cmd.String([]string{"-tool"}, "strace", "Tracing tool, strace or perf")
+	seconds := cmd.Int([]string{"t", "-time"}, 10, "Number of seconds to trace for (at most 300)")
+	outfile := cmd.String([]string{"o", "-output"}, "", "Write to a file, instead of STDOUT")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var (
+		output io.Writer = cli.out
+		err    error
+	)
+	if *outfile != "" {
+		output, err = os.Create(*outfile)
+		if err != nil {
+			return err
+		}
+	}
+
+	v := url.Values{}
+	v.Set("tool", *tool)
+	v.Set("duration", strconv.Itoa(*seconds))
+	return cli.stream("POST", "/containers/"+cmd.Arg(0)+"/trace?"+v.Encode(), nil, output, nil)
+}
+
 func (cli *DockerCli) CmdPort(args ...string) error {
 	cmd := cli.Subcmd("port", "CONTAINER PRIVATE_PORT", "Lookup the public-facing port that is NAT-ed to PRIVATE_PORT")
 	if err := cmd.Parse(args); err != nil {
@@ -953,6 +1365,67 @@ func (cli *DockerCli) CmdRmi(args ...string) error {
 	return encounteredError
 }
 
+func (cli *DockerCli) CmdOnbuild(args ...string) error {
+	cmd := cli.Subcmd("onbuild", "[OPTIONS] IMAGE [REPOSITORY[:TAG]]", "List the ONBUILD triggers of an image, or create a new image with changed triggers")
+	flStrip := cmd.Bool([]string{"-strip"}, false, "Remove the triggers of the image")
+	flAdd := opts.NewListOpts(nil)
+	cmd.Var(&flAdd, []string{"-add"}, "Add a trigger after the ones of the image (e.g. --add='RUN make')")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 || cmd.NArg() > 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	if !*flStrip && flAdd.Len() == 0 {
+		if cmd.NArg() > 1 {
+			return fmt.Errorf("Error: a repository can only be given along with --strip or --add")
+		}
+		body, _, err := readBody(cli.call("GET", "/images/"+cmd.Arg(0)+"/onbuild", nil, false))
+		if err != nil {
+			return err
+		}
+		outs := engine.NewTable("", 0)
+		if _, err := outs.ReadListFrom(body); err != nil {
+			return err
+		}
+		for _, out := range outs.Data {
+			fmt.Fprintln(cli.out, out.Get("Trigger"))
+		}
+		return nil
+	}
+
+	repository, tag := parsers.ParseRepositoryTag(cmd.Arg(1))
+	//Check if the given image name can be resolved
+	if repository != "" {
+		if _, _, err := registry.ResolveRepositoryName(repository); err != nil {
+			return err
+		}
+	}
+
+	v := url.Values{}
+	v.Set("repo", repository)
+	v.Set("tag", tag)
+	if *flStrip {
+		v.Set("strip", "1")
+	}
+	for _, trigger := range flAdd.GetAll() {
+		v.Add("add", trigger)
+	}
+
+	stream, _, err := cli.call("POST", "/images/"+cmd.Arg(0)+"/onbuild?"+v.Encode(), nil, false)
+	if err != nil {
+		return err
+	}
+	var env engine.Env
+	if err := env.Decode(stream); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", env.Get("Id"))
+	return nil
+}
+
 func (cli *DockerCli) CmdHistory(args ...string) error {
 	cmd := cli.Subcmd("history", "[OPTIONS] IMAGE", "Show the history of an image")
 	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only show numeric IDs")
@@ -1074,8 +1547,255 @@ func (cli *DockerCli) CmdKill(args ...string) error {
 	return encounteredError
 }
 
+func (cli *DockerCli) CmdLimit(args ...string) error {
+	cmd := cli.Subcmd("limit", "[OPTIONS] [CONTAINER...]", "Change the resource limits of one or more running containers")
+	flMemory := cmd.String([]string{"m", "-memory"}, "", "Memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+	flMemorySwap := cmd.String([]string{"-memory-swap"}, "", "Total memory usage (memory + swap) limit (format: <number><optional unit>, where unit = b, k, m or g), -1 for unlimited")
+	flMemoryReservation := cmd.String([]string{"-memory-reservation"}, "", "Memory soft limit (format: <number><optional unit>, where unit = b, k, m or g)")
+	flKernelMemory := cmd.String([]string{"-kernel-memory"}, "", "Kernel memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+	flKernelMemoryTCP := cmd.String([]string{"-kernel-memory-tcp"}, "", "Kernel memory limit for TCP buffers (format: <number><optional unit>, where unit = b, k, m or g)")
+	flSwappiness := cmd.Int64([]string{"-memory-swappiness"}, -1, "Tendency to swap out the memory of the container (0-100)")
+	flOomKillDisable := cmd.Bool([]string{"-oom-kill-disable"}, false, "Pause the container instead of killing its processes when it runs out of memory, --oom-kill-disable=false enables the OOM killer again")
+	flCpuShares := cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
+	flCpuQuota := cmd.Int64([]string{"-cpu-quota"}, 0, "CPU time the container gets per CFS period in microseconds, -1 for unlimited")
+	flCpuPeriod := cmd.Int64([]string{"-cpu-period"}, 0, "Length of the CFS period in microseconds (1000-1000000)")
+	flCpuBurst := cmd.Int64([]string{"-cpu-burst"}, 0, "Unused CPU quota the container accumulates for bursts in microseconds, -1 to disable")
+	flCpuRtRuntime := cmd.Int64([]string{"-cpu-rt-runtime"}, 0, "Real-time CPU time the container gets per period in microseconds")
+	flCpuRtPeriod := cmd.Int64([]string{"-cpu-rt-period"}, 0, "Length of the real-time period in microseconds (1000-1000000)")
+	flCpuset := cmd.String([]string{"-cpuset"}, "", "CPUs in which to allow execution (0-3, 0,1)")
+	flCpusetMems := cmd.String([]string{"-cpuset-mems"}, "", "Memory nodes in which to allow allocations (0-3, 0,1)")
+	flPidsLimit := cmd.Int64([]string{"-pids-limit"}, 0, "Maximum number of processes, -1 for unlimited")
+	flBlkioWeight := cmd.Int64([]string{"-blkio-weight"}, 0, "Relative weight of the block IO of the container (10-1000)")
+	flDeviceReadIOps := opts.NewListOpts(opts.ValidateThrottleIOps)
+	cmd.Var(&flDeviceReadIOps, []string{"-device-read-iops"}, "Limit read rate (IO per second) from a device (e.g. --device-read-iops=/dev/sda:1000)")
+	flDeviceWriteIOps := opts.NewListOpts(opts.ValidateThrottleIOps)
+	cmd.Var(&flDeviceWriteIOps, []string{"-device-write-iops"}, "Limit write rate (IO per second) to a device (e.g. --device-write-iops=/dev/sda:1000)")
+	flHugetlbLimits := opts.NewListOpts(opts.ValidateHugetlbLimit)
+	cmd.Var(&flHugetlbLimits, []string{"-hugetlb-limit"}, "Limit huge pages usage for a page size (e.g. --hugetlb-limit=2MB:64m)")
+	flNetClsClassid := cmd.String([]string{"-net-cls-classid"}, "", "Class id of the container's traffic for tc filters (e.g. --net-cls-classid=10:1)")
+	flNetPrio := opts.NewListOpts(opts.ValidateNetPrio)
+	cmd.Var(&flNetPrio, []string{"-net-prio"}, "Set the priority of the container's traffic on a host network interface (e.g. --net-prio=eth0:5)")
+	flSave := cmd.Bool([]string{"-save"}, false, "Keep the new limits when the container is restarted")
+	flPersistOnly := cmd.Bool([]string{"-persist-only"}, false, "Only save the new limits, for the container to get them when it restarts")
+	flCgroup := cmd.String([]string{"-cgroup"}, "", "Change the limits of this child cgroup of the container instead, relative to its cgroup (e.g. --cgroup=worker)")
+	flDryRun := cmd.Bool([]string{"-dry-run"}, false, "Print the cgroup file writes of the new limits without making them")
+	flForce := cmd.Bool([]string{"-force"}, false, "Set a memory limit above the memory of the host or overcommitting it, and reclaim the memory of a container over its new memory limit")
+	flFilter := opts.NewListOpts(nil)
+	cmd.Var(&flFilter, []string{"f", "-filter"}, "Change the limits of the running containers matching the filters instead. Valid filters:\nimage=<image> - containers running <image>\nname=<prefix> - containers whose name starts with <prefix>")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if (cmd.NArg() < 1) == (len(flFilter.GetAll()) == 0) {
+		cmd.Usage()
+		return nil
+	}
+	limitFilterArgs := filters.Args{}
+	for _, f := range flFilter.GetAll() {
+		var err error
+		if limitFilterArgs, err = filters.ParseFlag(f, limitFilterArgs); err != nil {
+			return err
+		}
+	}
+
+	// the limits are sent as a JSON body, the sizes parsed here
+	limits := make(map[string]interface{})
+	for param, size := range map[string]string{
+		"memory":            *flMemory,
+		"memoryReservation": *flMemoryReservation,
+		"memorySwap":        *flMemorySwap,
+		"kernelMemory":      *flKernelMemory,
+		"kernelMemoryTCP":   *flKernelMemoryTCP,
+	} {
+		// a change of the current limit, +256m, is resolved by the daemon
+		if size != "-1" && (strings.HasPrefix(size, "+") || strings.HasPrefix(size, "-")) {
+			limits[param] = size
+			continue
+		}
+		value, err := units.RAMLimitInBytes(size)
+		if err != nil {
+			return err
+		}
+		if value != 0 {
+			limits[param] = value
+		}
+	}
+	if *flSwappiness != -1 {
+		limits["memorySwappiness"] = *flSwappiness
+	}
+	oomKillDisableFlag := cmd.Lookup("-oom-kill-disable")
+	cmd.Visit(func(f *flag.Flag) {
+		if f == oomKillDisableFlag {
+			limits["oomKillDisable"] = *flOomKillDisable
+		}
+	})
+	for param, value := range map[string]int64{
+		"cpuShares":    *flCpuShares,
+		"cpuQuota":     *flCpuQuota,
+		"cpuPeriod":    *flCpuPeriod,
+		"cpuBurst":     *flCpuBurst,
+		"cpuRtRuntime": *flCpuRtRuntime,
+		"cpuRtPeriod":  *flCpuRtPeriod,
+		"pidsLimit":    *flPidsLimit,
+		"blkioWeight":  *flBlkioWeight,
+	} {
+		if value != 0 {
+			limits[param] = value
+		}
+	}
+	for param, value := range map[string]string{
+		"cpuset":        *flCpuset,
+		"cpusetMems":    *flCpusetMems,
+		"netClsClassid": *flNetClsClassid,
+		"cgroup":        *flCgroup,
+	} {
+		if value != "" {
+			limits[param] = value
+		}
+	}
+	for param, specs := range map[string][]string{
+		"blkioDeviceReadIOps":  flDeviceReadIOps.GetAll(),
+		"blkioDeviceWriteIOps": flDeviceWriteIOps.GetAll(),
+		"hugetlbLimit":         flHugetlbLimits.GetAll(),
+		"netPrio":              flNetPrio.GetAll(),
+	} {
+		if len(specs) != 0 {
+			limits[param] = specs
+		}
+	}
+	if *flSave {
+		limits["save"] = true
+	}
+	if *flPersistOnly {
+		limits["persistOnly"] = true
+	}
+	if *flDryRun {
+		limits["dryRun"] = true
+	}
+	if *flForce {
+		limits["force"] = true
+	}
+
+	// the limits of all the containers are changed in a single call
+	v := url.Values{}
+	for _, name := range cmd.Args() {
+		v.Add("name", name)
+	}
+	if len(limitFilterArgs) != 0 {
+		filterJson, err := filters.ToParam(limitFilterArgs)
+		if err != nil {
+			return err
+		}
+		v.Set("filters", filterJson)
+	}
+	body, _, err := readBody(cli.call("POST", "/containers/limit?"+v.Encode(), limits, false))
+	if err != nil {
+		return err
+	}
+	results := engine.NewTable("", 0)
+	if _, err := results.ReadListFrom(body); err != nil {
+		return err
+	}
+	var (
+		encounteredError error
+		writes           = engine.NewTable("", 0)
+	)
+	for _, result := range results.Data {
+		if errMsg := result.Get("Error"); errMsg != "" {
+			fmt.Fprintf(cli.err, "%s\n", errMsg)
+			encounteredError = fmt.Errorf("Error: failed to change the limits of one or more containers")
+		} else if *flDryRun {
+			if _, err := writes.ReadListFrom([]byte(result.Get("Output"))); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", result.Get("Name"))
+		}
+	}
+	if !*flDryRun {
+		return encounteredError
+	}
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "FILE\tVALUE\tVIA")
+	for _, write := range writes.Data {
+		via := "file"
+		if write.GetBool("Systemd") {
+			via = "systemd"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", write.Get("File"), write.Get("Value"), via)
+	}
+	w.Flush()
+	return encounteredError
+}
+
+func (cli *DockerCli) CmdLock(args ...string) error {
+	cmd := cli.Subcmd("lock", "[OPTIONS] CONTAINER PATH", "Take the advisory lock of the volume at PATH in a running container, released by docker unlock or when the container stops")
+	flTimeout := cmd.Int([]string{"t", "-timeout"}, 0, "Number of seconds to wait for another container to release the lock")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	v := url.Values{}
+	v.Set("path", cmd.Arg(1))
+	v.Set("timeout", strconv.Itoa(*flTimeout))
+	if _, _, err := readBody(cli.call("POST", fmt.Sprintf("/containers/%s/lock?%s", cmd.Arg(0), v.Encode()), nil, false)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cli *DockerCli) CmdUnlock(args ...string) error {
+	cmd := cli.Subcmd("unlock", "CONTAINER PATH", "Release the advisory lock of the volume at PATH in a container")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	v := url.Values{}
+	v.Set("path", cmd.Arg(1))
+	if _, _, err := readBody(cli.call("POST", fmt.Sprintf("/containers/%s/unlock?%s", cmd.Arg(0), v.Encode()), nil, false)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cli *DockerCli) CmdDevice(args ...string) error {
+	cmd := cli.Subcmd("device", "allow|deny [OPTIONS] CONTAINER DEVICE", "Allow or deny the access of a running container to a device of the host")
+	flPermissions := cmd.String([]string{"-permissions"}, "rwm", "Access to allow or deny: r (read), w (write) and/or m (mknod)")
+	if len(args) == 0 || (args[0] != "allow" && args[0] != "deny") {
+		cmd.Usage()
+		return nil
+	}
+	action := args[0]
+	if err := cmd.Parse(args[1:]); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	v := url.Values{}
+	v.Set("action", action)
+	v.Set("path", cmd.Arg(1))
+	v.Set("permissions", *flPermissions)
+
+	if _, _, err := readBody(cli.call("POST", fmt.Sprintf("/containers/%s/device?%s", cmd.Arg(0), v.Encode()), nil, false)); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (cli *DockerCli) CmdImport(args ...string) error {
 	cmd := cli.Subcmd("import", "URL|- [REPOSITORY[:TAG]]", "Create an empty filesystem image and import the contents of the tarball (.tar, .tar.gz, .tgz, .bzip, .tar.xz, .txz) into it, then optionally tag it.")
+	format := cmd.String([]string{"-format"}, "rootfs", "Format of the tarball: rootfs, or aci for an App Container Image")
+	configFile := cmd.String([]string{"-config"}, "", "Read the config of the image (Entrypoint, Cmd, Env, ExposedPorts...) from a JSON file")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -1093,6 +1813,19 @@ func (cli *DockerCli) CmdImport(args ...string) error {
 
 	v.Set("fromSrc", src)
 	v.Set("repo", repository)
+	if *format != "rootfs" {
+		v.Set("format", *format)
+	}
+	if *configFile != "" {
+		data, err := ioutil.ReadFile(*configFile)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &runconfig.Config{}); err != nil {
+			return fmt.Errorf("Invalid image config %s: %s", *configFile, err)
+		}
+		v.Set("config", string(data))
+	}
 
 	if cmd.NArg() == 3 {
 		fmt.Fprintf(cli.err, "[DEPRECATED] The format 'URL|- [REPOSITORY [TAG]]' as been deprecated. Please use URL|- [REPOSITORY[:TAG]]\n")
@@ -1746,6 +2479,47 @@ func (cli *DockerCli) CmdDiff(args ...string) error {
 	return nil
 }
 
+func (cli *DockerCli) CmdWatch(args ...string) error {
+	cmd := cli.Subcmd("watch", "CONTAINER", "Stream the changes of the files of a running container until it stops")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	stream, _, err := cli.call("GET", "/containers/"+cmd.Arg(0)+"/watch", nil, false)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	dec := json.NewDecoder(stream)
+	for {
+		var change struct {
+			Path string
+			Kind int
+		}
+		if err := dec.Decode(&change); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		var kind string
+		switch change.Kind {
+		case archive.ChangeModify:
+			kind = "C"
+		case archive.ChangeAdd:
+			kind = "A"
+		case archive.ChangeDelete:
+			kind = "D"
+		}
+		fmt.Fprintf(cli.out, "%s %s\n", kind, change.Path)
+	}
+}
+
 func (cli *DockerCli) CmdLogs(args ...string) error {
 	var (
 		cmd    = cli.Subcmd("logs", "CONTAINER", "Fetch the logs of a container")
@@ -1792,9 +2566,10 @@ func (cli *DockerCli) CmdLogs(args ...string) error {
 
 func (cli *DockerCli) CmdAttach(args ...string) error {
 	var (
-		cmd     = cli.Subcmd("attach", "[OPTIONS] CONTAINER", "Attach to a running container")
-		noStdin = cmd.Bool([]string{"#nostdin", "-no-stdin"}, false, "Do not attach STDIN")
-		proxy   = cmd.Bool([]string{"#sig-proxy", "-sig-proxy"}, true, "Proxy all received signals to the process (even in non-TTY mode). SIGCHLD, SIGKILL, and SIGSTOP are not proxied.")
+		cmd         = cli.Subcmd("attach", "[OPTIONS] CONTAINER", "Attach to a running container")
+		noStdin     = cmd.Bool([]string{"#nostdin", "-no-stdin"}, false, "Do not attach STDIN")
+		proxy       = cmd.Bool([]string{"#sig-proxy", "-sig-proxy"}, true, "Proxy all received signals to the process (even in non-TTY mode). SIGCHLD, SIGKILL, and SIGSTOP are not proxied.")
+		idleTimeout = cmd.Int([]string{"-idle-timeout"}, 0, "Detach once no data went through for this many seconds, 0 to never")
 	)
 
 	if err := cmd.Parse(args); err != nil {
@@ -1843,6 +2618,9 @@ func (cli *DockerCli) CmdAttach(args ...string) error {
 
 	v.Set("stdout", "1")
 	v.Set("stderr", "1")
+	if *idleTimeout > 0 {
+		v.Set("idleTimeout", strconv.Itoa(*idleTimeout))
+	}
 
 	if *proxy && !tty {
 		sigc := cli.forwardAllSignals(cmd.Arg(0))
@@ -2005,8 +2783,22 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 		flSigProxy    = cmd.Lookup("sig-proxy")
 		autoRemove, _ = strconv.ParseBool(flRm.Value.String())
 		sigProxy, _   = strconv.ParseBool(flSigProxy.Value.String())
+		resultFile    = cmd.Lookup("result-file").Value.String()
+		format        = cmd.Lookup("format").Value.String()
+		resultTmpl    *template.Template
 	)
 
+	if format != "" {
+		if config.AttachStdout || config.AttachStderr {
+			return fmt.Errorf("Conflicting options: --format can only be used in detached mode (-d)")
+		}
+		if format != "json" {
+			if resultTmpl, err = template.New("").Parse(format); err != nil {
+				return fmt.Errorf("Template parsing error: %v", err)
+			}
+		}
+	}
+
 	// Disable sigProxy in case on TTY
 	if config.Tty {
 		sigProxy = false
@@ -2084,7 +2876,7 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 		errCh         chan error
 	)
 
-	if !config.AttachStdout && !config.AttachStderr {
+	if !config.AttachStdout && !config.AttachStderr && format == "" {
 		// Make this asynchrone in order to let the client write to stdin before having to read the ID
 		waitDisplayId = make(chan struct{})
 		go func() {
@@ -2157,6 +2949,33 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 		return err
 	}
 
+	if resultFile != "" || format != "" {
+		result, err := cli.getRunOutput(runResult.Get("Id"))
+		if err != nil {
+			return err
+		}
+		if resultFile != "" {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(resultFile, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("Failed to write the result file: %s", err)
+			}
+		}
+		switch {
+		case resultTmpl != nil:
+			if err := resultTmpl.Execute(cli.out, result); err != nil {
+				return err
+			}
+			cli.out.Write([]byte{'\n'})
+		case format == "json":
+			if err := json.NewEncoder(cli.out).Encode(result); err != nil {
+				return err
+			}
+		}
+	}
+
 	if (config.AttachStdin || config.AttachStdout || config.AttachStderr) && config.Tty && cli.isTerminal {
 		if err := cli.monitorTtySize(runResult.Get("Id")); err != nil {
 			log.Errorf("Error monitoring TTY size: %s", err)
@@ -2173,7 +2992,9 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 	// Detached mode: wait for the id to be displayed and return.
 	if !config.AttachStdout && !config.AttachStderr {
 		// Detached mode
-		<-waitDisplayId
+		if waitDisplayId != nil {
+			<-waitDisplayId
+		}
 		return nil
 	}
 
@@ -2213,6 +3034,41 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 	return nil
 }
 
+// runOutput is what `docker run` writes to its --result-file and prints with
+// --format about the container it started.
+type runOutput struct {
+	Id        string
+	Name      string
+	IPAddress string
+	Ports     nat.PortMap
+}
+
+func (cli *DockerCli) getRunOutput(id string) (*runOutput, error) {
+	stream, _, err := cli.call("GET", "/containers/"+id+"/json", nil, false)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var container struct {
+		Id              string
+		Name            string
+		NetworkSettings struct {
+			IPAddress string
+			Ports     nat.PortMap
+		}
+	}
+	if err := json.NewDecoder(stream).Decode(&container); err != nil {
+		return nil, err
+	}
+	return &runOutput{
+		Id:        container.Id,
+		Name:      strings.TrimPrefix(container.Name, "/"),
+		IPAddress: container.NetworkSettings.IPAddress,
+		Ports:     container.NetworkSettings.Ports,
+	}, nil
+}
+
 func (cli *DockerCli) CmdCp(args ...string) error {
 	cmd := cli.Subcmd("cp", "CONTAINER:PATH HOSTPATH", "Copy files/folders from the PATH to the HOSTPATH")
 	if err := cmd.Parse(args); err != nil {
@@ -2256,6 +3112,7 @@ func (cli *DockerCli) CmdCp(args ...string) error {
 func (cli *DockerCli) CmdSave(args ...string) error {
 	cmd := cli.Subcmd("save", "IMAGE", "Save an image to a tar archive (streamed to STDOUT by default)")
 	outfile := cmd.String([]string{"o", "-output"}, "", "Write to an file, instead of STDOUT")
+	format := cmd.String([]string{"-format"}, "docker", "Layout of the archive: docker, or oci for an OCI image layout")
 
 	if err := cmd.Parse(args); err != nil {
 		return err
@@ -2276,13 +3133,33 @@ func (cli *DockerCli) CmdSave(args ...string) error {
 			return err
 		}
 	}
+	v := url.Values{}
+	if *format != "docker" {
+		v.Set("format", *format)
+	}
 	image := cmd.Arg(0)
-	if err := cli.stream("GET", "/images/"+image+"/get", nil, output, nil); err != nil {
+	if err := cli.stream("GET", "/images/"+image+"/get?"+v.Encode(), nil, output, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (cli *DockerCli) CmdTransfer(args ...string) error {
+	cmd := cli.Subcmd("transfer", "IMAGE HOST", "Send an image to the Docker daemon at HOST (tcp://host:port), without the layers it already has")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	v := url.Values{}
+	v.Set("host", cmd.Arg(1))
+	return cli.stream("POST", "/images/"+cmd.Arg(0)+"/transfer?"+v.Encode(), nil, cli.out, nil)
+}
+
 func (cli *DockerCli) CmdLoad(args ...string) error {
 	cmd := cli.Subcmd("load", "", "Load an image from a tar archive on STDIN")
 	infile := cmd.String([]string{"i", "-input"}, "", "Read from a tar archive file, instead of STDIN")
@@ -10,6 +10,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/dockerversion"
@@ -18,11 +19,28 @@ import (
 	"github.com/docker/docker/utils"
 )
 
+// Keepalive probes let both ends notice a peer that went away while a
+// hijacked connection is idle, e.g. when a proxy silently drops it.
+const keepAlivePeriod = 30 * time.Second
+
+// closeWriter is implemented by connections supporting half-close: the
+// write side is closed while the read side keeps receiving.
+type closeWriter interface {
+	CloseWrite() error
+}
+
 func (cli *DockerCli) dial() (net.Conn, error) {
+	if cli.proto == "ssh" {
+		return dialSSH(cli.addr)
+	}
+	dialer := &net.Dialer{}
+	if cli.proto == "tcp" {
+		dialer.KeepAlive = keepAlivePeriod
+	}
 	if cli.tlsConfig != nil && cli.proto != "unix" {
-		return tls.Dial(cli.proto, cli.addr, cli.tlsConfig)
+		return tls.DialWithDialer(dialer, cli.proto, cli.addr, cli.tlsConfig)
 	}
-	return net.Dial(cli.proto, cli.addr)
+	return dialer.Dial(cli.proto, cli.addr)
 }
 
 func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.ReadCloser, stdout, stderr io.Writer, started chan io.Closer) error {
@@ -104,12 +122,10 @@ func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.Rea
 			io.Copy(rwc, in)
 			log.Debugf("[hijack] End of stdin")
 		}
-		if tcpc, ok := rwc.(*net.TCPConn); ok {
-			if err := tcpc.CloseWrite(); err != nil {
-				log.Debugf("Couldn't send EOF: %s", err)
-			}
-		} else if unixc, ok := rwc.(*net.UnixConn); ok {
-			if err := unixc.CloseWrite(); err != nil {
+		// Half-close so the daemon sees the end of stdin while we
+		// keep reading the output of the container
+		if cw, ok := rwc.(closeWriter); ok {
+			if err := cw.CloseWrite(); err != nil {
 				log.Debugf("Couldn't send EOF: %s", err)
 			}
 		}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api"
+)
+
+// sshConn is a connection to the daemon's unix socket on a remote host,
+// carried over the stdio of an `ssh -W` process. It lets the client talk to
+// a daemon given as -H ssh://[user@]host[:port][/path/to/socket] without the
+// remote daemon listening on TCP.
+type sshConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	addr   sshAddr
+}
+
+type sshAddr string
+
+func (a sshAddr) Network() string { return "ssh" }
+func (a sshAddr) String() string  { return string(a) }
+
+// sshArgs turns the address part of an ssh:// host into the arguments
+// passed to the ssh binary. A user or host starting with "-" is refused, ssh
+// would take it as an option such as -oProxyCommand.
+func sshArgs(addr string) ([]string, error) {
+	u, err := url.Parse("ssh://" + addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("Invalid ssh address format: %s", addr)
+	}
+
+	var (
+		args   = []string{}
+		host   = u.Host
+		socket = u.Path
+	)
+	if h, port, err := net.SplitHostPort(u.Host); err == nil {
+		host = h
+		args = append(args, "-p", port)
+	}
+	if socket == "" || socket == "/" {
+		socket = api.DEFAULTUNIXSOCKET
+	}
+	if strings.HasPrefix(host, "-") {
+		return nil, fmt.Errorf("Invalid ssh host %s, it can't start with -", host)
+	}
+	if u.User != nil {
+		user := u.User.Username()
+		if strings.HasPrefix(user, "-") {
+			return nil, fmt.Errorf("Invalid ssh user %s, it can't start with -", user)
+		}
+		host = user + "@" + host
+	}
+	return append(args, "-W", socket, "--", host), nil
+}
+
+func dialSSH(addr string) (net.Conn, error) {
+	args, err := sshArgs(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("ssh", args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Cannot start ssh to connect to %s: %s", addr, err)
+	}
+
+	return &sshConn{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		addr:   sshAddr(addr),
+	}, nil
+}
+
+func (c *sshConn) Read(b []byte) (int, error) {
+	return c.stdout.Read(b)
+}
+
+func (c *sshConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+// CloseWrite closes the stdin of the ssh process, which ssh forwards to the
+// remote socket as a half-close.
+func (c *sshConn) CloseWrite() error {
+	return c.stdin.Close()
+}
+
+func (c *sshConn) Close() error {
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *sshConn) LocalAddr() net.Addr  { return sshAddr("local") }
+func (c *sshConn) RemoteAddr() net.Addr { return c.addr }
+
+// Deadlines are not supported on the ssh pipes, the ssh process
+// itself takes care of keepalives and timeouts.
+func (c *sshConn) SetDeadline(t time.Time) error      { return nil }
+func (c *sshConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *sshConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api"
+)
+
+func TestSshArgs(t *testing.T) {
+	for addr, expected := range map[string][]string{
+		"example.com":                          {"-W", api.DEFAULTUNIXSOCKET, "--", "example.com"},
+		"root@example.com:2222":                {"-p", "2222", "-W", api.DEFAULTUNIXSOCKET, "--", "root@example.com"},
+		"example.com/var/run/other.sock":       {"-W", "/var/run/other.sock", "--", "example.com"},
+		"admin@example.com:22/run/docker.sock": {"-p", "22", "-W", "/run/docker.sock", "--", "admin@example.com"},
+	} {
+		args, err := sshArgs(addr)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", addr, err)
+		}
+		if !reflect.DeepEqual(args, expected) {
+			t.Fatalf("Expected %v for %s, got %v", expected, addr, args)
+		}
+	}
+
+	for _, addr := range []string{"", "-oProxyCommand=id", "-oProxyCommand=id@example.com", "-x:22"} {
+		if _, err := sshArgs(addr); err == nil {
+			t.Fatalf("Expected %q to be refused", addr)
+		}
+	}
+}
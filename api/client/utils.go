@@ -16,6 +16,8 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"text/template"
+	"text/template/parse"
 
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/dockerversion"
@@ -34,6 +36,9 @@ func (cli *DockerCli) HTTPClient() *http.Client {
 	tr := &http.Transport{
 		TLSClientConfig: cli.tlsConfig,
 		Dial: func(network, addr string) (net.Conn, error) {
+			if cli.proto == "ssh" {
+				return dialSSH(cli.addr)
+			}
 			return net.Dial(cli.proto, cli.addr)
 		},
 	}
@@ -259,3 +264,79 @@ func readBody(stream io.ReadCloser, statusCode int, err error) ([]byte, int, err
 	}
 	return body, statusCode, nil
 }
+
+// templateFields returns the fields of the object tmpl is executed with
+// which it reads, as paths of keys separated by dots, for the daemon to only
+// send those. It returns false when tmpl reads the object as a whole, or in a
+// way the fields can't be told from.
+func templateFields(tmpl *template.Template) ([]string, bool) {
+	var (
+		fields []string
+		whole  bool
+		walk   func(node parse.Node, root bool)
+	)
+	// root tells whether dot is the object, it is one of its fields inside
+	// range and with, which are read whole
+	walk = func(node parse.Node, root bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, node := range n.Nodes {
+				walk(node, root)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, root)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, root)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, root)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, root)
+		case *parse.IfNode:
+			walk(n.Pipe, root)
+			walk(n.List, root)
+			walk(n.ElseList, root)
+		case *parse.RangeNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.WithNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.FieldNode:
+			if root {
+				fields = append(fields, strings.Join(n.Ident, "."))
+			}
+		case *parse.VariableNode:
+			// $ is the object wherever it is read
+			if n.Ident[0] == "$" {
+				if len(n.Ident) == 1 {
+					whole = true
+				} else {
+					fields = append(fields, strings.Join(n.Ident[1:], "."))
+				}
+			}
+		case *parse.DotNode:
+			if root {
+				whole = true
+			}
+		case *parse.TemplateNode:
+			whole = true
+		}
+	}
+	walk(tmpl.Tree.Root, true)
+	if whole || len(fields) == 0 {
+		return nil, false
+	}
+	return fields, true
+}
@@ -18,6 +18,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"code.google.com/p/go.net/websocket"
 	"github.com/docker/libcontainer/user"
@@ -40,17 +41,92 @@ var (
 
 type HttpApiFunc func(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error
 
-func hijackServer(w http.ResponseWriter) (io.ReadCloser, io.Writer, error) {
+// Keepalive probes detect clients that went away while a hijacked
+// connection is idle, so the attach does not hang forever behind a proxy.
+const keepAlivePeriod = 30 * time.Second
+
+type keepAliveListener struct {
+	net.Listener
+}
+
+func (l keepAliveListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tcpc, ok := c.(*net.TCPConn); ok {
+		tcpc.SetKeepAlive(true)
+		tcpc.SetKeepAlivePeriod(keepAlivePeriod)
+	}
+	return c, nil
+}
+
+// closeWriter is implemented by connections supporting half-close.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// idleConn closes a hijacked connection once no data went through it, either
+// way, for timeout, for the attach not to hang behind the proxies which keep
+// idle connections open. A quiet session may be healthy, it is opt-in.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func newIdleConn(conn net.Conn, timeout time.Duration) *idleConn {
+	return &idleConn{
+		Conn:    conn,
+		timeout: timeout,
+		timer:   time.AfterFunc(timeout, func() { conn.Close() }),
+	}
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.timer.Reset(c.timeout)
+	}
+	return n, err
+}
+
+func (c *idleConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.timer.Reset(c.timeout)
+	}
+	return n, err
+}
+
+func (c *idleConn) CloseWrite() error {
+	if cw, ok := c.Conn.(closeWriter); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+func (c *idleConn) Close() error {
+	c.timer.Stop()
+	return c.Conn.Close()
+}
+
+// hijackServer takes over the connection of w, closing it once idle for
+// idleTimeout, unless 0.
+func hijackServer(w http.ResponseWriter, idleTimeout time.Duration) (io.ReadCloser, io.Writer, error) {
 	conn, _, err := w.(http.Hijacker).Hijack()
 	if err != nil {
 		return nil, nil, err
 	}
+	if idleTimeout > 0 {
+		conn = newIdleConn(conn, idleTimeout)
+	}
 	// Flush the options to make sure the client sets the raw mode
 	conn.Write([]byte{})
 	return conn, conn, nil
 }
 
-//If we don't do this, POST method without Content-type (even with empty body) will fail
+// If we don't do this, POST method without Content-type (even with empty body) will fail
 func parseForm(r *http.Request) error {
 	if r == nil {
 		return nil
@@ -83,7 +159,7 @@ func httpError(w http.ResponseWriter, err error) {
 		statusCode = http.StatusNotAcceptable
 	} else if strings.Contains(err.Error(), "Wrong login/password") {
 		statusCode = http.StatusUnauthorized
-	} else if strings.Contains(err.Error(), "hasn't been activated") {
+	} else if strings.Contains(err.Error(), "hasn't been activated") || strings.Contains(err.Error(), "Forbidden") {
 		statusCode = http.StatusForbidden
 	}
 
@@ -102,12 +178,22 @@ func writeJSON(w http.ResponseWriter, code int, v engine.Env) error {
 func streamJSON(job *engine.Job, w http.ResponseWriter, flush bool) {
 	w.Header().Set("Content-Type", "application/json")
 	if flush {
+		streamHeaders(w)
 		job.Stdout.Add(utils.NewWriteFlusher(w))
 	} else {
 		job.Stdout.Add(w)
 	}
 }
 
+// streamHeaders asks intermediaries not to buffer or cache a response that
+// is streamed. Without a Content-Length it is sent chunked, and every write
+// to a WriteFlusher goes out as one chunk so frames are never split.
+func streamHeaders(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx buffers proxied responses unless told otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+}
+
 func getBoolParam(value string) (bool, error) {
 	if value == "" {
 		return false, nil
@@ -181,6 +267,181 @@ func postContainersPause(eng *engine.Engine, version version.Version, w http.Res
 	return nil
 }
 
+func postStorageSelftest(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	job := eng.Job("storage_selftest")
+	job.Setenv("layers", r.Form.Get("layers"))
+	job.Setenv("concurrency", r.Form.Get("concurrency"))
+	job.Setenv("size", r.Form.Get("size"))
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+// limitParams are the parameters of POST /containers/(name)/limit, given in
+// the query or in a JSON body, with the env of the limit job they set.
+var limitParams = map[string]string{
+	"memory":            "memory",
+	"memoryReservation": "memoryReservation",
+	"memorySwap":        "memorySwap",
+	"memorySwappiness":  "memorySwappiness",
+	"oomKillDisable":    "oomKillDisable",
+	"kernelMemory":      "kernelMemory",
+	"kernelMemoryTCP":   "kernelMemoryTCP",
+	"cpuShares":         "cpuShares",
+	"cpuQuota":          "cpuQuota",
+	"cpuPeriod":         "cpuPeriod",
+	"cpuBurst":          "cpuBurst",
+	"cpuRtRuntime":      "cpuRtRuntime",
+	"cpuRtPeriod":       "cpuRtPeriod",
+	"cpuset":            "cpuset",
+	"cpusetMems":        "cpusetMems",
+	"pidsLimit":         "pidsLimit",
+	"blkioWeight":       "blkioWeight",
+	"netClsClassid":     "netClsClassid",
+	"save":              "saveChanges",
+	"force":             "force",
+	"persistOnly":       "persistOnly",
+	"cgroup":            "cgroup",
+}
+
+// limitListParams are the parameters of POST /containers/(name)/limit given
+// once per device, page size or interface.
+var limitListParams = []string{"blkioDeviceReadIOps", "blkioDeviceWriteIOps", "hugetlbLimit", "netPrio"}
+
+// limitJob returns the limit job of the containers names with the limits of
+// the query or of the JSON body of r, and whether the limits came in a JSON
+// body.
+func limitJob(eng *engine.Engine, r *http.Request, names ...string) (*engine.Job, bool, error) {
+	dryRun, err := getBoolParam(r.Form.Get("dryRun"))
+	if err != nil {
+		return nil, false, err
+	}
+	job := eng.Job("limit", names...)
+	for param, key := range limitParams {
+		job.Setenv(key, r.Form.Get(param))
+	}
+	for _, param := range limitListParams {
+		job.SetenvList(param, r.Form[param])
+	}
+	// the parameters of a JSON body override the ones of the query, and the
+	// values of the changed files are answered
+	report := r.ContentLength > 0 && api.MatchesContentType(r.Header.Get("Content-Type"), "application/json")
+	if report {
+		body := &engine.Env{}
+		if err := body.Decode(r.Body); err != nil {
+			return nil, false, fmt.Errorf("Bad parameter, invalid JSON body: %s", err)
+		}
+		for param, key := range limitParams {
+			if body.Exists(param) {
+				job.Setenv(key, body.Get(param))
+			}
+		}
+		for _, param := range limitListParams {
+			if body.Exists(param) {
+				job.SetenvList(param, body.GetList(param))
+			}
+		}
+		if body.Exists("dryRun") {
+			dryRun = body.GetBool("dryRun")
+		}
+	}
+	job.SetenvBool("dryRun", dryRun)
+	job.SetenvBool("report", report)
+	job.Setenv("RequestToken", r.Header.Get("X-Docker-Request-Token"))
+	return job, report, nil
+}
+
+func postContainersLimit(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	job, report, err := limitJob(eng, r, vars["name"])
+	if err != nil {
+		return err
+	}
+	if job.GetenvBool("dryRun") || report {
+		streamJSON(job, w, false)
+		return job.Run()
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// postContainersLimitMany changes the limits of the containers given by
+// name, or of the running ones matching filters, the same way, answering
+// the outcome for each of them.
+func postContainersLimitMany(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if len(r.Form["name"]) == 0 && r.Form.Get("filters") == "" {
+		return fmt.Errorf("Bad parameter, no container to change the limits of")
+	}
+	job, _, err := limitJob(eng, r, r.Form["name"]...)
+	if err != nil {
+		return err
+	}
+	job.Setenv("filters", r.Form.Get("filters"))
+	// the outcome of each container is answered, even of a single one
+	job.SetenvBool("many", true)
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postContainersLock(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	job := eng.Job("volume_lock", vars["name"], r.Form.Get("path"))
+	job.Setenv("timeout", r.Form.Get("timeout"))
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func postContainersUnlock(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if err := eng.Job("volume_unlock", vars["name"], r.Form.Get("path")).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func postContainersDevice(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	job := eng.Job("device", r.Form.Get("action"), vars["name"], r.Form.Get("path"))
+	job.Setenv("permissions", r.Form.Get("permissions"))
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func postContainersUnpause(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
@@ -201,7 +462,8 @@ func getContainersExport(eng *engine.Engine, version version.Version, w http.Res
 		return fmt.Errorf("Missing parameter")
 	}
 	job := eng.Job("export", vars["name"])
-	job.Stdout.Add(w)
+	streamHeaders(w)
+	job.Stdout.Add(utils.NewWriteFlusher(w))
 	if err := job.Run(); err != nil {
 		return err
 	}
@@ -223,6 +485,8 @@ func getImagesJSON(eng *engine.Engine, version version.Version, w http.ResponseW
 	// FIXME this parameter could just be a match filter
 	job.Setenv("filter", r.Form.Get("filter"))
 	job.Setenv("all", r.Form.Get("all"))
+	job.Setenv("offset", r.Form.Get("offset"))
+	job.Setenv("limit", r.Form.Get("limit"))
 
 	if version.GreaterThanOrEqualTo("1.7") {
 		streamJSON(job, w, false)
@@ -266,6 +530,25 @@ func getImagesViz(eng *engine.Engine, version version.Version, w http.ResponseWr
 	return nil
 }
 
+func getContainersLifecycle(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	job := eng.Job("lifecycle_stats", "containers")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getImagesLifecycle(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	job := eng.Job("lifecycle_stats", "images")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getMetrics(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	job := eng.Job("metrics")
+	job.Stdout.Add(w)
+	return job.Run()
+}
+
 func getInfo(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	w.Header().Set("Content-Type", "application/json")
 	eng.ServeHTTP(w, r)
@@ -281,6 +564,9 @@ func getEvents(eng *engine.Engine, version version.Version, w http.ResponseWrite
 	streamJSON(job, w, true)
 	job.Setenv("since", r.Form.Get("since"))
 	job.Setenv("until", r.Form.Get("until"))
+	job.Setenv("heartbeat", r.Form.Get("heartbeat"))
+	job.Setenv("offset", r.Form.Get("offset"))
+	job.Setenv("limit", r.Form.Get("limit"))
 	return job.Run()
 }
 
@@ -298,6 +584,20 @@ func getImagesHistory(eng *engine.Engine, version version.Version, w http.Respon
 	return nil
 }
 
+func getImagesOnBuild(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	var job = eng.Job("image_onbuild", vars["name"])
+	streamJSON(job, w, false)
+
+	if err := job.Run(); err != nil {
+		return err
+	}
+	return nil
+}
+
 func getContainersChanges(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
@@ -308,6 +608,72 @@ func getContainersChanges(eng *engine.Engine, version version.Version, w http.Re
 	return job.Run()
 }
 
+func getContainersWatch(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var job = eng.Job("container_watch", vars["name"])
+	streamJSON(job, w, true)
+	job.Setenv("heartbeat", r.Form.Get("heartbeat"))
+	return job.Run()
+}
+
+func getContainersIO(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var job = eng.Job("container_io", vars["name"])
+	streamJSON(job, w, false)
+
+	return job.Run()
+}
+
+func getContainersThrottling(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var job = eng.Job("throttling", vars["name"])
+	streamJSON(job, w, false)
+
+	return job.Run()
+}
+
+func getContainersLimits(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	effective, err := getBoolParam(r.Form.Get("effective"))
+	if err != nil {
+		return err
+	}
+	var job = eng.Job("limits", vars["name"])
+	job.Setenv("cgroup", r.Form.Get("cgroup"))
+	job.SetenvBool("effective", effective)
+	streamJSON(job, w, false)
+
+	return job.Run()
+}
+
+func getVolumesOrphaned(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("volumes_orphaned")
+	streamJSON(job, w, false)
+
+	return job.Run()
+}
+
+func getVolumesLocks(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("volume_locks")
+	streamJSON(job, w, false)
+
+	return job.Run()
+}
+
 func getContainersTop(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if version.LessThan("1.4") {
 		return fmt.Errorf("top was improved a lot since 1.3, Please upgrade your docker client.")
@@ -339,6 +705,7 @@ func getContainersJSON(eng *engine.Engine, version version.Version, w http.Respo
 	job.Setenv("since", r.Form.Get("since"))
 	job.Setenv("before", r.Form.Get("before"))
 	job.Setenv("limit", r.Form.Get("limit"))
+	job.Setenv("offset", r.Form.Get("offset"))
 	job.Setenv("filters", r.Form.Get("filters"))
 
 	if version.GreaterThanOrEqualTo("1.5") {
@@ -394,6 +761,7 @@ func getContainersLogs(eng *engine.Engine, version version.Version, w http.Respo
 	}
 
 	var outStream, errStream io.Writer
+	streamHeaders(w)
 	outStream = utils.NewWriteFlusher(w)
 
 	if c.GetSubEnv("Config") != nil && !c.GetSubEnv("Config").GetBool("Tty") && version.GreaterThanOrEqualTo("1.6") {
@@ -428,6 +796,29 @@ func postImagesTag(eng *engine.Engine, version version.Version, w http.ResponseW
 	return nil
 }
 
+func postImagesOnBuild(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var (
+		env          engine.Env
+		job          = eng.Job("image_onbuild_set", vars["name"], r.Form.Get("repo"), r.Form.Get("tag"))
+		stdoutBuffer = bytes.NewBuffer(nil)
+	)
+	job.Setenv("strip", r.Form.Get("strip"))
+	job.SetenvList("add", r.Form["add"])
+
+	job.Stdout.Add(stdoutBuffer)
+	if err := job.Run(); err != nil {
+		return err
+	}
+	env.Set("Id", engine.Tail(stdoutBuffer, 1))
+	return writeJSON(w, http.StatusCreated, env)
+}
+
 func postCommit(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -503,6 +894,8 @@ func postImagesCreate(eng *engine.Engine, version version.Version, w http.Respon
 			repo, tag = parsers.ParseRepositoryTag(repo)
 		}
 		job = eng.Job("import", r.Form.Get("fromSrc"), repo, tag)
+		job.Setenv("format", r.Form.Get("format"))
+		job.Setenv("config", r.Form.Get("config"))
 		job.Stdin.Add(r.Body)
 	}
 
@@ -510,6 +903,7 @@ func postImagesCreate(eng *engine.Engine, version version.Version, w http.Respon
 		job.SetenvBool("json", true)
 		streamJSON(job, w, true)
 	} else {
+		streamHeaders(w)
 		job.Stdout.Add(utils.NewWriteFlusher(w))
 	}
 	if err := job.Run(); err != nil {
@@ -594,6 +988,7 @@ func postImagesPush(eng *engine.Engine, version version.Version, w http.Response
 		job.SetenvBool("json", true)
 		streamJSON(job, w, true)
 	} else {
+		streamHeaders(w)
 		job.Stdout.Add(utils.NewWriteFlusher(w))
 	}
 
@@ -611,11 +1006,33 @@ func getImagesGet(eng *engine.Engine, version version.Version, w http.ResponseWr
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
 	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
 	if version.GreaterThan("1.0") {
 		w.Header().Set("Content-Type", "application/x-tar")
 	}
 	job := eng.Job("image_export", vars["name"])
-	job.Stdout.Add(w)
+	job.Setenv("format", r.Form.Get("format"))
+	streamHeaders(w)
+	job.Stdout.Add(utils.NewWriteFlusher(w))
+	return job.Run()
+}
+
+func postImagesTransfer(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	host := r.Form.Get("host")
+	if host == "" {
+		return fmt.Errorf("Bad parameter, missing host")
+	}
+	job := eng.Job("image_transfer", vars["name"], host)
+	job.SetenvBool("json", true)
+	streamJSON(job, w, true)
 	return job.Run()
 }
 
@@ -639,6 +1056,7 @@ func postContainersCreate(eng *engine.Engine, version version.Version, w http.Re
 	if err := job.DecodeEnv(r.Body); err != nil {
 		return err
 	}
+	job.Setenv("RequestToken", r.Header.Get("X-Docker-Request-Token"))
 	// Read container ID from the first line of stdout
 	job.Stdout.Add(stdoutBuffer)
 	// Read warnings from stderr
@@ -726,6 +1144,7 @@ func postContainersStart(eng *engine.Engine, version version.Version, w http.Res
 			return err
 		}
 	}
+	job.Setenv("RequestToken", r.Header.Get("X-Docker-Request-Token"))
 
 	if err := job.Run(); err != nil {
 		if err.Error() == "Container already started" {
@@ -758,15 +1177,34 @@ func postContainersStop(eng *engine.Engine, version version.Version, w http.Resp
 	return nil
 }
 
+func postContainersTrace(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	job := eng.Job("trace", vars["name"])
+	job.Setenv("tool", r.Form.Get("tool"))
+	job.Setenv("duration", r.Form.Get("duration"))
+	streamHeaders(w)
+	job.Stdout.Add(utils.NewWriteFlusher(w))
+	return job.Run()
+}
+
 func postContainersWait(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
 	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
 	var (
 		env          engine.Env
 		stdoutBuffer = bytes.NewBuffer(nil)
 		job          = eng.Job("wait", vars["name"])
 	)
+	job.Setenv("condition", r.Form.Get("condition"))
 	job.Stdout.Add(stdoutBuffer)
 	if err := job.Run(); err != nil {
 		return err
@@ -796,6 +1234,13 @@ func postContainersAttach(eng *engine.Engine, version version.Version, w http.Re
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
 	}
+	var idleTimeout int
+	if value := r.Form.Get("idleTimeout"); value != "" {
+		var err error
+		if idleTimeout, err = strconv.Atoi(value); err != nil || idleTimeout < 0 {
+			return fmt.Errorf("Bad parameter, invalid idleTimeout %s", value)
+		}
+	}
 
 	var (
 		job    = eng.Job("container_inspect", vars["name"])
@@ -808,23 +1253,17 @@ func postContainersAttach(eng *engine.Engine, version version.Version, w http.Re
 		return err
 	}
 
-	inStream, outStream, err := hijackServer(w)
+	inStream, outStream, err := hijackServer(w, time.Duration(idleTimeout)*time.Second)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if tcpc, ok := inStream.(*net.TCPConn); ok {
-			tcpc.CloseWrite()
-		} else {
-			inStream.Close()
-		}
-	}()
-	defer func() {
-		if tcpc, ok := outStream.(*net.TCPConn); ok {
-			tcpc.CloseWrite()
-		} else if closer, ok := outStream.(io.Closer); ok {
-			closer.Close()
+		// Send EOF first so the client reads everything that was written,
+		// then release the connection even if the client never closes it.
+		if cw, ok := inStream.(closeWriter); ok {
+			cw.CloseWrite()
 		}
+		inStream.Close()
 	}()
 
 	var errStream io.Writer
@@ -866,15 +1305,35 @@ func wsContainersAttach(eng *engine.Engine, version version.Version, w http.Resp
 		return err
 	}
 
+	control, err := getBoolParam(r.Form.Get("control"))
+	if err != nil {
+		return err
+	}
+
 	h := websocket.Handler(func(ws *websocket.Conn) {
+		// Close sends a normal closure frame once the attach job is done
 		defer ws.Close()
+
+		var stdin io.Reader = ws
+		if control {
+			ws.PayloadType = websocket.BinaryFrame
+			stdin = &wsControlReader{
+				ws: ws,
+				onResize: func(height, width int) {
+					if err := eng.Job("resize", vars["name"], strconv.Itoa(height), strconv.Itoa(width)).Run(); err != nil {
+						log.Debugf("Error resizing from websocket: %s", err)
+					}
+				},
+			}
+		}
+
 		job := eng.Job("attach", vars["name"])
 		job.Setenv("logs", r.Form.Get("logs"))
 		job.Setenv("stream", r.Form.Get("stream"))
 		job.Setenv("stdin", r.Form.Get("stdin"))
 		job.Setenv("stdout", r.Form.Get("stdout"))
 		job.Setenv("stderr", r.Form.Get("stderr"))
-		job.Stdin.Add(ws)
+		job.Stdin.Add(stdin)
 		job.Stdout.Add(ws)
 		job.Stderr.Set(ws)
 		if err := job.Run(); err != nil {
@@ -886,14 +1345,96 @@ func wsContainersAttach(eng *engine.Engine, version version.Version, w http.Resp
 	return nil
 }
 
+// wsControlMessage is sent by websocket clients in a text frame when the
+// control protocol is enabled (control=1), binary frames carry the stdin data.
+type wsControlMessage struct {
+	Type   string // "resize" or "close"
+	Height int
+	Width  int
+}
+
+type wsFrame struct {
+	payloadType byte
+	data        []byte
+}
+
+var wsFrameCodec = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*wsFrame)
+		frame.payloadType = payloadType
+		frame.data = data
+		return nil
+	},
+}
+
+// wsControlReader reads stdin from the binary frames of a websocket and
+// handles the control messages interleaved with them. A "close" message
+// closes stdin while the output keeps streaming to the client.
+type wsControlReader struct {
+	ws       *websocket.Conn
+	buf      []byte
+	closed   bool
+	onResize func(height, width int)
+}
+
+func (r *wsControlReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.closed {
+			return 0, io.EOF
+		}
+		var frame wsFrame
+		if err := wsFrameCodec.Receive(r.ws, &frame); err != nil {
+			return 0, err
+		}
+		if frame.payloadType != websocket.TextFrame {
+			r.buf = frame.data
+			continue
+		}
+
+		var msg wsControlMessage
+		if err := json.Unmarshal(frame.data, &msg); err != nil {
+			log.Debugf("Invalid websocket control message: %s", err)
+			continue
+		}
+		switch msg.Type {
+		case "resize":
+			r.onResize(msg.Height, msg.Width)
+		case "close":
+			r.closed = true
+		default:
+			log.Debugf("Unknown websocket control message: %s", msg.Type)
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
 func getContainersByName(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
 	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
 	var job = eng.Job("container_inspect", vars["name"])
 	if version.LessThan("1.12") {
 		job.SetenvBool("raw", true)
 	}
+	job.SetenvList("fields", r.Form["fields"])
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getContainersInspect(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if len(r.Form["name"]) == 0 {
+		return fmt.Errorf("Bad parameter, no container to inspect")
+	}
+	var job = eng.Job("containers_inspect", r.Form["name"]...)
+	job.SetenvList("fields", r.Form["fields"])
 	streamJSON(job, w, false)
 	return job.Run()
 }
@@ -919,6 +1460,8 @@ func postBuild(eng *engine.Engine, version version.Version, w http.ResponseWrite
 		authConfig        = &registry.AuthConfig{}
 		configFileEncoded = r.Header.Get("X-Registry-Config")
 		configFile        = &registry.ConfigFile{}
+		secretsEncoded    = r.Header.Get("X-Build-Secrets")
+		secrets           = make(map[string][]byte)
 		job               = eng.Job("build")
 	)
 
@@ -944,10 +1487,19 @@ func postBuild(eng *engine.Engine, version version.Version, w http.ResponseWrite
 		}
 	}
 
+	if secretsEncoded != "" {
+		secretsJson := base64.NewDecoder(base64.URLEncoding, strings.NewReader(secretsEncoded))
+		// unlike the registry config, the build can't go on without the secrets
+		if err := json.NewDecoder(secretsJson).Decode(&secrets); err != nil {
+			return fmt.Errorf("Invalid build secrets: %s", err)
+		}
+	}
+
 	if version.GreaterThanOrEqualTo("1.8") {
 		job.SetenvBool("json", true)
 		streamJSON(job, w, true)
 	} else {
+		streamHeaders(w)
 		job.Stdout.Add(utils.NewWriteFlusher(w))
 	}
 
@@ -964,8 +1516,12 @@ func postBuild(eng *engine.Engine, version version.Version, w http.ResponseWrite
 	job.Setenv("q", r.FormValue("q"))
 	job.Setenv("nocache", r.FormValue("nocache"))
 	job.Setenv("forcerm", r.FormValue("forcerm"))
+	job.Setenv("sshagent", r.FormValue("sshagent"))
+	job.Setenv("network", r.FormValue("network"))
+	job.Setenv("parallel", r.FormValue("parallel"))
 	job.SetenvJson("authConfig", authConfig)
 	job.SetenvJson("configFile", configFile)
+	job.SetenvJson("secrets", secrets)
 
 	if err := job.Run(); err != nil {
 		if !job.Stdout.Used() {
@@ -1003,7 +1559,8 @@ func postContainersCopy(eng *engine.Engine, version version.Version, w http.Resp
 	}
 
 	job := eng.Job("container_copy", vars["name"], copyData.Get("Resource"))
-	job.Stdout.Add(w)
+	streamHeaders(w)
+	job.Stdout.Add(utils.NewWriteFlusher(w))
 	if err := job.Run(); err != nil {
 		log.Errorf("%s", err.Error())
 		if strings.Contains(err.Error(), "No such container") {
@@ -1098,24 +1655,36 @@ func createRouter(eng *engine.Engine, logging, enableCors bool, dockerVersion st
 	}
 	m := map[string]map[string]HttpApiFunc{
 		"GET": {
-			"/_ping":                          ping,
-			"/events":                         getEvents,
-			"/info":                           getInfo,
-			"/version":                        getVersion,
-			"/images/json":                    getImagesJSON,
-			"/images/viz":                     getImagesViz,
-			"/images/search":                  getImagesSearch,
-			"/images/{name:.*}/get":           getImagesGet,
-			"/images/{name:.*}/history":       getImagesHistory,
-			"/images/{name:.*}/json":          getImagesByName,
-			"/containers/ps":                  getContainersJSON,
-			"/containers/json":                getContainersJSON,
-			"/containers/{name:.*}/export":    getContainersExport,
-			"/containers/{name:.*}/changes":   getContainersChanges,
-			"/containers/{name:.*}/json":      getContainersByName,
-			"/containers/{name:.*}/top":       getContainersTop,
-			"/containers/{name:.*}/logs":      getContainersLogs,
-			"/containers/{name:.*}/attach/ws": wsContainersAttach,
+			"/_ping":                           ping,
+			"/events":                          getEvents,
+			"/info":                            getInfo,
+			"/version":                         getVersion,
+			"/images/json":                     getImagesJSON,
+			"/images/viz":                      getImagesViz,
+			"/images/lifecycle":                getImagesLifecycle,
+			"/metrics":                         getMetrics,
+			"/images/search":                   getImagesSearch,
+			"/images/{name:.*}/get":            getImagesGet,
+			"/images/{name:.*}/history":        getImagesHistory,
+			"/images/{name:.*}/onbuild":        getImagesOnBuild,
+			"/images/{name:.*}/json":           getImagesByName,
+			"/containers/ps":                   getContainersJSON,
+			"/containers/json":                 getContainersJSON,
+			"/containers/lifecycle":            getContainersLifecycle,
+			"/containers/inspect":              getContainersInspect,
+			"/containers/{name:.*}/export":     getContainersExport,
+			"/containers/{name:.*}/changes":    getContainersChanges,
+			"/containers/{name:.*}/watch":      getContainersWatch,
+			"/containers/{name:.*}/json":       getContainersByName,
+			"/containers/{name:.*}/top":        getContainersTop,
+			"/containers/{name:.*}/io":         getContainersIO,
+			"/containers/{name:.*}/throttling": getContainersThrottling,
+			"/containers/{name:.*}/limits":     getContainersLimits,
+			"/containers/{name:.*}/limit":      getContainersLimits,
+			"/containers/{name:.*}/logs":       getContainersLogs,
+			"/containers/{name:.*}/attach/ws":  wsContainersAttach,
+			"/volumes/orphaned":                getVolumesOrphaned,
+			"/volumes/locks":                   getVolumesLocks,
 		},
 		"POST": {
 			"/auth":                         postAuth,
@@ -1124,18 +1693,27 @@ func createRouter(eng *engine.Engine, logging, enableCors bool, dockerVersion st
 			"/images/create":                postImagesCreate,
 			"/images/load":                  postImagesLoad,
 			"/images/{name:.*}/push":        postImagesPush,
+			"/images/{name:.*}/transfer":    postImagesTransfer,
 			"/images/{name:.*}/tag":         postImagesTag,
+			"/images/{name:.*}/onbuild":     postImagesOnBuild,
 			"/containers/create":            postContainersCreate,
 			"/containers/{name:.*}/kill":    postContainersKill,
 			"/containers/{name:.*}/pause":   postContainersPause,
+			"/containers/{name:.*}/limit":   postContainersLimit,
+			"/containers/{name:.*}/device":  postContainersDevice,
+			"/containers/limit":             postContainersLimitMany,
 			"/containers/{name:.*}/unpause": postContainersUnpause,
 			"/containers/{name:.*}/restart": postContainersRestart,
 			"/containers/{name:.*}/start":   postContainersStart,
 			"/containers/{name:.*}/stop":    postContainersStop,
 			"/containers/{name:.*}/wait":    postContainersWait,
+			"/containers/{name:.*}/trace":   postContainersTrace,
+			"/containers/{name:.*}/lock":    postContainersLock,
+			"/containers/{name:.*}/unlock":  postContainersUnlock,
 			"/containers/{name:.*}/resize":  postContainersResize,
 			"/containers/{name:.*}/attach":  postContainersAttach,
 			"/containers/{name:.*}/copy":    postContainersCopy,
+			"/storage/selftest":             postStorageSelftest,
 		},
 		"DELETE": {
 			"/containers/{name:.*}": deleteContainers,
@@ -1255,6 +1833,10 @@ func ListenAndServe(proto, addr string, job *engine.Job) error {
 		return ServeFd(addr, r)
 	}
 
+	if proto == "ssh" {
+		return fmt.Errorf("ssh:// can only be used by the client to connect to a remote daemon")
+	}
+
 	if proto == "unix" {
 		if err := syscall.Unlink(addr); err != nil && !os.IsNotExist(err) {
 			return err
@@ -1279,6 +1861,10 @@ func ListenAndServe(proto, addr string, job *engine.Job) error {
 		return err
 	}
 
+	if proto == "tcp" {
+		l = keepAliveListener{l}
+	}
+
 	if proto != "unix" && (job.GetenvBool("Tls") || job.GetenvBool("TlsVerify")) {
 		tlsCert := job.Getenv("TlsCert")
 		tlsKey := job.Getenv("TlsKey")
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"code.google.com/p/go.net/websocket"
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/pkg/version"
@@ -414,6 +418,36 @@ func TestGetImagesHistory(t *testing.T) {
 	}
 }
 
+func TestPostImagesOnBuild(t *testing.T) {
+	eng := engine.New()
+	imageName := "docker-test-image"
+	var called bool
+	eng.Register("image_onbuild_set", func(job *engine.Job) engine.Status {
+		called = true
+		if expected := []string{imageName, "myrepo", "v1"}; !reflect.DeepEqual(job.Args, expected) {
+			t.Fatalf("Expected the arguments %v, got %v", expected, job.Args)
+		}
+		if !job.GetenvBool("strip") {
+			t.Fatal("strip env variable not set")
+		}
+		if expected := []string{"RUN make", "RUN make test"}; !reflect.DeepEqual(job.GetenvList("add"), expected) {
+			t.Fatalf("Expected the triggers %v, got %v", expected, job.GetenvList("add"))
+		}
+		job.Printf("%s\n", "1234")
+		return engine.StatusOK
+	})
+	r := serveRequest("POST", "/images/"+imageName+"/onbuild?repo=myrepo&tag=v1&strip=1&add=RUN+make&add=RUN+make+test", bytes.NewReader(nil), eng, t)
+	if !called {
+		t.Fatalf("handler was not called")
+	}
+	if r.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusCreated)
+	}
+	if id := readEnv(r.Body, t).Get("Id"); id != "1234" {
+		t.Fatalf("Expected the Id 1234, got %s", id)
+	}
+}
+
 func TestGetImagesByName(t *testing.T) {
 	eng := engine.New()
 	name := "image_name"
@@ -451,6 +485,34 @@ func TestGetImagesByName(t *testing.T) {
 	}
 }
 
+func TestGetContainersInspect(t *testing.T) {
+	eng := engine.New()
+	var called bool
+	eng.Register("containers_inspect", func(job *engine.Job) engine.Status {
+		called = true
+		if !reflect.DeepEqual(job.Args, []string{"foo", "bar"}) {
+			t.Fatalf("Unexpected containers %v", job.Args)
+		}
+		if fields := job.GetenvList("fields"); !reflect.DeepEqual(fields, []string{"State.Running", "Name"}) {
+			t.Fatalf("Unexpected fields %v", fields)
+		}
+		job.Stdout.Write([]byte(`[{"Name":"/foo"},{"Error":"No such container: bar"}]`))
+		return engine.StatusOK
+	})
+	r := serveRequest("GET", "/containers/inspect?name=foo&name=bar&fields=State.Running&fields=Name", nil, eng, t)
+	if !called {
+		t.Fatal("handler was not called")
+	}
+	if r.Code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusOK)
+	}
+
+	r = serveRequest("GET", "/containers/inspect", nil, eng, t)
+	if r.Code != http.StatusBadRequest {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusBadRequest)
+	}
+}
+
 func TestDeleteContainers(t *testing.T) {
 	eng := engine.New()
 	name := "foo"
@@ -474,6 +536,180 @@ func TestDeleteContainers(t *testing.T) {
 	}
 }
 
+func TestPostContainersLimitJSON(t *testing.T) {
+	eng := engine.New()
+	var called bool
+	eng.Register("limit", func(job *engine.Job) engine.Status {
+		called = true
+		for key, expected := range map[string]string{
+			"memory":      "268435456",
+			"cpuShares":   "512",
+			"cpuset":      "0-1",
+			"saveChanges": "true",
+			"report":      "1",
+		} {
+			if value := job.Getenv(key); value != expected {
+				t.Fatalf("Expected %s to be %s, got %s", key, expected, value)
+			}
+		}
+		if expected := []string{"/dev/sda:100"}; !reflect.DeepEqual(job.GetenvList("blkioDeviceReadIOps"), expected) {
+			t.Fatalf("Expected the rates %v, got %v", expected, job.GetenvList("blkioDeviceReadIOps"))
+		}
+		v := &engine.Env{}
+		v.Set("memory.limit_in_bytes", "268435456")
+		if _, err := v.WriteTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	})
+	body := `{"memory":268435456,"cpuShares":512,"cpuset":"0-1","save":true,"blkioDeviceReadIOps":["/dev/sda:100"]}`
+	req, err := http.NewRequest("POST", "/containers/foo/limit", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r := httptest.NewRecorder()
+	if err := ServeRequest(eng, api.APIVERSION, r, req); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatal("handler was not called")
+	}
+	if r.Code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusOK)
+	}
+	if value := readEnv(r.Body, t).Get("memory.limit_in_bytes"); value != "268435456" {
+		t.Fatalf("Expected the memory limit 268435456, got %s", value)
+	}
+}
+
+func TestGetContainersLimit(t *testing.T) {
+	eng := engine.New()
+	var called int
+	eng.Register("limits", func(job *engine.Job) engine.Status {
+		called++
+		if job.Args[0] != "foo" {
+			t.Fatalf("Expected the container foo, got %s", job.Args[0])
+		}
+		v := &engine.Env{}
+		v.Set("pids.max", "max")
+		if _, err := v.WriteTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	})
+	for _, path := range []string{"/containers/foo/limit", "/containers/foo/limits"} {
+		r := serveRequest("GET", path, nil, eng, t)
+		if r.Code != http.StatusOK {
+			t.Fatalf("Got status %d for %s, expected %d", r.Code, path, http.StatusOK)
+		}
+		if value := readEnv(r.Body, t).Get("pids.max"); value != "max" {
+			t.Fatalf("Expected the pids limit max for %s, got %s", path, value)
+		}
+	}
+	if called != 2 {
+		t.Fatalf("Expected the handler to be called twice, got %d", called)
+	}
+}
+
+func TestIdleConn(t *testing.T) {
+	server, client := net.Pipe()
+	conn := newIdleConn(server, 100*time.Millisecond)
+	defer conn.Close()
+
+	go client.Write([]byte("x"))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan error, 1)
+	go func() {
+		_, err := client.Read(make([]byte, 1))
+		closed <- err
+	}()
+	select {
+	case err := <-closed:
+		if err != io.EOF {
+			t.Fatalf("Expected the idle connection to be closed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the idle connection to be closed")
+	}
+}
+
+func TestPostContainersAttachIdleTimeout(t *testing.T) {
+	eng := engine.New()
+	eng.Register("container_inspect", func(job *engine.Job) engine.Status {
+		t.Fatal("Expected the invalid idleTimeout to be refused first")
+		return engine.StatusOK
+	})
+	for _, value := range []string{"abc", "-1"} {
+		r := serveRequest("POST", "/containers/foo/attach?stream=1&idleTimeout="+value, strings.NewReader(""), eng, t)
+		if r.Code != http.StatusBadRequest {
+			t.Fatalf("Got status %d for the idleTimeout %s, expected %d", r.Code, value, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestWsControlReader(t *testing.T) {
+	type result struct {
+		stdin   string
+		resizes [][2]int
+		err     error
+	}
+	results := make(chan result, 1)
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		var res result
+		r := &wsControlReader{ws: ws, onResize: func(height, width int) {
+			res.resizes = append(res.resizes, [2]int{height, width})
+		}}
+		var stdin []byte
+		stdin, res.err = ioutil.ReadAll(r)
+		res.stdin = string(stdin)
+		// stdin stays closed once closed
+		if n, err := r.Read(make([]byte, 1)); res.err == nil && (n != 0 || err != io.EOF) {
+			res.err = fmt.Errorf("Expected EOF after close, got %d bytes and %v", n, err)
+		}
+		results <- res
+	}))
+	defer srv.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	// the binary frames are stdin, the text frames control messages
+	for _, frame := range []interface{}{
+		[]byte("hello"),
+		`{"Type":"resize","Height":24,"Width":80}`,
+		`not json`,
+		[]byte(`{"Type":"close"}`),
+		`{"Type":"unknown"}`,
+		[]byte(" world"),
+		`{"Type":"close"}`,
+		[]byte("ignored"),
+	} {
+		if err := websocket.Message.Send(ws, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case res := <-results:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if expected := `hello{"Type":"close"} world`; res.stdin != expected {
+			t.Fatalf("Expected the stdin %q, got %q", expected, res.stdin)
+		}
+		if expected := [][2]int{{24, 80}}; !reflect.DeepEqual(res.resizes, expected) {
+			t.Fatalf("Expected the resizes %v, got %v", expected, res.resizes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for stdin to be closed")
+	}
+}
+
 func serveRequest(method, target string, body io.Reader, eng *engine.Engine, t *testing.T) *httptest.ResponseRecorder {
 	return serveRequestUsingVersion(method, target, api.APIVERSION, body, eng, t)
 }
@@ -68,6 +68,12 @@ func dockerVersion(job *engine.Job) engine.Status {
 	if kernelVersion, err := kernel.GetKernelVersion(); err == nil {
 		v.Set("KernelVersion", kernelVersion.String())
 	}
+	// the experimental features of the daemon, once it is loaded
+	features := job.Eng.Job("features")
+	if env, err := features.Stdout.AddEnv(); err == nil && features.Run() == nil {
+		v.SetBool("Experimental", env.GetBool("Experimental"))
+		v.SetList("Features", env.GetList("Features"))
+	}
 	if _, err := v.WriteTo(job.Stdout); err != nil {
 		return job.Error(err)
 	}
@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bindRule allows or denies bind mounting the host paths under path, origin
+// is where the rule comes from for the errors. An exact rule only applies to
+// path itself.
+type bindRule struct {
+	path   string
+	allow  bool
+	exact  bool
+	origin string
+}
+
+// bindRules returns the rules of the daemon restricting the host paths which
+// can be bind mounted. The root of the host, /etc and the data directory of
+// docker are denied unless they are allowed explicitly.
+func (daemon *Daemon) bindRules() []bindRule {
+	rules := []bindRule{
+		{path: "/", exact: true, origin: "the root of the host is denied by default"},
+		{path: "/etc", origin: "/etc is denied by default"},
+		{path: daemon.config.Root, origin: "the data directory of docker is denied by default"},
+	}
+	for _, p := range daemon.config.BindDeny {
+		rules = append(rules, bindRule{path: p, origin: fmt.Sprintf("%s is denied by --bind-deny", p)})
+	}
+	for _, p := range daemon.config.BindAllow {
+		rules = append(rules, bindRule{path: p, allow: true})
+	}
+	return rules
+}
+
+// checkBindSource checks that the host path source can be bind mounted. The
+// rule of the longest path covering source applies, an allow rule wins over
+// a deny rule of the same path. When there are allow rules, the paths none
+// of them covers are denied. Symbolic links are resolved first, so a link
+// can't point a bind mount to a denied path.
+func checkBindSource(rules []bindRule, source string) error {
+	resolved := resolveHostPath(source)
+
+	var (
+		match    *bindRule
+		matchLen int
+		hasAllow bool
+	)
+	for i := range rules {
+		rule := &rules[i]
+		if rule.allow {
+			hasAllow = true
+		}
+		p := resolveHostPath(rule.path)
+		if p != resolved && (rule.exact || !pathHasPrefix(resolved, p)) {
+			continue
+		}
+		if match == nil || len(p) > matchLen || (len(p) == matchLen && rule.allow) {
+			match, matchLen = rule, len(p)
+		}
+	}
+
+	switch {
+	case match != nil && match.allow:
+		return nil
+	case match != nil:
+		return fmt.Errorf("Cannot bind mount %s: %s, allow it with --bind-allow", source, match.origin)
+	case hasAllow:
+		return fmt.Errorf("Cannot bind mount %s: it is not under a path allowed with --bind-allow", source)
+	}
+	return nil
+}
+
+// resolveHostPath resolves the symbolic links of p, which may not exist yet:
+// the bind mount sources are created when missing.
+func resolveHostPath(p string) string {
+	p = filepath.Clean(p)
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	parent := filepath.Dir(p)
+	if parent == p {
+		return p
+	}
+	return filepath.Join(resolveHostPath(parent), filepath.Base(p))
+}
+
+// pathHasPrefix reports whether p is prefix or under it.
+func pathHasPrefix(p, prefix string) bool {
+	return p == prefix || prefix == "/" || strings.HasPrefix(p, prefix+string(os.PathSeparator))
+}
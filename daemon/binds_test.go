@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckBindSource(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-test-binds-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// the paths are resolved, so is the temporary directory
+	if tmp, err = filepath.EvalSymlinks(tmp); err != nil {
+		t.Fatal(err)
+	}
+	graph := filepath.Join(tmp, "docker")
+	if err := os.MkdirAll(filepath.Join(graph, "containers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(graph, "containers"), filepath.Join(tmp, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	daemon := &Daemon{config: &Config{Root: graph}}
+	rules := daemon.bindRules()
+	for _, source := range []string{"/etc/", "/etc/ssl/certs", "/", graph, filepath.Join(graph, "containers"), filepath.Join(tmp, "link", "missing")} {
+		if err := checkBindSource(rules, source); err == nil {
+			t.Errorf("Expected the bind mount of %s to be denied", source)
+		}
+	}
+	for _, source := range []string{"/etcetera", "/home/me", filepath.Join(tmp, "docker2"), filepath.Join(tmp, "missing", "dir")} {
+		if err := checkBindSource(rules, source); err != nil {
+			t.Errorf("Expected the bind mount of %s to be allowed: %s", source, err)
+		}
+	}
+
+	daemon.config.BindAllow = []string{"/etc/ssl/certs", "/srv"}
+	daemon.config.BindDeny = []string{"/srv/secret"}
+	rules = daemon.bindRules()
+	for _, source := range []string{"/etc/ssl/certs", "/srv", "/srv/www/static"} {
+		if err := checkBindSource(rules, source); err != nil {
+			t.Errorf("Expected the bind mount of %s to be allowed: %s", source, err)
+		}
+	}
+	for _, source := range []string{"/etc/ssl", "/srv/secret/key", "/home/me", "/"} {
+		if err := checkBindSource(rules, source); err == nil {
+			t.Errorf("Expected the bind mount of %s to be denied", source)
+		}
+	}
+}
@@ -44,13 +44,33 @@ func (daemon *Daemon) CmdBuild(job *engine.Job) engine.Status {
 		noCache        = job.GetenvBool("nocache")
 		rm             = job.GetenvBool("rm")
 		forceRm        = job.GetenvBool("forcerm")
+		sshAgent       = job.Getenv("sshagent")
+		networkMode    = runconfig.NetworkMode(job.Getenv("network"))
+		parallel       = job.GetenvInt("parallel")
 		authConfig     = &registry.AuthConfig{}
 		configFile     = &registry.ConfigFile{}
+		secrets        = make(map[string][]byte)
 		tag            string
 		context        io.ReadCloser
 	)
 	job.GetenvJson("authConfig", authConfig)
 	job.GetenvJson("configFile", configFile)
+	job.GetenvJson("secrets", &secrets)
+	for name := range secrets {
+		if err := validateSecretName(name); err != nil {
+			return job.Error(err)
+		}
+	}
+	if sshAgent != "" {
+		if err := validateSSHAgent(daemon.bindRules(), sshAgent); err != nil {
+			return job.Error(err)
+		}
+	}
+	switch networkMode {
+	case "", "bridge", "none", "host":
+	default:
+		return job.Errorf("Invalid network mode %s of the build, must be bridge, none or host", networkMode)
+	}
 	repoName, tag = parsers.ParseRepositoryTag(repoName)
 
 	if remoteURL == "" {
@@ -68,6 +88,9 @@ func (daemon *Daemon) CmdBuild(job *engine.Job) engine.Status {
 		if output, err := exec.Command("git", "clone", "--recursive", remoteURL, root).CombinedOutput(); err != nil {
 			return job.Errorf("Error trying to use git: %s (%s)", err, output)
 		}
+		if err := daemon.newScratchQuota().addTree(root); err != nil {
+			return job.Error(err)
+		}
 
 		c, err := archive.Tar(root, archive.Uncompressed)
 		if err != nil {
@@ -102,7 +125,7 @@ func (daemon *Daemon) CmdBuild(job *engine.Job) engine.Status {
 			Writer:          job.Stdout,
 			StreamFormatter: sf,
 		},
-		!suppressOutput, !noCache, rm, forceRm, job.Stdout, sf, authConfig, configFile)
+		!suppressOutput, !noCache, rm, forceRm, job.Stdout, sf, authConfig, configFile, secrets, sshAgent, networkMode, parallel)
 	id, err := b.Build(context)
 	if err != nil {
 		return job.Error(err)
@@ -142,9 +165,23 @@ type buildFile struct {
 	authConfig *registry.AuthConfig
 	configFile *registry.ConfigFile
 
+	// secrets are mounted at buildSecretsPath and the SSH agent socket at
+	// sshAgentPath in the RUN instructions only
+	secrets  map[string][]byte
+	sshAgent string
+
+	// networkMode is the network of the RUN instructions
+	networkMode runconfig.NetworkMode
+
+	// parallel is the number of stages built at the same time
+	parallel int
+
 	tmpContainers map[string]struct{}
 	tmpImages     map[string]struct{}
 
+	// scratch caps the context and the remote files of the build
+	scratch *scratchQuota
+
 	outStream io.Writer
 	errStream io.Writer
 
@@ -308,7 +345,12 @@ func (b *buildFile) CmdRun(args string) error {
 	c.Mount()
 	defer c.Unmount()
 
+	mountpoints, err := b.setupRunMounts(c)
+	if err != nil {
+		return err
+	}
 	err = b.run(c)
+	b.teardownRunMounts(c, mountpoints)
 	if err != nil {
 		return err
 	}
@@ -628,7 +670,7 @@ func (b *buildFile) runContextCommand(args string, allowRemote bool, allowDecomp
 		defer os.RemoveAll(tmpDirName)
 
 		// Download and dump result to tmp file
-		if _, err := io.Copy(tmpFile, resp.Body); err != nil {
+		if _, err := io.Copy(tmpFile, b.scratch.reader(resp.Body)); err != nil {
 			tmpFile.Close()
 			return err
 		}
@@ -755,11 +797,20 @@ func (b *buildFile) create() (*Container, error) {
 	}
 	b.config.Image = b.image
 
+	config := b.config
+	if b.networkMode.IsHost() {
+		// the container gets the hostname of the host, which must not end
+		// up in the image
+		hostConfig := *b.config
+		config = &hostConfig
+	}
+
 	// Create the container
-	c, _, err := b.daemon.Create(b.config, "")
+	c, _, err := b.daemon.Create(config, "")
 	if err != nil {
 		return nil, err
 	}
+	c.hostConfig.NetworkMode = b.networkMode
 	b.tmpContainers[c.ID] = struct{}{}
 	fmt.Fprintf(b.outStream, " ---> Running in %s\n", utils.TruncateID(c.ID))
 
@@ -770,6 +821,35 @@ func (b *buildFile) create() (*Container, error) {
 	return c, nil
 }
 
+// setupRunMounts gives the secrets and the SSH agent of the build to the
+// container of a RUN instruction. It returns the paths created in the rootfs
+// of the container to mount them, which teardownRunMounts removes before the
+// commit.
+func (b *buildFile) setupRunMounts(c *Container) ([]string, error) {
+	var mountpoints []string
+	if b.sshAgent != "" {
+		mountpoints = append(mountpoints, missingPaths(c.basefs, sshAgentPath)...)
+		c.sshAgent = b.sshAgent
+	}
+	if len(b.secrets) > 0 {
+		mountpoints = append(mountpoints, missingPaths(c.basefs, buildSecretsPath)...)
+		if err := c.mountSecrets(b.secrets); err != nil {
+			return nil, err
+		}
+	}
+	return mountpoints, nil
+}
+
+func (b *buildFile) teardownRunMounts(c *Container, mountpoints []string) {
+	c.sshAgent = ""
+	if len(b.secrets) > 0 {
+		if err := c.unmountSecrets(); err != nil {
+			log.Errorf("Error unmounting the build secrets of %s: %s", utils.TruncateID(c.ID), err)
+		}
+	}
+	removeMountpoints(c.basefs, mountpoints)
+}
+
 func (b *buildFile) run(c *Container) error {
 	var errCh chan error
 	if b.verbose {
@@ -873,11 +953,12 @@ func (b *buildFile) Build(context io.Reader) (string, error) {
 		return "", err
 	}
 
-	b.context = &tarsum.TarSum{Reader: decompressedStream, DisableCompression: true}
+	defer os.RemoveAll(tmpdirPath)
+
+	b.context = &tarsum.TarSum{Reader: b.scratch.reader(decompressedStream), DisableCompression: true}
 	if err := archive.Untar(b.context, tmpdirPath, nil); err != nil {
 		return "", err
 	}
-	defer os.RemoveAll(tmpdirPath)
 
 	b.contextPath = tmpdirPath
 	filename := path.Join(tmpdirPath, "Dockerfile")
@@ -893,22 +974,22 @@ func (b *buildFile) Build(context io.Reader) (string, error) {
 	}
 	var (
 		dockerfile = lineContinuation.ReplaceAllString(stripComments(fileBytes), "")
-		stepN      = 0
+		steps      []string
 	)
 	for _, line := range strings.Split(dockerfile, "\n") {
 		line = strings.Trim(strings.Replace(line, "\t", " ", -1), " \t\r\n")
 		if len(line) == 0 {
 			continue
 		}
-		if err := b.BuildStep(fmt.Sprintf("%d", stepN), line); err != nil {
-			if b.forceRm {
-				b.clearTmp(b.tmpContainers)
-			}
-			return "", err
-		} else if b.rm {
-			b.clearTmp(b.tmpContainers)
-		}
-		stepN += 1
+		steps = append(steps, line)
+	}
+	if b.parallel > 1 {
+		err = b.buildStages(splitStages(steps))
+	} else {
+		err = b.buildSteps(steps, 0)
+	}
+	if err != nil {
+		return "", err
 	}
 	if b.image != "" {
 		fmt.Fprintf(b.outStream, "Successfully built %s\n", utils.TruncateID(b.image))
@@ -918,6 +999,21 @@ func (b *buildFile) Build(context io.Reader) (string, error) {
 }
 
 // BuildStep parses a single build step from `instruction` and executes it in the current context.
+// buildSteps runs the instructions steps, numbered from firstStep.
+func (b *buildFile) buildSteps(steps []string, firstStep int) error {
+	for i, step := range steps {
+		if err := b.BuildStep(fmt.Sprintf("%d", firstStep+i), step); err != nil {
+			if b.forceRm {
+				b.clearTmp(b.tmpContainers)
+			}
+			return err
+		} else if b.rm {
+			b.clearTmp(b.tmpContainers)
+		}
+	}
+	return nil
+}
+
 func (b *buildFile) BuildStep(name, expression string) error {
 	fmt.Fprintf(b.outStream, "Step %s : %s\n", name, expression)
 	tmp := strings.SplitN(expression, " ", 2)
@@ -987,7 +1083,7 @@ func fixPermissions(destination string, uid, gid int) error {
 	})
 }
 
-func NewBuildFile(d *Daemon, eng *engine.Engine, outStream, errStream io.Writer, verbose, utilizeCache, rm bool, forceRm bool, outOld io.Writer, sf *utils.StreamFormatter, auth *registry.AuthConfig, authConfigFile *registry.ConfigFile) BuildFile {
+func NewBuildFile(d *Daemon, eng *engine.Engine, outStream, errStream io.Writer, verbose, utilizeCache, rm bool, forceRm bool, outOld io.Writer, sf *utils.StreamFormatter, auth *registry.AuthConfig, authConfigFile *registry.ConfigFile, secrets map[string][]byte, sshAgent string, networkMode runconfig.NetworkMode, parallel int) BuildFile {
 	return &buildFile{
 		daemon:        d,
 		eng:           eng,
@@ -996,6 +1092,7 @@ func NewBuildFile(d *Daemon, eng *engine.Engine, outStream, errStream io.Writer,
 		errStream:     errStream,
 		tmpContainers: make(map[string]struct{}),
 		tmpImages:     make(map[string]struct{}),
+		scratch:       d.newScratchQuota(),
 		verbose:       verbose,
 		utilizeCache:  utilizeCache,
 		rm:            rm,
@@ -1003,6 +1100,10 @@ func NewBuildFile(d *Daemon, eng *engine.Engine, outStream, errStream io.Writer,
 		sf:            sf,
 		authConfig:    auth,
 		configFile:    authConfigFile,
+		secrets:       secrets,
+		sshAgent:      sshAgent,
+		networkMode:   networkMode,
+		parallel:      parallel,
 		outOld:        outOld,
 	}
 }
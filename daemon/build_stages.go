@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+// errStageSkipped is the error of the stages not started after another one
+// failed.
+var errStageSkipped = errors.New("Stage skipped after the failure of another one")
+
+// buildStage is the instructions of a Dockerfile building one image, from a
+// FROM up to the next one.
+type buildStage struct {
+	steps     []string
+	firstStep int
+}
+
+// splitStages splits the instructions steps of a Dockerfile at its FROM
+// instructions. The stages don't depend on each other, a FROM can't refer to
+// the image built by a previous stage.
+func splitStages(steps []string) []buildStage {
+	var stages []buildStage
+	for i, step := range steps {
+		instruction := strings.SplitN(step, " ", 2)[0]
+		if len(stages) == 0 || strings.ToUpper(instruction) == "FROM" {
+			stages = append(stages, buildStage{firstStep: i})
+		}
+		stages[len(stages)-1].steps = append(stages[len(stages)-1].steps, step)
+	}
+	return stages
+}
+
+// buildStages builds stages at the same time, b.parallel at most. The output
+// of a stage is held until the stages before it are done, so it reads the
+// same as a sequential build, and the image of the last stage is the one of
+// the build. Each stage starts from a blank builder: the MAINTAINER and the
+// CMD of a stage are not carried over to the next ones.
+func (b *buildFile) buildStages(stages []buildStage) error {
+	var (
+		workers = make(chan struct{}, b.parallel)
+		outputs = make([]*stageOutput, len(stages))
+		builds  = make([]*buildFile, len(stages))
+		errs    = make([]chan error, len(stages))
+		abort   = make(chan struct{})
+		once    sync.Once
+	)
+	for i := range stages {
+		outputs[i] = &stageOutput{w: b.outOld}
+		builds[i] = b.stage(outputs[i])
+		errs[i] = make(chan error, 1)
+	}
+
+	// the stages are started in order, as workers are free
+	go func() {
+		for i, stage := range stages {
+			workers <- struct{}{}
+			go func(s *buildFile, stage buildStage, errCh chan error) {
+				defer func() { <-workers }()
+				select {
+				case <-abort:
+					errCh <- errStageSkipped
+					return
+				default:
+				}
+				err := s.buildSteps(stage.steps, stage.firstStep)
+				if err != nil {
+					once.Do(func() { close(abort) })
+				}
+				errCh <- err
+			}(builds[i], stage, errs[i])
+		}
+	}()
+
+	var firstErr error
+	for i := range stages {
+		if err := outputs[i].flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := <-errs[i]; err != nil && err != errStageSkipped && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	b.image = builds[len(builds)-1].image
+	return nil
+}
+
+// stage returns a builder for a stage of b writing its output to out.
+func (b *buildFile) stage(out io.Writer) *buildFile {
+	s := *b
+	s.image = ""
+	s.maintainer = ""
+	s.config = &runconfig.Config{}
+	s.cmdSet = false
+	s.tmpContainers = make(map[string]struct{})
+	s.tmpImages = make(map[string]struct{})
+	s.outStream = &utils.StdoutFormater{Writer: out, StreamFormatter: b.sf}
+	s.errStream = &utils.StderrFormater{Writer: out, StreamFormatter: b.sf}
+	s.outOld = out
+	return &s
+}
+
+// stageOutput holds the output of a stage until flush, and lets it through
+// after.
+type stageOutput struct {
+	sync.Mutex
+	w    io.Writer
+	buf  bytes.Buffer
+	live bool
+}
+
+func (o *stageOutput) Write(p []byte) (int, error) {
+	o.Lock()
+	defer o.Unlock()
+	if o.live {
+		return o.w.Write(p)
+	}
+	return o.buf.Write(p)
+}
+
+func (o *stageOutput) flush() error {
+	o.Lock()
+	defer o.Unlock()
+	o.live = true
+	_, err := o.buf.WriteTo(o.w)
+	return err
+}
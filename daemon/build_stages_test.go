@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSplitStages(t *testing.T) {
+	stages := splitStages([]string{
+		"from busybox",
+		"run echo a",
+		"FROM ubuntu",
+		"MAINTAINER me",
+		"from busybox",
+	})
+	if len(stages) != 3 {
+		t.Fatalf("Expected 3 stages, got %+v", stages)
+	}
+	for i, expected := range []buildStage{
+		{steps: []string{"from busybox", "run echo a"}, firstStep: 0},
+		{steps: []string{"FROM ubuntu", "MAINTAINER me"}, firstStep: 2},
+		{steps: []string{"from busybox"}, firstStep: 4},
+	} {
+		if !reflect.DeepEqual(stages[i], expected) {
+			t.Fatalf("Expected stage %d to be %+v, got %+v", i, expected, stages[i])
+		}
+	}
+}
+
+func TestStageOutput(t *testing.T) {
+	var (
+		out    bytes.Buffer
+		output = &stageOutput{w: &out}
+	)
+	output.Write([]byte("held "))
+	if out.Len() != 0 {
+		t.Fatalf("Expected the output to be held, got %q", out.String())
+	}
+	if err := output.flush(); err != nil {
+		t.Fatal(err)
+	}
+	output.Write([]byte("live"))
+	if out.String() != "held live" {
+		t.Fatalf("Expected the held output then the live one, got %q", out.String())
+	}
+}
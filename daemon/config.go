@@ -35,7 +35,31 @@ type Config struct {
 	Mtu                         int
 	DisableNetwork              bool
 	EnableSelinuxSupport        bool
+	NameTemplate                string
+	TmpDir                      string
+	TmpDirSize                  string
+	TraceImage                  string
+	HostCommands                bool
+	BindAllow                   []string
+	BindDeny                    []string
+	VolumesPolicy               string
+	EventsWebhooks              []string
+	MigrateDryRun               bool
+	StatsInterval               int
+	CgroupWriteRetries          int
+	MemoryWarning               int
+	MemoryPressure              []string
+	StartupDefer                []string
+	RegistryAddr                string
+	Experimental                bool
+	Features                    []string
 	Context                     map[string][]string
+	// the certificates of --tlsverify, which the daemon also authenticates
+	// with to the daemons it transfers images to
+	TlsVerify bool
+	TlsCa     string
+	TlsCert   string
+	TlsKey    string
 }
 
 // InstallFlags adds command-line options to the top-level flag parser for
@@ -53,10 +77,28 @@ func (config *Config) InstallFlags() {
 	flag.BoolVar(&config.InterContainerCommunication, []string{"#icc", "-icc"}, true, "Enable inter-container communication")
 	flag.StringVar(&config.GraphDriver, []string{"s", "-storage-driver"}, "", "Force the Docker runtime to use a specific storage driver")
 	flag.StringVar(&config.ExecDriver, []string{"e", "-exec-driver"}, "native", "Force the Docker runtime to use a specific exec driver")
+	flag.BoolVar(&config.MigrateDryRun, []string{"-migrate-dry-run"}, false, "Show the migrations of the state under the graph directory an upgrade would run, and exit without running them")
+	flag.BoolVar(&config.Experimental, []string{"-experimental"}, false, "Enable all the experimental features, see --feature")
+	flag.BoolVar(&config.HostCommands, []string{"-host-commands"}, false, "Allow the containers to run hooks and host services on the host, as root")
 	flag.BoolVar(&config.EnableSelinuxSupport, []string{"-selinux-enabled"}, false, "Enable selinux support. SELinux does not presently support the BTRFS storage driver")
+	flag.StringVar(&config.NameTemplate, []string{"-name-template"}, "", "Template for the names given to containers created without --name (e.g. web-{{.Seq}})\nfields: {{.Random}}, {{.Seq}}, {{.ID}}")
+	flag.StringVar(&config.TmpDir, []string{"-tmpdir"}, "", "Path to use for the scratch data of builds and imports, default $DOCKER_TMPDIR or <graph>/tmp")
+	flag.StringVar(&config.TmpDirSize, []string{"-tmpdir-size"}, "", "Maximum size of the scratch data of a single build (format: <number><optional unit>, where unit = b, k, m or g)")
+	flag.StringVar(&config.TraceImage, []string{"-trace-image"}, "", "Image with strace and perf to trace the processes of containers with, for docker trace")
+	flag.StringVar(&config.VolumesPolicy, []string{"-volumes-policy"}, "keep", "What happens to the anonymous volumes of the removed containers by default (remove, keep, keep:DAYS)")
+	flag.StringVar(&config.RegistryAddr, []string{"-registry-addr"}, "", "Serve the local images read-only over the v2 registry protocol on this address (e.g. 0.0.0.0:5000)")
+	flag.IntVar(&config.StatsInterval, []string{"-stats-interval"}, 1, "Seconds between the samples of the cgroup stats of the running containers, 0 to only read them when requested")
+	flag.IntVar(&config.MemoryWarning, []string{"-memory-warning"}, 0, "Percentage of its memory limit a container is warned about when its memory usage grows over it, 0 to disable")
+	flag.IntVar(&config.CgroupWriteRetries, []string{"-cgroup-write-retries"}, 3, "Number of times a write of the cpusets or of the memory limits of a container is retried while the kernel is busy")
 	flag.IntVar(&config.Mtu, []string{"#mtu", "-mtu"}, 0, "Set the containers network MTU\nif no value is provided: default to the default route MTU or 1500 if no default route is available")
 	opts.IPVar(&config.DefaultIp, []string{"#ip", "-ip"}, "0.0.0.0", "Default IP address to use when binding container ports")
 	opts.ListVar(&config.GraphOptions, []string{"-storage-opt"}, "Set storage driver options")
+	opts.ListVar(&config.BindAllow, []string{"-bind-allow"}, "Allow bind mounting the host paths under this one, the others are denied once one is given")
+	opts.ListVar(&config.BindDeny, []string{"-bind-deny"}, "Deny bind mounting the host paths under this one, along with /, /etc and the graph directory")
+	opts.ListVar(&config.MemoryPressure, []string{"-memory-pressure"}, "Log a memory-pressure-LEVEL event each time the memory pressure of a container reaches this level (low, medium, critical)")
+	opts.ListVar(&config.Features, []string{"-feature"}, "Enable an experimental feature, or disable it with NAME=false (registry)")
+	opts.ListVar(&config.StartupDefer, []string{"-startup-defer"}, "Run a step of the startup in the background instead of before the API is served (restarts, gc)")
+	opts.ListVar(&config.EventsWebhooks, []string{"-events-webhook"}, "Post the events to an HTTP endpoint (format: URL[,type=container|image][,event=EVENT][,secret-file=FILE])")
 	// FIXME: why the inconsistency between "hosts" and "sockets"?
 	opts.IPListVar(&config.Dns, []string{"#dns", "-dns"}, "Force Docker to use specific DNS servers")
 	opts.DnsSearchListVar(&config.DnsSearch, []string{"-dns-search"}, "Force Docker to use specific DNS search domains")
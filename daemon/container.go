@@ -6,14 +6,18 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/cgroups/fs"
 	"github.com/docker/libcontainer/devices"
 	"github.com/docker/libcontainer/label"
 
@@ -29,6 +33,7 @@ import (
 	"github.com/docker/docker/pkg/networkfs/etchosts"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
 )
@@ -44,6 +49,10 @@ var (
 
 type Container struct {
 	sync.Mutex
+	// freezer is held while the container is paused or unpaused, and while
+	// its limits are written for a paused container to stay frozen meanwhile
+	freezer sync.Mutex
+
 	root   string // Path to the "home" of the container, including metadata.
 	basefs string // Path to the graphdriver mountpoint
 
@@ -82,9 +91,19 @@ type Container struct {
 	// Easier than migrating older container configs :)
 	VolumesRW  map[string]bool
 	hostConfig *runconfig.HostConfig
+	// The cpuset and the cpuset of memory nodes the daemon placed the
+	// container on when it was given none, from its affinities
+	PlacedCpuset     string
+	PlacedCpusetMems string
 
 	activeLinks map[string]*links.Link
 	monitor     *containerMonitor
+	notifyConn  *net.UnixConn
+	secrets     bool
+	sshAgent    string
+
+	watchdog     *watchdog
+	watchdogLock sync.Mutex
 }
 
 func (container *Container) FromDisk() error {
@@ -172,8 +191,21 @@ func (container *Container) WriteHostConfig() error {
 }
 
 func (container *Container) LogEvent(action string) {
+	container.LogEventAttributes(action, nil)
+}
+
+// LogEventAttributes logs the event action with the details attributes
+// attached, such as the old and new values of the limits an update changed.
+func (container *Container) LogEventAttributes(action string, attributes map[string]string) {
 	d := container.daemon
-	if err := d.eng.Job("log", action, container.ID, d.Repositories().ImageName(container.Image)).Run(); err != nil {
+	job := d.eng.Job("log", action, container.ID, d.Repositories().ImageName(container.Image))
+	if len(attributes) != 0 {
+		if err := job.SetenvJson("attributes", attributes); err != nil {
+			log.Errorf("Error logging event %s for %s: %s", action, container.ID, err)
+			return
+		}
+	}
+	if err := job.Run(); err != nil {
 		log.Errorf("Error logging event %s for %s: %s", action, container.ID, err)
 	}
 }
@@ -243,11 +275,84 @@ func populateCommand(c *Container, env []string) error {
 	// TODO: this can be removed after lxc-conf is fully deprecated
 	mergeLxcConfIntoOptions(c.hostConfig, context)
 
+	readIOps, err := getBlkioThrottleDevices(c.hostConfig.BlkioDeviceReadIOps)
+	if err != nil {
+		return err
+	}
+	writeIOps, err := getBlkioThrottleDevices(c.hostConfig.BlkioDeviceWriteIOps)
+	if err != nil {
+		return err
+	}
+
+	if s := c.hostConfig.MemorySwappiness; s != nil && (*s < 0 || *s > 100) {
+		return fmt.Errorf("Invalid memory swappiness %d, must be between 0 and 100", *s)
+	}
+	if err := validateCpuBandwidth(c.hostConfig.CpuQuota, c.hostConfig.CpuPeriod); err != nil {
+		return err
+	}
+	if err := validateCpuBurst(c.hostConfig.CpuBurst, c.hostConfig.CpuQuota); err != nil {
+		return err
+	}
+	if err := validateCpuRt(c.hostConfig.CpuRtRuntime, c.hostConfig.CpuRtPeriod); err != nil {
+		return err
+	}
+	if err := validateBlkioWeight(c.hostConfig.BlkioWeight); err != nil {
+		return err
+	}
+	if c.hostConfig.CpusetCpuExclusive && c.Config.Cpuset == "" {
+		return fmt.Errorf("Exclusive cpus need a cpuset, the container can't have all the cpus of the host")
+	}
+	if c.hostConfig.CpusetMemExclusive && c.hostConfig.CpusetMems == "" {
+		return fmt.Errorf("Exclusive memory nodes need a cpuset of memory nodes, the container can't have all the memory nodes of the host")
+	}
+
+	hugetlbLimit, err := getHugetlbLimits(c.hostConfig.HugetlbLimits)
+	if err != nil {
+		return err
+	}
+	netClsClassid, err := getNetClsClassid(c.hostConfig.NetClsClassid)
+	if err != nil {
+		return err
+	}
+	netPrioIfpriomap, err := getNetPrioIfpriomap(c.hostConfig.NetPrioIfpriomap)
+	if err != nil {
+		return err
+	}
+
 	resources := &execdriver.Resources{
-		Memory:     c.Config.Memory,
-		MemorySwap: c.Config.MemorySwap,
-		CpuShares:  c.Config.CpuShares,
-		Cpuset:     c.Config.Cpuset,
+		Memory:       c.Config.Memory,
+		MemorySwap:   c.Config.MemorySwap,
+		CpuShares:    c.Config.CpuShares,
+		CpuQuota:     c.hostConfig.CpuQuota,
+		CpuPeriod:    c.hostConfig.CpuPeriod,
+		CpuBurst:     c.hostConfig.CpuBurst,
+		CpuRtRuntime: c.hostConfig.CpuRtRuntime,
+		CpuRtPeriod:  c.hostConfig.CpuRtPeriod,
+		Cpuset:       c.Config.Cpuset,
+		CpusetMems:   c.hostConfig.CpusetMems,
+
+		CpusetCpuExclusive: c.hostConfig.CpusetCpuExclusive,
+		CpusetMemExclusive: c.hostConfig.CpusetMemExclusive,
+
+		BlkioWeight:                  c.hostConfig.BlkioWeight,
+		BlkioThrottleReadIOpsDevice:  readIOps,
+		BlkioThrottleWriteIOpsDevice: writeIOps,
+
+		MemoryReservation: c.hostConfig.MemoryReservation,
+		KernelMemory:      c.hostConfig.KernelMemory,
+		KernelMemoryTCP:   c.hostConfig.KernelMemoryTCP,
+		MemorySwappiness:  c.hostConfig.MemorySwappiness,
+		OomKillDisable:    c.hostConfig.OomKillDisable,
+		PidsLimit:         c.hostConfig.PidsLimit,
+		HugetlbLimit:      hugetlbLimit,
+		NetClsClassid:     netClsClassid,
+		NetPrioIfpriomap:  netPrioIfpriomap,
+	}
+	if resources.Cpuset == "" {
+		resources.Cpuset = c.PlacedCpuset
+	}
+	if resources.CpusetMems == "" {
+		resources.CpusetMems = c.PlacedCpusetMems
 	}
 	c.command = &execdriver.Command{
 		ID:                 c.ID,
@@ -272,6 +377,170 @@ func populateCommand(c *Container, env []string) error {
 	return nil
 }
 
+// getBlkioThrottleDevices resolves the device paths of /path/to/device:rate
+// throttling specs into the "major:minor rate" entries the blkio cgroup expects,
+// the rate being a number of IO per second, 0 for unlimited.
+func getBlkioThrottleDevices(specs []string) ([]string, error) {
+	var entries []string
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("Bad parameter, invalid device throttling specification: %s", spec)
+		}
+		if _, err := strconv.ParseUint(spec[i+1:], 10, 64); err != nil {
+			return nil, fmt.Errorf("Bad parameter, invalid rate %q of %s, must be a number of IO per second", spec[i+1:], spec[:i])
+		}
+		device, err := devices.GetDevice(spec[:i], "")
+		if err != nil {
+			return nil, fmt.Errorf("error gathering device information for %s: %s", spec[:i], err)
+		}
+		if device.Type != 'b' {
+			return nil, fmt.Errorf("%s is not a block device", spec[:i])
+		}
+		entries = append(entries, fmt.Sprintf("%d:%d %s", device.MajorNumber, device.MinorNumber, spec[i+1:]))
+	}
+	return entries, nil
+}
+
+// validateCpuBandwidth checks the CFS quota and period in microseconds, 0
+// leaves them unset and a quota of -1 removes the limit.
+func validateCpuBandwidth(quota, period int64) error {
+	if quota != 0 && quota != -1 && quota < 1000 {
+		return fmt.Errorf("Invalid CPU quota %d, must be at least 1000 microseconds or -1 for unlimited", quota)
+	}
+	if period != 0 && (period < 1000 || period > 1000000) {
+		return fmt.Errorf("Invalid CPU period %d, must be between 1000 and 1000000 microseconds", period)
+	}
+	return nil
+}
+
+// validateCpuBurst checks the burst in microseconds against the CFS quota,
+// the kernel keeps the burst under it. 0 is no burst.
+func validateCpuBurst(burst, quota int64) error {
+	if burst == 0 {
+		return nil
+	}
+	if quota <= 0 {
+		return fmt.Errorf("A CPU burst needs a CPU quota, the container accumulates the quota it doesn't use")
+	}
+	if burst < 0 || burst > quota {
+		return fmt.Errorf("Invalid CPU burst %d, must be between 0 and the CPU quota (%d microseconds)", burst, quota)
+	}
+	return nil
+}
+
+// validateCpuRt checks the real-time runtime and period in microseconds, 0
+// leaves them unset and the period defaults to the one of the host.
+func validateCpuRt(runtime, period int64) error {
+	if period != 0 && (period < 1000 || period > 1000000) {
+		return fmt.Errorf("Invalid CPU real-time period %d, must be between 1000 and 1000000 microseconds", period)
+	}
+	max := period
+	if max == 0 {
+		max = 1000000
+	}
+	if runtime < 0 || runtime > max {
+		return fmt.Errorf("Invalid CPU real-time runtime %d, must be between 0 and the period (%d microseconds)", runtime, max)
+	}
+	return nil
+}
+
+// validateBlkioWeight checks a blkio weight, 0 leaves it to the default of
+// the host.
+func validateBlkioWeight(weight int64) error {
+	if weight != 0 && (weight < 10 || weight > 1000) {
+		return fmt.Errorf("Invalid blkio weight %d, must be between 10 and 1000", weight)
+	}
+	return nil
+}
+
+// getHugetlbLimits converts pagesize:limit specs into limits in bytes keyed by
+// the page size names of the hugetlb cgroup.
+func getHugetlbLimits(specs []string) (map[string]int64, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	limits := make(map[string]int64)
+	for _, spec := range specs {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid hugetlb limit specification: %s", spec)
+		}
+		size, err := units.RAMInBytes(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		limit, err := units.RAMInBytes(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		pageSize := cgroups.HugePageSizeName(size)
+		if !isSupportedHugePageSize(pageSize) {
+			return nil, fmt.Errorf("Huge page size %s is not supported by the host", pageSize)
+		}
+		limits[pageSize] = limit
+	}
+	return limits, nil
+}
+
+func isSupportedHugePageSize(pageSize string) bool {
+	for _, size := range fs.HugePageSizes {
+		if size == pageSize {
+			return true
+		}
+	}
+	return false
+}
+
+// getNetClsClassid converts a class id given either as a tc handle (i.e.
+// 10:1, in hex like tc does) or as a number into the value net_cls.classid
+// expects.
+func getNetClsClassid(classid string) (string, error) {
+	if classid == "" {
+		return "", nil
+	}
+	var value uint64
+	if parts := strings.Split(classid, ":"); len(parts) == 2 {
+		major, err := strconv.ParseUint(parts[0], 16, 16)
+		if err != nil {
+			return "", fmt.Errorf("Invalid net_cls class id: %s", classid)
+		}
+		minor, err := strconv.ParseUint(parts[1], 16, 16)
+		if err != nil {
+			return "", fmt.Errorf("Invalid net_cls class id: %s", classid)
+		}
+		value = major<<16 | minor
+	} else {
+		v, err := strconv.ParseUint(classid, 0, 32)
+		if err != nil {
+			return "", fmt.Errorf("Invalid net_cls class id: %s", classid)
+		}
+		value = v
+	}
+	return strconv.FormatUint(value, 10), nil
+}
+
+// getNetPrioIfpriomap converts interface:priority specs into priorities keyed
+// by interface name.
+func getNetPrioIfpriomap(specs []string) (map[string]int64, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	prios := make(map[string]int64)
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid network priority specification: %s", spec)
+		}
+		prio, err := strconv.ParseUint(spec[i+1:], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid network priority for %s: %s", spec[:i], spec[i+1:])
+		}
+		prios[spec[:i]] = int64(prio)
+	}
+	return prios, nil
+}
+
 func (container *Container) Start() (err error) {
 	container.Lock()
 	defer container.Unlock()
@@ -309,6 +578,30 @@ func (container *Container) Start() (err error) {
 		return err
 	}
 	env := container.createDaemonEnvironment(linkedEnv)
+	if container.hostConfig.Notify || container.hostConfig.Watchdog.Interval > 0 {
+		if err := container.listenNotify(); err != nil {
+			return err
+		}
+		env = append(env, "NOTIFY_SOCKET="+notifySocketPath)
+	}
+	if container.sshAgent != "" {
+		env = append(env, "SSH_AUTH_SOCK="+sshAgentPath)
+	}
+	if interval := container.hostConfig.Watchdog.Interval; interval > 0 {
+		if _, err := runconfig.ParseWatchdogAction(container.hostConfig.Watchdog.Action); err != nil {
+			return err
+		}
+		// in microseconds, like systemd gives it to its services
+		env = append(env, fmt.Sprintf("WATCHDOG_USEC=%d", interval*1000000))
+	}
+	for _, service := range container.hostConfig.HostServices {
+		if err := runconfig.ValidateHostService(service); err != nil {
+			return err
+		}
+	}
+	if err := container.daemon.place(container); err != nil {
+		return err
+	}
 	if err := populateCommand(container, env); err != nil {
 		return err
 	}
@@ -510,6 +803,9 @@ func (container *Container) cleanup() {
 	if err := container.Unmount(); err != nil {
 		log.Errorf("%v: Failed to umount filesystem: %v", container.ID, err)
 	}
+
+	container.closeNotify()
+	container.daemon.volumeLocks.releaseAll(container.ID)
 }
 
 func (container *Container) KillSig(sig int) error {
@@ -541,6 +837,8 @@ func (container *Container) KillSig(sig int) error {
 }
 
 func (container *Container) Pause() error {
+	container.freezer.Lock()
+	defer container.freezer.Unlock()
 	if container.State.IsPaused() {
 		return fmt.Errorf("Container %s is already paused", container.ID)
 	}
@@ -551,6 +849,8 @@ func (container *Container) Pause() error {
 }
 
 func (container *Container) Unpause() error {
+	container.freezer.Lock()
+	defer container.freezer.Unlock()
 	if !container.State.IsPaused() {
 		return fmt.Errorf("Container %s is not paused", container.ID)
 	}
@@ -942,6 +1242,10 @@ func (container *Container) verifyDaemonSettings() {
 		log.Infof("WARNING: Your kernel does not support swap limit capabilities. Limitation discarded.")
 		container.Config.MemorySwap = -1
 	}
+	if container.hostConfig.CpuBurst != 0 && !container.daemon.sysInfo.CpuCfsBurst {
+		log.Infof("WARNING: Your kernel does not support CFS burst. Burst discarded.")
+		container.hostConfig.CpuBurst = 0
+	}
 	if container.daemon.sysInfo.IPv4ForwardingDisabled {
 		log.Infof("WARNING: IPv4 forwarding is disabled. Networking will not work")
 	}
@@ -974,6 +1278,14 @@ func (container *Container) setupLinkedContainers() ([]string, error) {
 				return nil, fmt.Errorf("Cannot link to a non running container: %s AS %s", child.Name, linkAlias)
 			}
 
+			// start after the linked containers using a notify socket are ready
+			if child.hostConfig != nil && child.hostConfig.Notify {
+				if err := child.State.WaitReady(linkReadyTimeout); err != nil {
+					rollback()
+					return nil, fmt.Errorf("Linked container %s AS %s is not ready: %s", child.Name, linkAlias, err)
+				}
+			}
+
 			link, err := links.NewLink(
 				container.NetworkSettings.IPAddress,
 				child.NetworkSettings.IPAddress,
@@ -1,8 +1,10 @@
 package daemon
 
 import (
-	"github.com/docker/docker/nat"
+	"strings"
 	"testing"
+
+	"github.com/docker/docker/nat"
 )
 
 func TestParseNetworkOptsPrivateOnly(t *testing.T) {
@@ -178,3 +180,110 @@ func TestGetFullName(t *testing.T) {
 		t.Fatal("Error should not be nil")
 	}
 }
+
+func TestGetNetClsClassid(t *testing.T) {
+	for classid, expected := range map[string]string{
+		"":          "",
+		"10:1":      "1048577",
+		"ffff:ffff": "4294967295",
+		"0x100001":  "1048577",
+		"1048577":   "1048577",
+	} {
+		value, err := getNetClsClassid(classid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Fatalf("Expected %q to be %q, got %q", classid, expected, value)
+		}
+	}
+
+	for _, classid := range []string{"10:", "10:1:1", "10000:1", "tc", "4294967296"} {
+		if _, err := getNetClsClassid(classid); err == nil {
+			t.Fatalf("Expected %q to be an invalid class id", classid)
+		}
+	}
+}
+
+func TestGetNetPrioIfpriomap(t *testing.T) {
+	prios, err := getNetPrioIfpriomap([]string{"eth0:5", "eth0:1:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prios) != 2 || prios["eth0"] != 5 || prios["eth0:1"] != 2 {
+		t.Fatalf("Unexpected network priorities: %v", prios)
+	}
+
+	for _, spec := range []string{"eth0", ":5", "eth0:high"} {
+		if _, err := getNetPrioIfpriomap([]string{spec}); err == nil {
+			t.Fatalf("Expected %q to be an invalid network priority", spec)
+		}
+	}
+}
+
+func TestValidateCpuBandwidth(t *testing.T) {
+	for _, valid := range [][2]int64{{0, 0}, {-1, 0}, {50000, 100000}, {1000, 1000}, {200000, 1000000}} {
+		if err := validateCpuBandwidth(valid[0], valid[1]); err != nil {
+			t.Fatalf("Expected quota %d and period %d to be valid: %s", valid[0], valid[1], err)
+		}
+	}
+	for _, invalid := range [][2]int64{{999, 0}, {-2, 0}, {0, 999}, {0, 1000001}, {50000, -1}} {
+		if err := validateCpuBandwidth(invalid[0], invalid[1]); err == nil {
+			t.Fatalf("Expected quota %d and period %d to be invalid", invalid[0], invalid[1])
+		}
+	}
+}
+
+func TestValidateCpuRt(t *testing.T) {
+	for _, valid := range [][2]int64{{0, 0}, {950000, 0}, {10000, 100000}, {1000, 1000}} {
+		if err := validateCpuRt(valid[0], valid[1]); err != nil {
+			t.Fatalf("Expected runtime %d and period %d to be valid: %s", valid[0], valid[1], err)
+		}
+	}
+	for _, invalid := range [][2]int64{{-1, 0}, {2000000, 0}, {200000, 100000}, {0, 999}} {
+		if err := validateCpuRt(invalid[0], invalid[1]); err == nil {
+			t.Fatalf("Expected runtime %d and period %d to be invalid", invalid[0], invalid[1])
+		}
+	}
+}
+
+func TestValidateBlkioWeight(t *testing.T) {
+	for _, weight := range []int64{0, 10, 500, 1000} {
+		if err := validateBlkioWeight(weight); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, weight := range []int64{-1, 9, 1001} {
+		if err := validateBlkioWeight(weight); err == nil {
+			t.Fatalf("Expected %d to be an invalid blkio weight", weight)
+		}
+	}
+	if entries := throttleEntries(map[string]int64{"8:0": 1000}); len(entries) != 1 || entries[0] != "8:0 1000" {
+		t.Fatalf("Unexpected throttling entries %v", entries)
+	}
+}
+
+func TestGetBlkioThrottleDevices(t *testing.T) {
+	for _, spec := range []string{"/dev/sda", "/dev/sda:", "/dev/sda:fast", "/dev/sda:-100", "/dev/sda:1.5"} {
+		if _, err := getBlkioThrottleDevices([]string{spec}); err == nil || !strings.Contains(err.Error(), "Bad parameter") {
+			t.Fatalf("Expected %s to be refused as a bad parameter, got %v", spec, err)
+		}
+	}
+	// the rate is fine, the device is not a block one
+	if _, err := getBlkioThrottleDevices([]string{"/dev/null:1000"}); err == nil || !strings.Contains(err.Error(), "not a block device") {
+		t.Fatalf("Expected /dev/null to be refused as not a block device, got %v", err)
+	}
+}
+
+func TestValidateCpuBurst(t *testing.T) {
+	for _, valid := range [][2]int64{{0, 0}, {0, -1}, {20000, 50000}, {50000, 50000}} {
+		if err := validateCpuBurst(valid[0], valid[1]); err != nil {
+			t.Fatalf("Expected burst %d and quota %d to be valid: %s", valid[0], valid[1], err)
+		}
+	}
+	for _, invalid := range [][2]int64{{20000, 0}, {20000, -1}, {-1, 50000}, {60000, 50000}} {
+		if err := validateCpuBurst(invalid[0], invalid[1]); err == nil {
+			t.Fatalf("Expected burst %d and quota %d to be invalid", invalid[0], invalid[1])
+		}
+	}
+}
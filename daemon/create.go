@@ -54,10 +54,13 @@ func (daemon *Daemon) ContainerCreate(job *engine.Job) engine.Status {
 }
 
 // Create creates a new container from the given configuration with a given name.
+// When it fails, what was created for the container is removed: its name,
+// its directory and its layers.
 func (daemon *Daemon) Create(config *runconfig.Config, name string) (*Container, []string, error) {
 	var (
 		container *Container
 		warnings  []string
+		tx        = &transaction{}
 	)
 
 	img, err := daemon.repositories.LookupImage(config.Image)
@@ -70,16 +73,20 @@ func (daemon *Daemon) Create(config *runconfig.Config, name string) (*Container,
 	if warnings, err = daemon.mergeAndVerifyConfig(config, img); err != nil {
 		return nil, nil, err
 	}
-	if container, err = daemon.newContainer(name, config, img); err != nil {
+	if container, err = daemon.newContainer(name, config, img, tx); err != nil {
+		tx.rollback()
 		return nil, nil, err
 	}
-	if err := daemon.createRootfs(container, img); err != nil {
+	if err := daemon.createRootfs(container, img, tx); err != nil {
+		tx.rollback()
 		return nil, nil, err
 	}
 	if err := container.ToDisk(); err != nil {
+		tx.rollback()
 		return nil, nil, err
 	}
 	if err := daemon.Register(container); err != nil {
+		tx.rollback()
 		return nil, nil, err
 	}
 	return container, warnings, nil
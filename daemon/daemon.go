@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/docker/libcontainer/cgroups/fs"
 	"github.com/docker/libcontainer/label"
 
 	"github.com/docker/docker/archive"
@@ -35,6 +36,7 @@ import (
 	"github.com/docker/docker/pkg/parsers/kernel"
 	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/docker/pkg/truncindex"
+	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
 )
@@ -94,6 +96,16 @@ type Daemon struct {
 	containerGraph *graphdb.Database
 	driver         graphdriver.Driver
 	execDriver     execdriver.Driver
+	nameGenerator  *namesgenerator.Generator
+	scratchSize    int64 // Maximum size of the scratch data of an operation, 0 for unlimited
+	volumesPolicy  runconfig.VolumesPolicy
+	volumeLocks    *volumeLocks
+	requestTokens  *requestTokens
+	lifecycle      *lifecycleMetrics
+	stats          *statsCollector
+	startup        *startupProfile
+	features       *features
+	registry       *graph.RegistryServer // nil without --registry-addr
 }
 
 // Install installs daemon capabilities to eng.
@@ -102,28 +114,44 @@ func (daemon *Daemon) Install(eng *engine.Engine) error {
 	// FIXME: rename ContainerDestroy to ContainerRm for consistency with the CLI command
 	// FIXME: remove ImageDelete's dependency on Daemon, then move to graph/
 	for name, method := range map[string]engine.Handler{
-		"attach":            daemon.ContainerAttach,
-		"build":             daemon.CmdBuild,
-		"commit":            daemon.ContainerCommit,
-		"container_changes": daemon.ContainerChanges,
-		"container_copy":    daemon.ContainerCopy,
-		"container_inspect": daemon.ContainerInspect,
-		"containers":        daemon.Containers,
-		"create":            daemon.ContainerCreate,
-		"delete":            daemon.ContainerDestroy,
-		"export":            daemon.ContainerExport,
-		"info":              daemon.CmdInfo,
-		"kill":              daemon.ContainerKill,
-		"logs":              daemon.ContainerLogs,
-		"pause":             daemon.ContainerPause,
-		"resize":            daemon.ContainerResize,
-		"restart":           daemon.ContainerRestart,
-		"start":             daemon.ContainerStart,
-		"stop":              daemon.ContainerStop,
-		"top":               daemon.ContainerTop,
-		"unpause":           daemon.ContainerUnpause,
-		"wait":              daemon.ContainerWait,
-		"image_delete":      daemon.ImageDelete, // FIXME: see above
+		"attach":             daemon.ContainerAttach,
+		"build":              daemon.CmdBuild,
+		"commit":             daemon.ContainerCommit,
+		"container_changes":  daemon.ContainerChanges,
+		"container_watch":    daemon.ContainerWatch,
+		"container_copy":     daemon.ContainerCopy,
+		"container_inspect":  daemon.ContainerInspect,
+		"containers":         daemon.Containers,
+		"containers_inspect": daemon.ContainersInspect,
+		"create":             daemon.idempotent(daemon.ContainerCreate),
+		"delete":             daemon.ContainerDestroy,
+		"export":             daemon.ContainerExport,
+		"features":           daemon.CmdFeatures,
+		"info":               daemon.CmdInfo,
+		"container_io":       daemon.ContainerIO,
+		"kill":               daemon.ContainerKill,
+		"limit":              daemon.idempotent(daemon.ContainerLimit),
+		"limits":             daemon.ContainerLimits,
+		"lifecycle_stats":    daemon.LifecycleStats,
+		"metrics":            daemon.Metrics,
+		"throttling":         daemon.ContainerThrottling,
+		"device":             daemon.ContainerDevice,
+		"logs":               daemon.ContainerLogs,
+		"pause":              daemon.ContainerPause,
+		"resize":             daemon.ContainerResize,
+		"restart":            daemon.ContainerRestart,
+		"start":              daemon.idempotent(daemon.ContainerStart),
+		"stop":               daemon.ContainerStop,
+		"storage_selftest":   daemon.StorageSelfTest,
+		"top":                daemon.ContainerTop,
+		"trace":              daemon.ContainerTrace,
+		"unpause":            daemon.ContainerUnpause,
+		"volumes_orphaned":   daemon.VolumesOrphaned,
+		"volume_lock":        daemon.ContainerVolumeLock,
+		"volume_unlock":      daemon.ContainerVolumeUnlock,
+		"volume_locks":       daemon.VolumeLocks,
+		"wait":               daemon.ContainerWait,
+		"image_delete":       daemon.ImageDelete, // FIXME: see above
 	} {
 		if err := eng.Register(name, method); err != nil {
 			return err
@@ -285,7 +313,9 @@ func (daemon *Daemon) LogToDisk(src *broadcastwriter.BroadcastWriter, dst, strea
 	return nil
 }
 
-func (daemon *Daemon) restore() error {
+// restore loads and registers the containers of the daemon, and returns
+// them to be restarted by restartContainers.
+func (daemon *Daemon) restore() ([]*Container, error) {
 	var (
 		debug         = (os.Getenv("DEBUG") != "" || os.Getenv("TEST") != "")
 		containers    = make(map[string]*Container)
@@ -297,7 +327,7 @@ func (daemon *Daemon) restore() error {
 	}
 	dir, err := ioutil.ReadDir(daemon.repository)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, v := range dir {
@@ -359,28 +389,31 @@ func (daemon *Daemon) restore() error {
 		registeredContainers = append(registeredContainers, container)
 	}
 
-	// check the restart policy on the containers and restart any container with
-	// the restart policy of "always"
-	if daemon.config.AutoRestart {
-		log.Debugf("Restarting containers...")
+	if !debug {
+		log.Infof(": done.")
+	}
 
-		for _, container := range registeredContainers {
-			if container.hostConfig.RestartPolicy.Name == "always" ||
-				(container.hostConfig.RestartPolicy.Name == "on-failure" && container.State.ExitCode != 0) {
-				log.Debugf("Starting container %s", container.ID)
+	return registeredContainers, nil
+}
 
-				if err := container.Start(); err != nil {
-					log.Debugf("Failed to start container %s: %s", container.ID, err)
-				}
-			}
-		}
+// restartContainers checks the restart policy on the containers and restarts
+// any container with the restart policy of "always"
+func (daemon *Daemon) restartContainers(containers []*Container) {
+	if !daemon.config.AutoRestart {
+		return
 	}
+	log.Debugf("Restarting containers...")
 
-	if !debug {
-		log.Infof(": done.")
-	}
+	for _, container := range containers {
+		if container.hostConfig.RestartPolicy.Name == "always" ||
+			(container.hostConfig.RestartPolicy.Name == "on-failure" && container.State.ExitCode != 0) {
+			log.Debugf("Starting container %s", container.ID)
 
-	return nil
+			if err := container.Start(); err != nil {
+				log.Debugf("Failed to start container %s: %s", container.ID, err)
+			}
+		}
+	}
 }
 
 func (daemon *Daemon) checkDeprecatedExpose(config *runconfig.Config) bool {
@@ -467,9 +500,27 @@ func (daemon *Daemon) reserveName(id, name string) (string, error) {
 }
 
 func (daemon *Daemon) generateNewName(id string) (string, error) {
-	var name string
-	for i := 0; i < 6; i++ {
-		name = namesgenerator.GetRandomName(i)
+	var (
+		name    string
+		retries = 6
+	)
+	if daemon.nameGenerator != nil {
+		// Sequential names start over when the daemon restarts, leave
+		// room to skip the ones already taken
+		retries = 1000
+	}
+	for i := 0; i < retries; i++ {
+		if daemon.nameGenerator == nil {
+			name = namesgenerator.GetRandomName(i)
+		} else {
+			var err error
+			if name, err = daemon.nameGenerator.Name(id, i); err != nil {
+				return "", err
+			}
+			if !validContainerNamePattern.MatchString(name) {
+				return "", fmt.Errorf("Invalid generated container name (%s), only %s are allowed", name, validContainerNameChars)
+			}
+		}
 		if name[0] != '/' {
 			name = "/" + name
 		}
@@ -513,7 +564,7 @@ func (daemon *Daemon) getEntrypointAndArgs(config *runconfig.Config) (string, []
 	return entrypoint, args
 }
 
-func (daemon *Daemon) newContainer(name string, config *runconfig.Config, img *image.Image) (*Container, error) {
+func (daemon *Daemon) newContainer(name string, config *runconfig.Config, img *image.Image, tx *transaction) (*Container, error) {
 	var (
 		id  string
 		err error
@@ -522,6 +573,9 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, img *i
 	if err != nil {
 		return nil, err
 	}
+	tx.onRollback("the reservation of the name "+name, func() error {
+		return daemon.containerGraph.Delete(name)
+	})
 
 	daemon.generateHostname(id, config)
 	entrypoint, args := daemon.getEntrypointAndArgs(config)
@@ -549,16 +603,22 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, img *i
 	return container, nil
 }
 
-func (daemon *Daemon) createRootfs(container *Container, img *image.Image) error {
+func (daemon *Daemon) createRootfs(container *Container, img *image.Image, tx *transaction) error {
 	// Step 1: create the container directory.
 	// This doubles as a barrier to avoid race conditions.
 	if err := os.Mkdir(container.root, 0700); err != nil {
 		return err
 	}
+	tx.onRollback("the creation of "+container.root, func() error {
+		return os.RemoveAll(container.root)
+	})
 	initID := fmt.Sprintf("%s-init", container.ID)
 	if err := daemon.driver.Create(initID, img.ID); err != nil {
 		return err
 	}
+	tx.onRollback("the creation of the init layer of "+container.ID, func() error {
+		return daemon.driver.Remove(initID)
+	})
 	initPath, err := daemon.driver.Get(initID, "")
 	if err != nil {
 		return err
@@ -572,6 +632,9 @@ func (daemon *Daemon) createRootfs(container *Container, img *image.Image) error
 	if err := daemon.driver.Create(container.ID, initID); err != nil {
 		return err
 	}
+	tx.onRollback("the creation of the layer of "+container.ID, func() error {
+		return daemon.driver.Remove(container.ID)
+	})
 	return nil
 }
 
@@ -686,6 +749,64 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	// FIXME: DisableNetworkBidge doesn't need to be public anymore
 	config.DisableNetwork = config.BridgeIface == DisableNetworkBridge
 
+	var nameGenerator *namesgenerator.Generator
+	if config.NameTemplate != "" {
+		var err error
+		if nameGenerator, err = namesgenerator.NewGenerator(config.NameTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	var scratchSize int64
+	if config.TmpDirSize != "" {
+		var err error
+		if scratchSize, err = units.RAMInBytes(config.TmpDirSize); err != nil || scratchSize <= 0 {
+			return nil, fmt.Errorf("Invalid --tmpdir-size %s, must be a positive size (format: <number><optional unit>, where unit = b, k, m or g)", config.TmpDirSize)
+		}
+	}
+
+	if config.StatsInterval < 0 {
+		return nil, fmt.Errorf("Invalid --stats-interval %d, must be a number of seconds, or 0 to only read the stats when requested", config.StatsInterval)
+	}
+
+	if config.MemoryWarning < 0 || config.MemoryWarning >= 100 {
+		return nil, fmt.Errorf("Invalid --memory-warning %d, must be a percentage of the memory limit under 100, or 0 to disable", config.MemoryWarning)
+	}
+
+	for _, level := range config.MemoryPressure {
+		if level != "low" && level != "medium" && level != "critical" {
+			return nil, fmt.Errorf("Invalid --memory-pressure %s, must be low, medium or critical", level)
+		}
+	}
+
+	if config.CgroupWriteRetries < 0 {
+		return nil, fmt.Errorf("Invalid --cgroup-write-retries %d, must be 0 or more", config.CgroupWriteRetries)
+	}
+	fs.WriteRetry.Retries = config.CgroupWriteRetries
+
+	startup, err := newStartupProfile(config.StartupDefer)
+	if err != nil {
+		return nil, err
+	}
+
+	features, err := newFeatures(config.Experimental, config.Features)
+	if err != nil {
+		return nil, err
+	}
+	if config.RegistryAddr != "" {
+		if err := features.require("registry", "--registry-addr"); err != nil {
+			return nil, err
+		}
+	}
+
+	var volumesPolicy runconfig.VolumesPolicy
+	if config.VolumesPolicy != "" {
+		var err error
+		if volumesPolicy, err = runconfig.ParseVolumesPolicy(config.VolumesPolicy); err != nil {
+			return nil, fmt.Errorf("Invalid --volumes-policy: %s", err)
+		}
+	}
+
 	// Claim the pidfile first, to avoid any and all unexpected race conditions.
 	// Some of the init doesn't need a pidfile lock - but let's not try to be smart.
 	if config.Pidfile != "" {
@@ -710,10 +831,16 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		log.Fatalf(err.Error())
 	}
 
-	// set up the TempDir to use a canonical path
-	tmp, err := utils.TempDir(config.Root)
+	// set up the TempDir to use a canonical path, --tmpdir takes precedence
+	// over DOCKER_TMPDIR
+	tmp := config.TmpDir
+	if tmp != "" {
+		err = os.MkdirAll(tmp, 0700)
+	} else {
+		tmp, err = utils.TempDir(config.Root)
+	}
 	if err != nil {
-		log.Fatalf("Unable to get the TempDir under %s: %s", config.Root, err)
+		log.Fatalf("Unable to create the TempDir %s: %s", tmp, err)
 	}
 	realTmp, err := utils.ReadSymlinkedDirectory(tmp)
 	if err != nil {
@@ -739,13 +866,21 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	if err := os.MkdirAll(config.Root, 0700); err != nil && !os.IsExist(err) {
 		return nil, err
 	}
+	if err := startup.time("state migrations", func() error {
+		return migrateState(config.Root)
+	}); err != nil {
+		return nil, err
+	}
 
 	// Set the default driver
 	graphdriver.DefaultDriver = config.GraphDriver
 
 	// Load storage driver
-	driver, err := graphdriver.New(config.Root, config.GraphOptions)
-	if err != nil {
+	var driver graphdriver.Driver
+	if err := startup.time("graph driver", func() (err error) {
+		driver, err = graphdriver.New(config.Root, config.GraphOptions)
+		return err
+	}); err != nil {
 		return nil, err
 	}
 	log.Debugf("Using graph driver %s", driver)
@@ -766,28 +901,42 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		return nil, err
 	}
 
-	log.Debugf("Creating images graph")
-	g, err := graph.NewGraph(path.Join(config.Root, "graph"), driver)
-	if err != nil {
-		return nil, err
-	}
+	var (
+		g, volumes   *graph.Graph
+		repositories *graph.TagStore
+	)
+	if err := startup.time("graphs", func() (err error) {
+		log.Debugf("Creating images graph")
+		g, err = graph.NewGraph(path.Join(config.Root, "graph"), driver)
+		if err != nil {
+			return err
+		}
 
-	// We don't want to use a complex driver like aufs or devmapper
-	// for volumes, just a plain filesystem
-	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions)
-	if err != nil {
-		return nil, err
-	}
-	log.Debugf("Creating volumes graph")
-	volumes, err := graph.NewGraph(path.Join(config.Root, "volumes"), volumesDriver)
-	if err != nil {
+		// We don't want to use a complex driver like aufs or devmapper
+		// for volumes, just a plain filesystem
+		volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Creating volumes graph")
+		volumes, err = graph.NewGraph(path.Join(config.Root, "volumes"), volumesDriver)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Creating repository list")
+		repositories, err = graph.NewTagStore(path.Join(config.Root, "repositories-"+driver.String()), g)
+		if err != nil {
+			return fmt.Errorf("Couldn't create Tag store: %s", err)
+		}
+		if config.TlsVerify {
+			if repositories.TransferTLS, err = graph.NewTransferTLS(config.TlsCa, config.TlsCert, config.TlsKey); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
 		return nil, err
 	}
-	log.Debugf("Creating repository list")
-	repositories, err := graph.NewTagStore(path.Join(config.Root, "repositories-"+driver.String()), g)
-	if err != nil {
-		return nil, fmt.Errorf("Couldn't create Tag store: %s", err)
-	}
 
 	if !config.DisableNetwork {
 		job := eng.Job("init_networkdriver")
@@ -799,7 +948,7 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		job.Setenv("BridgeIP", config.BridgeIP)
 		job.Setenv("DefaultBindingIP", config.DefaultIp.String())
 
-		if err := job.Run(); err != nil {
+		if err := startup.time("network", job.Run); err != nil {
 			return nil, err
 		}
 	}
@@ -850,13 +999,51 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		sysInitPath:    sysInitPath,
 		execDriver:     ed,
 		eng:            eng,
-	}
+		nameGenerator:  nameGenerator,
+		scratchSize:    scratchSize,
+		volumesPolicy:  volumesPolicy,
+		volumeLocks:    newVolumeLocks(),
+		requestTokens:  newRequestTokens(),
+		lifecycle:      newLifecycleMetrics(),
+		startup:        startup,
+		features:       features,
+	}
+	daemon.stats = newStatsCollector(time.Duration(config.StatsInterval)*time.Second, daemon.openStats)
 	if err := daemon.checkLocaldns(); err != nil {
 		return nil, err
 	}
-	if err := daemon.restore(); err != nil {
+	// before restoring, for the webhooks to get the events of the restarts
+	for _, webhook := range config.EventsWebhooks {
+		if err := eng.Job("events_webhook", webhook).Run(); err != nil {
+			return nil, err
+		}
+	}
+	var restored []*Container
+	if err := startup.time("containers", func() (err error) {
+		restored, err = daemon.restore()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if err := startup.run(startupRestarts, func() error {
+		daemon.restartContainers(restored)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := startup.run(startupGC, func() error {
+		daemon.startExecStateGC()
+		daemon.startVolumesReaper()
+		return nil
+	}); err != nil {
 		return nil, err
 	}
+	daemon.startStatsCollector()
+	if config.RegistryAddr != "" {
+		if err := daemon.serveRegistry(config.RegistryAddr); err != nil {
+			return nil, err
+		}
+	}
 	// Setup shutdown handlers
 	// FIXME: can these shutdown handlers be registered closer to their source?
 	eng.OnShutdown(func() {
@@ -877,6 +1064,7 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		}
 	})
 
+	startup.done()
 	return daemon, nil
 }
 
@@ -972,7 +1160,13 @@ func (daemon *Daemon) Run(c *Container, pipes *execdriver.Pipes, startCallback e
 
 func (daemon *Daemon) Pause(c *Container) error {
 	if err := daemon.execDriver.Pause(c.command); err != nil {
-		return err
+		if err != execdriver.ErrPauseNotSupported {
+			return err
+		}
+		// freeze the cgroup of the container ourselves
+		if err := fs.FreezeContainer(c.ID, daemon.cgroupParent()); err != nil {
+			return cgroupError(err)
+		}
 	}
 	c.State.SetPaused()
 	return nil
@@ -980,7 +1174,12 @@ func (daemon *Daemon) Pause(c *Container) error {
 
 func (daemon *Daemon) Unpause(c *Container) error {
 	if err := daemon.execDriver.Unpause(c.command); err != nil {
-		return err
+		if err != execdriver.ErrPauseNotSupported {
+			return err
+		}
+		if err := fs.ThawContainer(c.ID, daemon.cgroupParent()); err != nil {
+			return cgroupError(err)
+		}
 	}
 	c.State.SetUnpaused()
 	return nil
@@ -65,70 +65,96 @@ func (daemon *Daemon) ContainerDestroy(job *engine.Job) engine.Status {
 		}
 		container.LogEvent("destroy")
 
-		if removeVolume {
-			var (
-				volumes     = make(map[string]struct{})
-				binds       = make(map[string]struct{})
-				usedVolumes = make(map[string]*Container)
-			)
-
-			// the volume id is always the base of the path
-			getVolumeId := func(p string) string {
-				return filepath.Base(strings.TrimSuffix(p, "/layer"))
+		policy := daemon.containerVolumesPolicy(container)
+		if removeVolume || policy.Name == "remove" {
+			if err := daemon.removeVolumes(container); err != nil {
+				return job.Error(err)
 			}
+		} else if policy.KeepDays > 0 {
+			daemon.orphanVolumes(container, policy.KeepDays)
+		}
+	} else {
+		return job.Errorf("No such container: %s", name)
+	}
+	return engine.StatusOK
+}
 
-			// populate bind map so that they can be skipped and not removed
-			for _, bind := range container.HostConfig().Binds {
-				source := strings.Split(bind, ":")[0]
-				// TODO: refactor all volume stuff, all of it
-				// it is very important that we eval the link or comparing the keys to container.Volumes will not work
-				//
-				// eval symlink can fail, ref #5244 if we receive an is not exist error we can ignore it
-				p, err := filepath.EvalSymlinks(source)
-				if err != nil && !os.IsNotExist(err) {
-					return job.Error(err)
-				}
-				if p != "" {
-					source = p
-				}
-				binds[source] = struct{}{}
-			}
+// removeVolumes removes the volumes of a destroyed container which are not
+// bind mounts nor used by another container.
+func (daemon *Daemon) removeVolumes(container *Container) error {
+	volumes, err := containerVolumeIds(container)
+	if err != nil {
+		return err
+	}
+	usedVolumes := daemon.usedVolumes()
+	for volumeId := range volumes {
+		// If the requested volu
+		if c, exists := usedVolumes[volumeId]; exists {
+			log.Infof("The volume %s is used by the container %s. Impossible to remove it. Skipping.", volumeId, c.ID)
+			continue
+		}
+		if err := daemon.Volumes().Delete(volumeId); err != nil {
+			return fmt.Errorf("Error calling volumes.Delete(%q): %v", volumeId, err)
+		}
+	}
+	return nil
+}
 
-			// Store all the deleted containers volumes
-			for _, volumeId := range container.Volumes {
-				// Skip the volumes mounted from external
-				// bind mounts here will will be evaluated for a symlink
-				if _, exists := binds[volumeId]; exists {
-					continue
-				}
+// the volume id is always the base of the path
+func getVolumeId(p string) string {
+	return filepath.Base(strings.TrimSuffix(p, "/layer"))
+}
 
-				volumeId = getVolumeId(volumeId)
-				volumes[volumeId] = struct{}{}
-			}
+// containerVolumeIds returns the ids of the volumes of container which are
+// not bind mounts.
+func containerVolumeIds(container *Container) (map[string]struct{}, error) {
+	var (
+		volumes = make(map[string]struct{})
+		binds   = make(map[string]struct{})
+	)
+
+	// populate bind map so that they can be skipped and not removed
+	for _, bind := range container.HostConfig().Binds {
+		source := strings.Split(bind, ":")[0]
+		// TODO: refactor all volume stuff, all of it
+		// it is very important that we eval the link or comparing the keys to container.Volumes will not work
+		//
+		// eval symlink can fail, ref #5244 if we receive an is not exist error we can ignore it
+		p, err := filepath.EvalSymlinks(source)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if p != "" {
+			source = p
+		}
+		binds[source] = struct{}{}
+	}
 
-			// Retrieve all volumes from all remaining containers
-			for _, container := range daemon.List() {
-				for _, containerVolumeId := range container.Volumes {
-					containerVolumeId = getVolumeId(containerVolumeId)
-					usedVolumes[containerVolumeId] = container
-				}
-			}
+	// Store all the deleted containers volumes
+	for _, volumeId := range container.Volumes {
+		// Skip the volumes mounted from external
+		// bind mounts here will will be evaluated for a symlink
+		if _, exists := binds[volumeId]; exists {
+			continue
+		}
 
-			for volumeId := range volumes {
-				// If the requested volu
-				if c, exists := usedVolumes[volumeId]; exists {
-					log.Infof("The volume %s is used by the container %s. Impossible to remove it. Skipping.", volumeId, c.ID)
-					continue
-				}
-				if err := daemon.Volumes().Delete(volumeId); err != nil {
-					return job.Errorf("Error calling volumes.Delete(%q): %v", volumeId, err)
-				}
-			}
+		volumeId = getVolumeId(volumeId)
+		volumes[volumeId] = struct{}{}
+	}
+	return volumes, nil
+}
+
+// usedVolumes returns the containers of the daemon by the ids of the
+// volumes they use.
+func (daemon *Daemon) usedVolumes() map[string]*Container {
+	usedVolumes := make(map[string]*Container)
+	for _, container := range daemon.List() {
+		for _, containerVolumeId := range container.Volumes {
+			containerVolumeId = getVolumeId(containerVolumeId)
+			usedVolumes[containerVolumeId] = container
 		}
-	} else {
-		return job.Errorf("No such container: %s", name)
 	}
-	return engine.StatusOK
+	return usedVolumes
 }
 
 // Destroy unregisters a container from the daemon and cleanly removes its contents from the filesystem.
@@ -150,6 +176,7 @@ func (daemon *Daemon) Destroy(container *Container) error {
 	// Deregister the container before removing its directory, to avoid race conditions
 	daemon.idIndex.Delete(container.ID)
 	daemon.containers.Delete(container.ID)
+	daemon.lifecycle.forget(container.ID)
 
 	if _, err := daemon.containerGraph.Purge(container.ID); err != nil {
 		log.Debugf("Unable to remove container from link graph: %s", err)
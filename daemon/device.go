@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/libcontainer/cgroups/fs"
+	"github.com/docker/libcontainer/devices"
+)
+
+// ContainerDevice allows or denies the access of a running container to a
+// device of the host by writing to its devices cgroup. The device node
+// itself is not created in the container.
+func (daemon *Daemon) ContainerDevice(job *engine.Job) engine.Status {
+	if len(job.Args) != 3 {
+		return job.Errorf("Usage: %s allow|deny CONTAINER DEVICE", job.Name)
+	}
+	var (
+		action      = job.Args[0]
+		name        = job.Args[1]
+		path        = job.Args[2]
+		permissions = job.Getenv("permissions")
+	)
+	if action != "allow" && action != "deny" {
+		return job.Errorf("Bad parameter, invalid action %s, must be allow or deny", action)
+	}
+	if permissions == "" {
+		permissions = "rwm"
+	}
+	if strings.Trim(permissions, "rwm") != "" {
+		return job.Errorf("Bad parameter, invalid device permissions %s, must be a combination of r, w and m", permissions)
+	}
+
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+
+	device, err := devices.GetDevice(path, permissions)
+	if err != nil {
+		return job.Errorf("error gathering device information for %s: %s", path, err)
+	}
+	if err := fs.Set(container.ID, daemon.cgroupParent(), "devices."+action, device.GetCgroupAllowString()); err != nil {
+		return job.Errorf("Cannot %s %s for %s: %s", action, path, name, cgroupError(err))
+	}
+	container.LogEvent("device")
+	return engine.StatusOK
+}
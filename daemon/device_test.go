@@ -0,0 +1,33 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/engine"
+)
+
+func TestContainerDeviceArgs(t *testing.T) {
+	var (
+		daemon = &Daemon{}
+		eng    = engine.New()
+	)
+	if err := eng.Register("device", daemon.ContainerDevice); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		args        []string
+		permissions string
+		expected    string
+	}{
+		{[]string{"allow", "web"}, "", "Usage"},
+		{[]string{"grant", "web", "/dev/fuse"}, "", "Bad parameter, invalid action grant"},
+		{[]string{"allow", "web", "/dev/fuse"}, "rwx", "Bad parameter, invalid device permissions rwx"},
+	} {
+		job := eng.Job("device", c.args...)
+		job.Setenv("permissions", c.permissions)
+		if err := job.Run(); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Fatalf("Expected %v with the permissions %q to fail with %q, got %v", c.args, c.permissions, c.expected, err)
+		}
+	}
+}
@@ -18,6 +18,7 @@ var (
 	ErrWaitTimeoutReached      = errors.New("Wait timeout reached")
 	ErrDriverAlreadyRegistered = errors.New("A driver already registered this docker init function")
 	ErrDriverNotFound          = errors.New("The requested docker init has not been found")
+	ErrPauseNotSupported       = errors.New("The exec driver can not pause containers")
 )
 
 type StartCallback func(*Command)
@@ -51,6 +52,15 @@ type Driver interface {
 	Terminate(c *Command) error                   // kill it with fire
 }
 
+// StateCollector is implemented by the drivers which keep state on disk for
+// the containers they run, so the daemon can reclaim what is left behind by
+// containers that died without the driver cleaning up after them.
+type StateCollector interface {
+	// CollectState removes the state of the containers which are neither
+	// run by the driver nor alive anymore and returns their ids.
+	CollectState() ([]string, error)
+}
+
 // Network settings of the container
 type Network struct {
 	Interface      *NetworkInterface `json:"interface"` // if interface is nil then networking is disabled
@@ -67,10 +77,53 @@ type NetworkInterface struct {
 }
 
 type Resources struct {
-	Memory     int64  `json:"memory"`
-	MemorySwap int64  `json:"memory_swap"`
-	CpuShares  int64  `json:"cpu_shares"`
-	Cpuset     string `json:"cpuset"`
+	Memory       int64  `json:"memory"`
+	MemorySwap   int64  `json:"memory_swap"`
+	CpuShares    int64  `json:"cpu_shares"`
+	CpuQuota     int64  `json:"cpu_quota"`      // CFS quota in microseconds, -1 for unlimited
+	CpuPeriod    int64  `json:"cpu_period"`     // CFS period in microseconds
+	CpuBurst     int64  `json:"cpu_burst"`      // Unused CFS quota accumulated for bursts in microseconds
+	CpuRtRuntime int64  `json:"cpu_rt_runtime"` // Real-time CPU time per period in microseconds
+	CpuRtPeriod  int64  `json:"cpu_rt_period"`  // Real-time period in microseconds
+	Cpuset       string `json:"cpuset"`
+	CpusetMems   string `json:"cpuset_mems"` // Memory nodes in which to allow allocations
+
+	// Keep the cpus or the memory nodes of the cpuset from the other containers
+	CpusetCpuExclusive bool `json:"cpuset_cpu_exclusive"`
+	CpusetMemExclusive bool `json:"cpuset_mem_exclusive"`
+
+	// Relative weight of the block IO of the container, from 10 to 1000
+	BlkioWeight int64 `json:"blkio_weight"`
+
+	// Per-device IO/s limits in the kernel's "major:minor rate" format
+	BlkioThrottleReadIOpsDevice  []string `json:"blkio_throttle_read_iops_device"`
+	BlkioThrottleWriteIOpsDevice []string `json:"blkio_throttle_write_iops_device"`
+
+	PidsLimit int64 `json:"pids_limit"`
+
+	// Memory soft limit in bytes, the memory limit is used when it is not set
+	MemoryReservation int64 `json:"memory_reservation"`
+
+	// Kernel memory limit in bytes, it has to be set before the container starts
+	KernelMemory int64 `json:"kernel_memory"`
+
+	// Limit of the kernel memory used by the TCP buffers of the container
+	KernelMemoryTCP int64 `json:"kernel_memory_tcp"`
+
+	// Tendency to swap out the memory of the container, nil for the host default
+	MemorySwappiness *int64 `json:"memory_swappiness"`
+
+	// Pause the container instead of killing its processes when it runs out of memory
+	OomKillDisable bool `json:"oom_kill_disable"`
+
+	// Huge pages limits in bytes, by page size as named by the kernel
+	HugetlbLimit map[string]int64 `json:"hugetlb_limit"`
+
+	// Class id tagged on the packets of the container, as the kernel expects it
+	NetClsClassid string `json:"net_cls_classid"`
+
+	// Priority of the traffic of the container, by host network interface
+	NetPrioIfpriomap map[string]int64 `json:"net_prio_ifpriomap"`
 }
 
 type Mount struct {
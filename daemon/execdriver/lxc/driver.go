@@ -209,27 +209,23 @@ func (d *driver) Kill(c *execdriver.Command, sig int) error {
 }
 
 func (d *driver) Pause(c *execdriver.Command) error {
-	_, err := exec.LookPath("lxc-freeze")
-	if err == nil {
-		output, errExec := exec.Command("lxc-freeze", "-n", c.ID).CombinedOutput()
-		if errExec != nil {
-			return fmt.Errorf("Err: %s Output: %s", errExec, output)
-		}
+	if _, err := exec.LookPath("lxc-freeze"); err != nil {
+		return execdriver.ErrPauseNotSupported
 	}
-
-	return err
+	if output, err := exec.Command("lxc-freeze", "-n", c.ID).CombinedOutput(); err != nil {
+		return fmt.Errorf("Err: %s Output: %s", err, output)
+	}
+	return nil
 }
 
 func (d *driver) Unpause(c *execdriver.Command) error {
-	_, err := exec.LookPath("lxc-unfreeze")
-	if err == nil {
-		output, errExec := exec.Command("lxc-unfreeze", "-n", c.ID).CombinedOutput()
-		if errExec != nil {
-			return fmt.Errorf("Err: %s Output: %s", errExec, output)
-		}
+	if _, err := exec.LookPath("lxc-unfreeze"); err != nil {
+		return execdriver.ErrPauseNotSupported
 	}
-
-	return err
+	if output, err := exec.Command("lxc-unfreeze", "-n", c.ID).CombinedOutput(); err != nil {
+		return fmt.Errorf("Err: %s Output: %s", err, output)
+	}
+	return nil
 }
 
 func (d *driver) Terminate(c *execdriver.Command) error {
@@ -93,17 +93,64 @@ lxc.aa_profile = unconfined
 {{if .Resources}}
 {{if .Resources.Memory}}
 lxc.cgroup.memory.limit_in_bytes = {{.Resources.Memory}}
-lxc.cgroup.memory.soft_limit_in_bytes = {{.Resources.Memory}}
 {{with $memSwap := getMemorySwap .Resources}}
 lxc.cgroup.memory.memsw.limit_in_bytes = {{$memSwap}}
 {{end}}
 {{end}}
+{{with $softLimit := getSoftLimit .Resources}}
+lxc.cgroup.memory.soft_limit_in_bytes = {{$softLimit}}
+{{end}}
 {{if .Resources.CpuShares}}
 lxc.cgroup.cpu.shares = {{.Resources.CpuShares}}
 {{end}}
+{{if .Resources.CpuPeriod}}
+lxc.cgroup.cpu.cfs_period_us = {{.Resources.CpuPeriod}}
+{{end}}
+{{if .Resources.CpuQuota}}
+lxc.cgroup.cpu.cfs_quota_us = {{.Resources.CpuQuota}}
+{{end}}
+{{if .Resources.CpuBurst}}
+lxc.cgroup.cpu.cfs_burst_us = {{.Resources.CpuBurst}}
+{{end}}
 {{if .Resources.Cpuset}}
 lxc.cgroup.cpuset.cpus = {{.Resources.Cpuset}}
 {{end}}
+{{if .Resources.CpusetMems}}
+lxc.cgroup.cpuset.mems = {{.Resources.CpusetMems}}
+{{end}}
+{{if .Resources.BlkioWeight}}
+lxc.cgroup.blkio.weight = {{.Resources.BlkioWeight}}
+{{end}}
+{{range $entry := .Resources.BlkioThrottleReadIOpsDevice}}
+lxc.cgroup.blkio.throttle.read_iops_device = {{$entry}}
+{{end}}
+{{range $entry := .Resources.BlkioThrottleWriteIOpsDevice}}
+lxc.cgroup.blkio.throttle.write_iops_device = {{$entry}}
+{{end}}
+{{if .Resources.KernelMemory}}
+lxc.cgroup.memory.kmem.limit_in_bytes = {{.Resources.KernelMemory}}
+{{end}}
+{{if .Resources.KernelMemoryTCP}}
+lxc.cgroup.memory.kmem.tcp.limit_in_bytes = {{.Resources.KernelMemoryTCP}}
+{{end}}
+{{if .Resources.OomKillDisable}}
+lxc.cgroup.memory.oom_control = 1
+{{end}}
+{{if .Resources.MemorySwappiness}}
+lxc.cgroup.memory.swappiness = {{.Resources.MemorySwappiness}}
+{{end}}
+{{if gt .Resources.PidsLimit 0}}
+lxc.cgroup.pids.max = {{.Resources.PidsLimit}}
+{{end}}
+{{if .Resources.NetClsClassid}}
+lxc.cgroup.net_cls.classid = {{.Resources.NetClsClassid}}
+{{end}}
+{{range $pageSize, $limit := .Resources.HugetlbLimit}}
+lxc.cgroup.hugetlb.{{$pageSize}}.limit_in_bytes = {{$limit}}
+{{end}}
+{{range $iface, $prio := .Resources.NetPrioIfpriomap}}
+lxc.cgroup.net_prio.ifpriomap = {{$iface}} {{$prio}}
+{{end}}
 {{end}}
 
 {{if .Config.lxc}}
@@ -127,9 +174,21 @@ func getMemorySwap(v *execdriver.Resources) int64 {
 	if v.MemorySwap < 0 {
 		return 0
 	}
+	if v.MemorySwap > 0 {
+		return v.MemorySwap
+	}
 	return v.Memory * 2
 }
 
+// getSoftLimit returns the memory reservation, which defaults to the memory
+// limit when it is not set.
+func getSoftLimit(v *execdriver.Resources) int64 {
+	if v.MemoryReservation != 0 {
+		return v.MemoryReservation
+	}
+	return v.Memory
+}
+
 func getLabel(c map[string][]string, name string) string {
 	label := c["label"]
 	for _, l := range label {
@@ -145,6 +204,7 @@ func init() {
 	var err error
 	funcMap := template.FuncMap{
 		"getMemorySwap":     getMemorySwap,
+		"getSoftLimit":      getSoftLimit,
 		"escapeFstabSpaces": escapeFstabSpaces,
 		"formatMountLabel":  label.FormatMountLabel,
 	}
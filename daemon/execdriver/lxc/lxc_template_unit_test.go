@@ -138,3 +138,12 @@ func TestEscapeFstabSpaces(t *testing.T) {
 		}
 	}
 }
+
+func TestGetSoftLimit(t *testing.T) {
+	if limit := getSoftLimit(&execdriver.Resources{Memory: 1024}); limit != 1024 {
+		t.Fatalf("Expected the soft limit to default to the memory limit, got %d", limit)
+	}
+	if limit := getSoftLimit(&execdriver.Resources{Memory: 1024, MemoryReservation: 512}); limit != 512 {
+		t.Fatalf("Expected the soft limit to be the memory reservation, got %d", limit)
+	}
+}
@@ -27,6 +27,7 @@ var actions = map[string]Action{
 	"cgroups.memory_reservation": memoryReservation, // set the memory reservation
 	"cgroups.memory_swap":        memorySwap,        // set the memory swap limit
 	"cgroups.cpuset.cpus":        cpusetCpus,        // set the cpus used
+	"cgroups.cpuset.mems":        cpusetMems,        // set the memory nodes used
 
 	"systemd.slice": systemdSlice, // set parent Slice used for systemd unit
 
@@ -44,6 +45,15 @@ func cpusetCpus(container *libcontainer.Config, context interface{}, value strin
 	return nil
 }
 
+func cpusetMems(container *libcontainer.Config, context interface{}, value string) error {
+	if container.Cgroups == nil {
+		return fmt.Errorf("cannot set cgroups when they are disabled")
+	}
+	container.Cgroups.CpusetMems = value
+
+	return nil
+}
+
 func systemdSlice(container *libcontainer.Config, context interface{}, value string) error {
 	if container.Cgroups == nil {
 		return fmt.Errorf("cannot set slice when cgroups are disabled")
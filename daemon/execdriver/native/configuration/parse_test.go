@@ -74,6 +74,22 @@ func TestCpusetCpus(t *testing.T) {
 	}
 }
 
+func TestCpusetMems(t *testing.T) {
+	var (
+		container = template.New()
+		opts      = []string{
+			"cgroups.cpuset.mems=0",
+		}
+	)
+	if err := ParseConfiguration(container, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "0"; container.Cgroups.CpusetMems != expected {
+		t.Fatalf("expected %s got %s for cpuset.mems", expected, container.Cgroups.CpusetMems)
+	}
+}
+
 func TestAppArmorProfile(t *testing.T) {
 	var (
 		container = template.New()
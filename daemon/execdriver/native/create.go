@@ -13,6 +13,7 @@ import (
 	"github.com/docker/docker/daemon/execdriver/native/template"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/apparmor"
+	"github.com/docker/libcontainer/cgroups"
 	"github.com/docker/libcontainer/devices"
 	"github.com/docker/libcontainer/mount"
 	"github.com/docker/libcontainer/security/capabilities"
@@ -150,10 +151,42 @@ func (d *driver) setCapabilities(container *libcontainer.Config, c *execdriver.C
 func (d *driver) setupCgroups(container *libcontainer.Config, c *execdriver.Command) error {
 	if c.Resources != nil {
 		container.Cgroups.CpuShares = c.Resources.CpuShares
+		container.Cgroups.CpuQuota = c.Resources.CpuQuota
+		container.Cgroups.CpuPeriod = c.Resources.CpuPeriod
+		container.Cgroups.CpuBurst = c.Resources.CpuBurst
+		container.Cgroups.CpuRtRuntime = c.Resources.CpuRtRuntime
+		container.Cgroups.CpuRtPeriod = c.Resources.CpuRtPeriod
 		container.Cgroups.Memory = c.Resources.Memory
 		container.Cgroups.MemoryReservation = c.Resources.Memory
+		if c.Resources.MemoryReservation != 0 {
+			container.Cgroups.MemoryReservation = c.Resources.MemoryReservation
+		}
 		container.Cgroups.MemorySwap = c.Resources.MemorySwap
+		container.Cgroups.KernelMemory = c.Resources.KernelMemory
+		container.Cgroups.KernelMemoryTCP = c.Resources.KernelMemoryTCP
+		container.Cgroups.MemorySwappiness = c.Resources.MemorySwappiness
+		container.Cgroups.OomKillDisable = c.Resources.OomKillDisable
 		container.Cgroups.CpusetCpus = c.Resources.Cpuset
+		container.Cgroups.CpusetMems = c.Resources.CpusetMems
+		container.Cgroups.CpusetCpuExclusive = c.Resources.CpusetCpuExclusive
+		container.Cgroups.CpusetMemExclusive = c.Resources.CpusetMemExclusive
+		container.Cgroups.BlkioWeight = c.Resources.BlkioWeight
+		container.Cgroups.BlkioThrottleReadIOpsDevice = c.Resources.BlkioThrottleReadIOpsDevice
+		container.Cgroups.BlkioThrottleWriteIOpsDevice = c.Resources.BlkioThrottleWriteIOpsDevice
+		container.Cgroups.PidsLimit = c.Resources.PidsLimit
+		container.Cgroups.NetClsClassid = c.Resources.NetClsClassid
+		for pageSize, limit := range c.Resources.HugetlbLimit {
+			container.Cgroups.HugetlbLimit = append(container.Cgroups.HugetlbLimit, &cgroups.HugepageLimit{
+				Pagesize: pageSize,
+				Limit:    limit,
+			})
+		}
+		for iface, prio := range c.Resources.NetPrioIfpriomap {
+			container.Cgroups.NetPrioIfpriomap = append(container.Cgroups.NetPrioIfpriomap, &cgroups.IfPrioMap{
+				Interface: iface,
+				Priority:  prio,
+			})
+		}
 	}
 
 	return nil
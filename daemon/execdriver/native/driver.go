@@ -218,6 +218,49 @@ func (d *driver) GetPidsForContainer(id string) ([]int, error) {
 	return fs.GetPids(c)
 }
 
+// CollectState removes the state directories, fifos and pid files left in the
+// driver root by containers which are not active anymore, typically because
+// they or the daemon crashed before removeContainerRoot could run.
+func (d *driver) CollectState() ([]string, error) {
+	entries, err := ioutil.ReadDir(d.root)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	removed := []string{}
+	for _, entry := range entries {
+		id := entry.Name()
+		if _, exists := d.activeContainers[id]; exists {
+			continue
+		}
+		if entry.IsDir() && isAlive(filepath.Join(d.root, id)) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(d.root, id)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, id)
+	}
+	return removed, nil
+}
+
+// isAlive returns true if the init process recorded in the state of the
+// container at path is still running.
+func isAlive(path string) bool {
+	state, err := libcontainer.GetState(path)
+	if err != nil || state.InitPid == 0 {
+		return false
+	}
+	currentStartTime, err := system.GetProcessStartTime(state.InitPid)
+	if err != nil {
+		return false
+	}
+	return state.InitStartTime == currentStartTime
+}
+
 func (d *driver) writeContainerFile(container *libcontainer.Config, id string) error {
 	data, err := json.Marshal(container)
 	if err != nil {
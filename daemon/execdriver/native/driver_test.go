@@ -0,0 +1,71 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"testing"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/system"
+)
+
+func TestCollectState(t *testing.T) {
+	root, err := ioutil.TempDir("", "native-collect-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	d := &driver{
+		root:             root,
+		activeContainers: map[string]*activeContainer{"active": {}},
+	}
+	for _, id := range []string{"active", "alive", "crashed"} {
+		if err := d.createContainerRoot(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "crashed", "start"), []byte("1"), 0655); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "fifo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	startTime, err := system.GetProcessStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(&libcontainer.State{InitPid: os.Getpid(), InitStartTime: startTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "alive", "state.json"), data, 0655); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := d.CollectState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(removed)
+	if len(removed) != 2 || removed[0] != "crashed" || removed[1] != "fifo" {
+		t.Fatalf("Expected crashed and fifo to be removed, got %v", removed)
+	}
+	for _, id := range []string{"active", "alive"} {
+		if _, err := os.Stat(filepath.Join(root, id)); err != nil {
+			t.Fatalf("Expected %s to be kept: %s", id, err)
+		}
+	}
+	for _, id := range removed {
+		if _, err := os.Stat(filepath.Join(root, id)); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed", id)
+		}
+	}
+}
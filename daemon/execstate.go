@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
+)
+
+// execStateGCInterval is how often the daemon looks for exec driver state
+// left behind by crashed containers.
+const execStateGCInterval = time.Hour
+
+// collectExecState asks the exec driver, if it keeps state on disk, to remove
+// the state of the containers it does not run anymore and logs what was
+// reclaimed.
+func (daemon *Daemon) collectExecState() {
+	collector, ok := daemon.execDriver.(execdriver.StateCollector)
+	if !ok {
+		return
+	}
+	removed, err := collector.CollectState()
+	if len(removed) > 0 {
+		ids := make([]string, len(removed))
+		for i, id := range removed {
+			ids[i] = utils.TruncateID(id)
+		}
+		log.Infof("Removed stale exec driver state of %d containers: %s", len(removed), strings.Join(ids, ", "))
+	}
+	if err != nil {
+		log.Errorf("Error collecting exec driver state: %s", err)
+	}
+}
+
+// startExecStateGC collects the exec driver state once and then every
+// execStateGCInterval for the lifetime of the daemon.
+func (daemon *Daemon) startExecStateGC() {
+	daemon.collectExecState()
+	go func() {
+		for _ = range time.Tick(execStateGCInterval) {
+			daemon.collectExecState()
+		}
+	}()
+}
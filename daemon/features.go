@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/engine"
+)
+
+// featureGate is a subsystem which ships dark: it stays off unless the host
+// enables it, with --experimental for all the gates or with its own
+// --feature.
+type featureGate struct {
+	Name        string
+	Description string
+}
+
+// featureGates are the gates known to the daemon, in the order docker info
+// lists them.
+var featureGates = []featureGate{
+	{"registry", "Serve the local images over the v2 registry protocol with --registry-addr"},
+}
+
+// features are the gates enabled on the host.
+type features struct {
+	experimental bool
+	enabled      map[string]bool
+}
+
+// newFeatures enables all the gates when experimental, then each of specs,
+// NAME or NAME=false, enables or disables its gate, for a host to take only
+// some of the experimental subsystems.
+func newFeatures(experimental bool, specs []string) (*features, error) {
+	f := &features{experimental: experimental, enabled: make(map[string]bool)}
+	for _, gate := range featureGates {
+		f.enabled[gate.Name] = experimental
+	}
+	for _, spec := range specs {
+		var (
+			parts = strings.SplitN(spec, "=", 2)
+			name  = parts[0]
+			on    = true
+		)
+		if _, exists := f.enabled[name]; !exists {
+			return nil, fmt.Errorf("Invalid --feature %s, unknown feature gate %s", spec, name)
+		}
+		if len(parts) == 2 {
+			var err error
+			if on, err = strconv.ParseBool(parts[1]); err != nil {
+				return nil, fmt.Errorf("Invalid --feature %s, must be NAME or NAME=true|false", spec)
+			}
+		}
+		f.enabled[name] = on
+	}
+	return f, nil
+}
+
+// Enabled returns whether the gate name is enabled on the host.
+func (f *features) Enabled(name string) bool {
+	return f.enabled[name]
+}
+
+// list returns the names of the enabled gates.
+func (f *features) list() []string {
+	var names []string
+	for _, gate := range featureGates {
+		if f.enabled[gate.Name] {
+			names = append(names, gate.Name)
+		}
+	}
+	return names
+}
+
+// require returns an error when the gate name is off, for the option of
+// the daemon it gates, such as --registry-addr.
+func (f *features) require(name, option string) error {
+	if f.Enabled(name) {
+		return nil
+	}
+	return fmt.Errorf("%s is experimental, enable it with --feature %s or --experimental", option, name)
+}
+
+// CmdFeatures returns whether the daemon runs with --experimental and the
+// feature gates enabled on the host, for the clients to know which
+// experimental endpoints they can use.
+func (daemon *Daemon) CmdFeatures(job *engine.Job) engine.Status {
+	v := &engine.Env{}
+	v.SetBool("Experimental", daemon.features.experimental)
+	v.SetList("Features", daemon.features.list())
+	if _, err := v.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
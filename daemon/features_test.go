@@ -0,0 +1,36 @@
+package daemon
+
+import "testing"
+
+func TestNewFeatures(t *testing.T) {
+	f, err := newFeatures(false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Enabled("registry") || len(f.list()) != 0 {
+		t.Fatalf("Expected the gates to be off by default, got %v", f.list())
+	}
+	if err := f.require("registry", "--registry-addr"); err == nil {
+		t.Fatal("Expected --registry-addr to require its gate")
+	}
+
+	if f, err = newFeatures(false, []string{"registry"}); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Enabled("registry") {
+		t.Fatal("Expected --feature registry to enable its gate")
+	}
+
+	if f, err = newFeatures(true, []string{"registry=false"}); err != nil {
+		t.Fatal(err)
+	}
+	if f.Enabled("registry") {
+		t.Fatal("Expected --feature registry=false to disable its gate along with --experimental")
+	}
+
+	for _, spec := range []string{"lazy-pulls", "registry=maybe"} {
+		if _, err := newFeatures(true, []string{spec}); err == nil {
+			t.Fatalf("Expected --feature %s to be refused", spec)
+		}
+	}
+}
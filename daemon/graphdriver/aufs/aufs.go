@@ -310,6 +310,11 @@ func (a *Driver) DiffSize(id string) (int64, error) {
 	return utils.TreeSize(path.Join(a.rootPath(), "diff", id))
 }
 
+// Returns the directory the files changed in the layer id are written to
+func (a *Driver) UpperDir(id string) string {
+	return path.Join(a.rootPath(), "diff", id)
+}
+
 func (a *Driver) Changes(id string) ([]archive.Change, error) {
 	layers, err := a.getParentLayerPaths(id)
 	if err != nil {
@@ -41,6 +41,12 @@ type Differ interface {
 	DiffSize(id string) (bytes int64, err error)
 }
 
+// UpperDirer is implemented by the union drivers writing the files changed
+// in a layer to a directory of the host apart from the mount of the layer.
+type UpperDirer interface {
+	UpperDir(id string) string
+}
+
 var (
 	DefaultDriver string
 	// All registred drivers
@@ -227,3 +227,36 @@ func DriverTestCreateSnap(t *testing.T, drivername string) {
 		t.Fatal(err)
 	}
 }
+
+// Runs the self-test of the driver and verifies it went through every
+// operation
+func DriverTestSelfTest(t *testing.T, drivername string) {
+	driver := GetDriver(t, drivername)
+	defer PutDriver(t)
+
+	report, err := graphdriver.SelfTest(driver, graphdriver.SelfTestOptions{
+		Layers:      3,
+		Concurrency: 4,
+		Size:        1024 * 1024,
+		MaxDepth:    127,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := []string{"create", "mount", "write", "diff", "apply", "concurrent mount", "remove"}
+	if len(report.Results) != len(operations) {
+		t.Fatalf("Expected %d results, got %d", len(operations), len(report.Results))
+	}
+	for i, result := range report.Results {
+		if result.Operation != operations[i] {
+			t.Fatalf("Expected operation %s, got %s", operations[i], result.Operation)
+		}
+	}
+	if report.Results[2].Bytes != 1024*1024 {
+		t.Fatalf("Expected 1MB to be written, got %d", report.Results[2].Bytes)
+	}
+	if report.Results[3].Bytes < 1024*1024 {
+		t.Fatalf("Expected the diff to hold the data written, got %d bytes", report.Results[3].Bytes)
+	}
+}
@@ -0,0 +1,317 @@
+package graphdriver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/utils"
+)
+
+// SelfTestOptions sets the amount of work done by SelfTest.
+type SelfTestOptions struct {
+	Layers      int   // Depth of the chain of layers created
+	Concurrency int   // Number of concurrent mounts of the layers
+	Size        int64 // Bytes written into the top layer, then diffed and applied
+	MaxDepth    int   // Maximum number of layers of a container, for the known issues
+}
+
+// SelfTestResult is the time taken by a SelfTest operation.
+type SelfTestResult struct {
+	Operation string
+	Count     int
+	Duration  time.Duration
+	Bytes     int64 // Data written or read by the operation, if any
+}
+
+// SelfTestReport is the outcome of SelfTest.
+type SelfTestReport struct {
+	Driver   string
+	Results  []SelfTestResult
+	Warnings []string // Known issues of the driver in this setup
+}
+
+type selfTest struct {
+	driver Driver
+	report *SelfTestReport
+	layers []string
+}
+
+// SelfTest exercises driver with throwaway layers: it creates a chain of
+// layers, mounts them, writes into the top one, diffs it, applies the diff
+// onto a new layer, mounts the layers concurrently and removes them all. The
+// time taken by each operation is reported along with the known issues of the
+// driver in this setup.
+func SelfTest(driver Driver, opts SelfTestOptions) (*SelfTestReport, error) {
+	if opts.Layers < 1 || opts.Concurrency < 1 || opts.Size < 0 {
+		return nil, fmt.Errorf("Invalid self-test options %+v", opts)
+	}
+	t := &selfTest{
+		driver: driver,
+		report: &SelfTestReport{
+			Driver:   driver.String(),
+			Warnings: knownIssues(driver, opts.MaxDepth),
+		},
+	}
+	defer t.cleanup()
+
+	for _, step := range []func(SelfTestOptions) error{
+		t.create,
+		t.mount,
+		t.write,
+		t.diffAndApply,
+		t.concurrentMounts,
+		t.remove,
+	} {
+		if err := step(opts); err != nil {
+			return t.report, err
+		}
+	}
+	return t.report, nil
+}
+
+func (t *selfTest) add(operation string, count int, start time.Time, bytes int64) {
+	t.report.Results = append(t.report.Results, SelfTestResult{
+		Operation: operation,
+		Count:     count,
+		Duration:  time.Since(start),
+		Bytes:     bytes,
+	})
+}
+
+func (t *selfTest) top() string {
+	return t.layers[len(t.layers)-1]
+}
+
+func (t *selfTest) parent(id string) string {
+	for i, layer := range t.layers {
+		if layer == id && i > 0 {
+			return t.layers[i-1]
+		}
+	}
+	return ""
+}
+
+func (t *selfTest) create(opts SelfTestOptions) error {
+	start := time.Now()
+	parent := ""
+	for i := 0; i < opts.Layers; i++ {
+		id := utils.GenerateRandomID()
+		if err := t.driver.Create(id, parent); err != nil {
+			return fmt.Errorf("Cannot create layer %d: %s", i, err)
+		}
+		t.layers = append(t.layers, id)
+		parent = id
+	}
+	t.add("create", opts.Layers, start, 0)
+	return nil
+}
+
+func (t *selfTest) mount(opts SelfTestOptions) error {
+	start := time.Now()
+	for i, id := range t.layers {
+		dir, err := t.driver.Get(id, "")
+		if err != nil {
+			return fmt.Errorf("Cannot mount layer %d: %s", i, err)
+		}
+		err = ioutil.WriteFile(path.Join(dir, fmt.Sprintf("selftest-%d", i)), []byte(id), 0644)
+		t.driver.Put(id)
+		if err != nil {
+			return fmt.Errorf("Cannot write into layer %d: %s", i, err)
+		}
+	}
+	t.add("mount", len(t.layers), start, 0)
+	return nil
+}
+
+func (t *selfTest) write(opts SelfTestOptions) error {
+	dir, err := t.driver.Get(t.top(), "")
+	if err != nil {
+		return fmt.Errorf("Cannot mount the top layer: %s", err)
+	}
+	defer t.driver.Put(t.top())
+
+	start := time.Now()
+	f, err := os.Create(path.Join(dir, "selftest-data"))
+	if err != nil {
+		return err
+	}
+	block := bytes.Repeat([]byte{'x'}, 32*1024)
+	for written := int64(0); written < opts.Size; written += int64(len(block)) {
+		if remaining := opts.Size - written; remaining < int64(len(block)) {
+			block = block[:remaining]
+		}
+		if _, err := f.Write(block); err != nil {
+			f.Close()
+			return fmt.Errorf("Cannot write into the top layer: %s", err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	t.add("write", 1, start, opts.Size)
+	return nil
+}
+
+func (t *selfTest) diffAndApply(opts SelfTestOptions) error {
+	start := time.Now()
+	layerData, err := t.diff(t.top())
+	if err != nil {
+		return fmt.Errorf("Cannot diff the top layer: %s", err)
+	}
+	t.add("diff", 1, start, int64(len(layerData)))
+
+	id := utils.GenerateRandomID()
+	if err := t.driver.Create(id, t.parent(t.top())); err != nil {
+		return fmt.Errorf("Cannot create the layer to apply the diff on: %s", err)
+	}
+	t.layers = append(t.layers, id)
+
+	start = time.Now()
+	if err := t.apply(id, bytes.NewReader(layerData)); err != nil {
+		return fmt.Errorf("Cannot apply the diff of the top layer: %s", err)
+	}
+	t.add("apply", 1, start, int64(len(layerData)))
+	return nil
+}
+
+// diff returns the changes of layer id to its parent as a tar archive, the
+// same way the daemon does for the drivers which don't implement Differ.
+func (t *selfTest) diff(id string) ([]byte, error) {
+	var layerData archive.Archive
+	if differ, ok := t.driver.(Differ); ok {
+		var err error
+		if layerData, err = differ.Diff(id); err != nil {
+			return nil, err
+		}
+	} else {
+		dir, err := t.driver.Get(id, "")
+		if err != nil {
+			return nil, err
+		}
+		defer t.driver.Put(id)
+		parent := t.parent(id)
+		if parent == "" {
+			if layerData, err = archive.Tar(dir, archive.Uncompressed); err != nil {
+				return nil, err
+			}
+		} else {
+			parentDir, err := t.driver.Get(parent, "")
+			if err != nil {
+				return nil, err
+			}
+			defer t.driver.Put(parent)
+			changes, err := archive.ChangesDirs(dir, parentDir)
+			if err != nil {
+				return nil, err
+			}
+			if layerData, err = archive.ExportChanges(dir, changes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	defer layerData.Close()
+	return ioutil.ReadAll(layerData)
+}
+
+func (t *selfTest) apply(id string, layerData io.Reader) error {
+	if differ, ok := t.driver.(Differ); ok {
+		return differ.ApplyDiff(id, layerData)
+	}
+	dir, err := t.driver.Get(id, "")
+	if err != nil {
+		return err
+	}
+	defer t.driver.Put(id)
+	return archive.ApplyLayer(dir, layerData)
+}
+
+func (t *selfTest) concurrentMounts(opts SelfTestOptions) error {
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+		start    = time.Now()
+	)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, id := range t.layers {
+				if _, err := t.driver.Get(id, ""); err != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errLock.Unlock()
+					return
+				}
+				t.driver.Put(id)
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return fmt.Errorf("Cannot mount the layers concurrently: %s", firstErr)
+	}
+	t.add("concurrent mount", opts.Concurrency*len(t.layers), start, 0)
+	return nil
+}
+
+func (t *selfTest) remove(opts SelfTestOptions) error {
+	start := time.Now()
+	count := len(t.layers)
+	for len(t.layers) > 0 {
+		if err := t.driver.Remove(t.top()); err != nil {
+			return fmt.Errorf("Cannot remove layer %s: %s", utils.TruncateID(t.top()), err)
+		}
+		t.layers = t.layers[:len(t.layers)-1]
+	}
+	t.add("remove", count, start, 0)
+	return nil
+}
+
+// cleanup removes the layers left by a failed self-test.
+func (t *selfTest) cleanup() {
+	for i := len(t.layers) - 1; i >= 0; i-- {
+		t.driver.Remove(t.layers[i])
+	}
+}
+
+// knownIssues returns the known issues of driver in this setup.
+func knownIssues(driver Driver, maxDepth int) []string {
+	status := make(map[string]string)
+	for _, pair := range driver.Status() {
+		status[pair[0]] = pair[1]
+	}
+
+	var issues []string
+	switch driver.String() {
+	case "aufs":
+		// the branches of an aufs mount are given in the mount options,
+		// which the kernel limits to a page
+		branch := len(path.Join(status["Root Dir"], "diff", strings.Repeat("0", 64))) + len("=ro+wh:")
+		if options := maxDepth * branch; options > os.Getpagesize() {
+			issues = append(issues, fmt.Sprintf("The branches of the images deeper than %d layers don't fit in the aufs mount options, they are mounted one by one which is slower. A shorter --graph path raises this depth.", os.Getpagesize()/branch))
+		}
+	case "devicemapper":
+		// the data file is only created when no data device is given
+		if _, err := os.Stat(status["Data file"]); err == nil {
+			issues = append(issues, "The devicemapper thin pool is backed by loopback devices, which is slow and not meant for production. Use --storage-opt dm.datadev and dm.metadatadev to give it block devices.")
+		}
+	case "vfs":
+		issues = append(issues, "vfs copies the whole parent into every new layer, it uses a lot of disk space and creates layers slowly.")
+	}
+	return issues
+}
@@ -23,6 +23,10 @@ func TestVfsCreateSnap(t *testing.T) {
 	graphtest.DriverTestCreateSnap(t, "vfs")
 }
 
+func TestVfsSelfTest(t *testing.T) {
+	graphtest.DriverTestSelfTest(t, "vfs")
+}
+
 func TestVfsTeardown(t *testing.T) {
 	graphtest.PutDriver(t)
 }
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/runconfig"
+)
+
+// Lifecycle points of a container at which its hooks are run.
+const (
+	hookPrestart  = "prestart"
+	hookPoststart = "poststart"
+	hookPoststop  = "poststop"
+)
+
+// hookTimeout is how long a hook may run before it is killed, so that a
+// hung hook does not block the container.
+const hookTimeout = 30 * time.Second
+
+// checkHostCommands refuses the hooks and the host services of hostConfig
+// unless the daemon runs with --host-commands: any client of the API could
+// otherwise run commands and start or stop systemd units on the host as root.
+func (daemon *Daemon) checkHostCommands(hostConfig *runconfig.HostConfig) error {
+	if hostConfig == nil || daemon.config.HostCommands {
+		return nil
+	}
+	hooks := hostConfig.Hooks
+	if len(hooks.Prestart) != 0 || len(hooks.Poststart) != 0 || len(hooks.Poststop) != 0 {
+		return fmt.Errorf("Forbidden, hooks run on the host, the daemon allows them with --host-commands")
+	}
+	if len(hostConfig.HostServices) != 0 {
+		return fmt.Errorf("Forbidden, the host service %s runs on the host, the daemon allows it with --host-commands", hostConfig.HostServices[0].Name)
+	}
+	return nil
+}
+
+// hooks returns the commands to run at the lifecycle point stage.
+func (container *Container) hooks(stage string) []string {
+	if container.hostConfig == nil {
+		return nil
+	}
+	switch stage {
+	case hookPrestart:
+		return container.hostConfig.Hooks.Prestart
+	case hookPoststart:
+		return container.hostConfig.Hooks.Poststart
+	case hookPoststop:
+		return container.hostConfig.Hooks.Poststop
+	}
+	return nil
+}
+
+// hookEnv returns the environment the hooks of the container are run with:
+// the daemon's own environment plus the metadata of the container.
+func (container *Container) hookEnv(stage string, exitCode int) []string {
+	env := append(os.Environ(),
+		"DOCKER_HOOK="+stage,
+		"DOCKER_CONTAINER_ID="+container.ID,
+		"DOCKER_CONTAINER_NAME="+strings.TrimPrefix(container.Name, "/"),
+		"DOCKER_CONTAINER_IMAGE="+container.Config.Image,
+	)
+	if container.NetworkSettings != nil && container.NetworkSettings.IPAddress != "" {
+		env = append(env, "DOCKER_CONTAINER_IP="+container.NetworkSettings.IPAddress)
+	}
+	switch stage {
+	case hookPoststart:
+		env = append(env, "DOCKER_CONTAINER_PID="+strconv.Itoa(container.State.GetPid()))
+	case hookPoststop:
+		env = append(env, "DOCKER_CONTAINER_EXIT_CODE="+strconv.Itoa(exitCode))
+	}
+	return env
+}
+
+// runHooks runs the hooks of the container for the lifecycle point stage on
+// the host, one after the other. It stops at the first hook that fails.
+func (container *Container) runHooks(stage string, exitCode int) error {
+	hooks := container.hooks(stage)
+	if len(hooks) == 0 {
+		return nil
+	}
+	env := container.hookEnv(stage, exitCode)
+	for _, hook := range hooks {
+		log.Debugf("Running %s hook of %s: %s", stage, container.ID, hook)
+		cmd := exec.Command("/bin/sh", "-c", hook)
+		cmd.Env = env
+		if err := runHook(cmd); err != nil {
+			return fmt.Errorf("%s hook %q of %s failed: %s", stage, hook, container.ID, err)
+		}
+	}
+	return nil
+}
+
+func runHook(cmd *exec.Cmd) error {
+	var output []byte
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		output, err = cmd.CombinedOutput()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && len(output) > 0 {
+			return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output)))
+		}
+		return err
+	case <-time.After(hookTimeout):
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		<-done
+		return fmt.Errorf("timed out after %s", hookTimeout)
+	}
+}
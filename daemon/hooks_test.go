@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestRunHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-hooks-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "output")
+
+	container := &Container{
+		ID:              "e90e34656806",
+		Name:            "/web",
+		Config:          &runconfig.Config{Image: "busybox"},
+		State:           NewState(),
+		NetworkSettings: &NetworkSettings{IPAddress: "172.17.0.2"},
+		hostConfig: &runconfig.HostConfig{
+			Hooks: runconfig.Hooks{
+				Poststop: []string{
+					"echo $DOCKER_HOOK $DOCKER_CONTAINER_NAME $DOCKER_CONTAINER_IMAGE $DOCKER_CONTAINER_IP $DOCKER_CONTAINER_EXIT_CODE > " + output,
+				},
+				Prestart: []string{"echo broken >&2; exit 3", "touch " + output},
+			},
+		},
+	}
+
+	if err := container.runHooks(hookPoststop, 2); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "poststop web busybox 172.17.0.2 2\n"; string(content) != expected {
+		t.Fatalf("Expected hook output %q, got %q", expected, content)
+	}
+	os.Remove(output)
+
+	err = container.runHooks(hookPrestart, 0)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("Expected the prestart hook to fail with its output, got %v", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatal("Expected the hooks after a failing one not to run")
+	}
+
+	if err := container.runHooks(hookPoststart, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckHostCommands(t *testing.T) {
+	daemon := &Daemon{config: &Config{}}
+	for _, hostConfig := range []*runconfig.HostConfig{
+		{Hooks: runconfig.Hooks{Poststop: []string{"lb-deregister web"}}},
+		{HostServices: []runconfig.HostService{{Kind: "script", Name: "/usr/local/bin/web-route"}}},
+		{HostServices: []runconfig.HostService{{Kind: "unit", Name: "firewall.service"}}},
+	} {
+		if err := daemon.checkHostCommands(hostConfig); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+			t.Fatalf("Expected the commands of %v to be forbidden without --host-commands, got %v", hostConfig, err)
+		}
+	}
+
+	daemon.config.HostCommands = true
+	if err := daemon.checkHostCommands(&runconfig.HostConfig{Hooks: runconfig.Hooks{Prestart: []string{"true"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := daemon.checkHostCommands(&runconfig.HostConfig{HostServices: []runconfig.HostService{{Kind: "unit", Name: "firewall.service"}}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/runconfig"
+)
+
+// Actions toggling the host services of a container.
+const (
+	hostServiceStart = "start"
+	hostServiceStop  = "stop"
+)
+
+// hostServicesAuditFile is the file of the root of a container recording
+// every start and stop of its host services, one JSON object per line.
+const hostServicesAuditFile = "host-services.log"
+
+type hostServiceAudit struct {
+	Time    time.Time
+	Service runconfig.HostService
+	Action  string
+	Error   string `json:",omitempty"`
+}
+
+// hostServiceCommand returns the command taking action on service: systemctl
+// for a unit, the script itself for a script, run with the environment of
+// the hooks.
+func (container *Container) hostServiceCommand(service runconfig.HostService, action string, exitCode int) *exec.Cmd {
+	if service.Kind == "unit" {
+		return exec.Command("systemctl", action, service.Name)
+	}
+	stage := hookPrestart
+	if action == hostServiceStop {
+		stage = hookPoststop
+	}
+	cmd := exec.Command(service.Name, action)
+	cmd.Env = container.hookEnv(stage, exitCode)
+	return cmd
+}
+
+// toggleHostService takes action on service, and records it in the audit
+// file of the container.
+func (container *Container) toggleHostService(service runconfig.HostService, action string, exitCode int) error {
+	log.Infof("Running %s on the host service %s:%s of %s", action, service.Kind, service.Name, container.ID)
+	err := runHook(container.hostServiceCommand(service, action, exitCode))
+
+	audit := hostServiceAudit{Time: time.Now().UTC(), Service: service, Action: action}
+	if err != nil {
+		audit.Error = err.Error()
+	}
+	if auditErr := container.auditHostService(audit); auditErr != nil {
+		log.Errorf("Cannot audit the host service %s:%s of %s: %s", service.Kind, service.Name, container.ID, auditErr)
+	}
+	return err
+}
+
+func (container *Container) auditHostService(audit hostServiceAudit) error {
+	p, err := container.getRootResourcePath(hostServicesAuditFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// startHostServices starts the host services of the container in order. When
+// one fails, the ones already started are stopped again.
+func (container *Container) startHostServices() error {
+	if container.hostConfig == nil {
+		return nil
+	}
+	services := container.hostConfig.HostServices
+	for i, service := range services {
+		if err := container.toggleHostService(service, hostServiceStart, 0); err != nil {
+			container.stopHostServices(services[:i], 0)
+			return fmt.Errorf("Cannot start the host service %s:%s of %s: %s", service.Kind, service.Name, container.ID, err)
+		}
+	}
+	return nil
+}
+
+// stopHostServices stops services in the reverse order of their start, after
+// the container exited with exitCode. A failure is only logged.
+func (container *Container) stopHostServices(services []runconfig.HostService, exitCode int) {
+	for i := len(services) - 1; i >= 0; i-- {
+		if err := container.toggleHostService(services[i], hostServiceStop, exitCode); err != nil {
+			log.Errorf("Cannot stop the host service %s:%s of %s: %s", services[i].Kind, services[i].Name, container.ID, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestHostServices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-host-services-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		output = filepath.Join(dir, "output")
+		script = filepath.Join(dir, "route")
+		broken = filepath.Join(dir, "firewall")
+	)
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho $1 $DOCKER_CONTAINER_NAME $DOCKER_CONTAINER_EXIT_CODE >> "+output+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(broken, []byte("#!/bin/sh\necho no such port >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	container := &Container{
+		ID:     "e90e34656806",
+		Name:   "/web",
+		root:   dir,
+		Config: &runconfig.Config{Image: "busybox"},
+		State:  NewState(),
+		hostConfig: &runconfig.HostConfig{
+			HostServices: []runconfig.HostService{{Kind: "script", Name: script}},
+		},
+	}
+	if err := container.startHostServices(); err != nil {
+		t.Fatal(err)
+	}
+	container.stopHostServices(container.hostConfig.HostServices, 2)
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "start web\nstop web 2\n"; string(content) != expected {
+		t.Fatalf("Expected the script to be run with %q, got %q", expected, content)
+	}
+	os.Remove(output)
+
+	// the services started before a failing one are stopped
+	container.hostConfig.HostServices = append(container.hostConfig.HostServices, runconfig.HostService{Kind: "script", Name: broken})
+	err = container.startHostServices()
+	if err == nil || !strings.Contains(err.Error(), "no such port") {
+		t.Fatalf("Expected the start to fail with the output of the script, got %v", err)
+	}
+	if content, _ := ioutil.ReadFile(output); string(content) != "start web\nstop web 0\n" {
+		t.Fatalf("Expected the started service to be stopped again, got %q", content)
+	}
+
+	audit, err := ioutil.ReadFile(filepath.Join(dir, hostServicesAuditFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(audit)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 audited actions, got %q", audit)
+	}
+	var last hostServiceAudit
+	if err := json.Unmarshal([]byte(lines[3]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Service.Name != broken || last.Action != hostServiceStart || last.Error == "" {
+		t.Fatalf("Expected the failed start to be audited, got %+v", last)
+	}
+}
@@ -108,6 +108,9 @@ func (daemon *Daemon) DeleteImage(eng *engine.Engine, name string, imgs *engine.
 			if err := daemon.Graph().Delete(img.ID); err != nil {
 				return err
 			}
+			if daemon.registry != nil {
+				daemon.registry.Prune(img.ID)
+			}
 			out := &engine.Env{}
 			out.Set("Deleted", img.ID)
 			imgs.Add(out)
@@ -56,6 +56,7 @@ func (daemon *Daemon) CmdInfo(job *engine.Job) engine.Status {
 	v.SetJson("DriverStatus", daemon.GraphDriver().Status())
 	v.SetBool("MemoryLimit", daemon.SystemConfig().MemoryLimit)
 	v.SetBool("SwapLimit", daemon.SystemConfig().SwapLimit)
+	v.SetBool("CpuCfsBurst", daemon.SystemConfig().CpuCfsBurst)
 	v.SetBool("IPv4Forwarding", !daemon.SystemConfig().IPv4ForwardingDisabled)
 	v.SetBool("Debug", os.Getenv("DEBUG") != "")
 	v.SetInt("NFd", utils.GetTotalUsedFds())
@@ -67,6 +68,12 @@ func (daemon *Daemon) CmdInfo(job *engine.Job) engine.Status {
 	v.Set("IndexServerAddress", registry.IndexServerAddress())
 	v.Set("InitSha1", dockerversion.INITSHA1)
 	v.Set("InitPath", initPath)
+	// the breakdown of the startup, for diagnosing the slow ones
+	total, steps := daemon.startup.profile()
+	v.SetJson("StartupTime", total.Seconds())
+	v.SetJson("StartupSteps", steps)
+	v.SetBool("Experimental", daemon.features.experimental)
+	v.SetList("Features", daemon.features.list())
 	if _, err := v.WriteTo(job.Stdout); err != nil {
 		return job.Error(err)
 	}
@@ -1,33 +1,76 @@
 package daemon
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/runconfig"
 )
 
+// ContainerInspect returns the low-level information on a container. With
+// fields, only those fields are returned, each a path of keys separated by
+// dots such as State.Running, in the structure of the whole information.
 func (daemon *Daemon) ContainerInspect(job *engine.Job) engine.Status {
 	if len(job.Args) != 1 {
 		return job.Errorf("usage: %s NAME", job.Name)
 	}
-	name := job.Args[0]
-	if container := daemon.Get(name); container != nil {
-		container.Lock()
-		defer container.Unlock()
-		if job.GetenvBool("raw") {
-			b, err := json.Marshal(&struct {
-				*Container
-				HostConfig *runconfig.HostConfig
-			}{container, container.hostConfig})
-			if err != nil {
+	b, err := daemon.inspectContainer(job.Args[0], job.GetenvBool("raw"), job.GetenvList("fields"))
+	if err != nil {
+		return job.Error(err)
+	}
+	job.Stdout.Write(b)
+	return engine.StatusOK
+}
+
+// ContainersInspect returns the low-level information on several containers
+// at once, as a list in the order of the names, for the clients inspecting
+// many containers not to make a request for each. A container which can't be
+// inspected is an object with only the Error of its inspection. fields
+// selects the fields returned as for ContainerInspect.
+func (daemon *Daemon) ContainersInspect(job *engine.Job) engine.Status {
+	if len(job.Args) == 0 {
+		return job.Errorf("usage: %s NAME [NAME...]", job.Name)
+	}
+	fields := job.GetenvList("fields")
+	objs := make([]json.RawMessage, len(job.Args))
+	for i, name := range job.Args {
+		b, err := daemon.inspectContainer(name, false, fields)
+		if err != nil {
+			if b, err = json.Marshal(map[string]string{"Error": err.Error()}); err != nil {
 				return job.Error(err)
 			}
-			job.Stdout.Write(b)
-			return engine.StatusOK
 		}
+		objs[i] = json.RawMessage(b)
+	}
+	if err := json.NewEncoder(job.Stdout).Encode(objs); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// inspectContainer returns the information on the container name as JSON,
+// as stored on disk when raw, and only its fields when some are given.
+func (daemon *Daemon) inspectContainer(name string, raw bool, fields []string) ([]byte, error) {
+	container := daemon.Get(name)
+	if container == nil {
+		return nil, fmt.Errorf("No such container: %s", name)
+	}
+	container.Lock()
+	defer container.Unlock()
 
+	var b []byte
+	if raw {
+		var err error
+		if b, err = json.Marshal(&struct {
+			*Container
+			HostConfig *runconfig.HostConfig
+		}{container, container.hostConfig}); err != nil {
+			return nil, err
+		}
+	} else {
 		out := &engine.Env{}
 		out.Set("Id", container.ID)
 		out.SetAuto("Created", container.Created)
@@ -57,10 +100,60 @@ func (daemon *Daemon) ContainerInspect(job *engine.Job) engine.Status {
 		out.SetJson("HostConfig", container.hostConfig)
 
 		container.hostConfig.Links = nil
-		if _, err := out.WriteTo(job.Stdout); err != nil {
-			return job.Error(err)
+		var buf bytes.Buffer
+		if _, err := out.WriteTo(&buf); err != nil {
+			return nil, err
+		}
+		b = buf.Bytes()
+	}
+	if len(fields) == 0 {
+		return b, nil
+	}
+	return selectFields(b, fields)
+}
+
+// selectFields returns the fields of the JSON object b, paths of keys
+// separated by dots, in the structure of b. The paths matching nothing are
+// left out.
+func selectFields(b []byte, fields []string) ([]byte, error) {
+	var obj map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	// the numbers are kept as they are, the memory limits don't fit a float
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]interface{})
+	for _, field := range fields {
+		keys := strings.Split(field, ".")
+		var (
+			value interface{} = obj
+			found             = true
+		)
+		for _, key := range keys {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				found = false
+				break
+			}
+			if value, ok = m[key]; !ok {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		dst := selected
+		for _, key := range keys[:len(keys)-1] {
+			m, ok := dst[key].(map[string]interface{})
+			if !ok {
+				m = make(map[string]interface{})
+				dst[key] = m
+			}
+			dst = m
 		}
-		return engine.StatusOK
+		dst[keys[len(keys)-1]] = value
 	}
-	return job.Errorf("No such container: %s", name)
+	return json.Marshal(selected)
 }
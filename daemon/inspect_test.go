@@ -0,0 +1,14 @@
+package daemon
+
+import "testing"
+
+func TestSelectFields(t *testing.T) {
+	b := []byte(`{"Id":"abc","State":{"Running":true,"Pid":42},"HostConfig":{"Memory":1073741824123},"Name":"/web"}`)
+	selected, err := selectFields(b, []string{"State.Running", "HostConfig.Memory", "Name", "State.Missing", "Id.Foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"HostConfig":{"Memory":1073741824123},"Name":"/web","State":{"Running":true}}`; string(selected) != expected {
+		t.Fatalf("Expected %s, got %s", expected, selected)
+	}
+}
@@ -0,0 +1,196 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/devices"
+)
+
+// ioDevice is the I/O done by a container on a block device of the host,
+// along with what the container keeps on that device: its rw layer and/or
+// some of its volumes.
+type ioDevice struct {
+	Device   string
+	RootFs   bool
+	Volumes  []string
+	Read     uint64
+	Write    uint64
+	ReadOps  uint64
+	WriteOps uint64
+}
+
+// ContainerIO reports the I/O of a running container by block device. As the
+// devices are mapped to the rw layer and the volumes of the container, the
+// writes to the layer can be told apart from the writes to the volumes when
+// they are stored on different devices.
+func (daemon *Daemon) ContainerIO(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+	sample, err := daemon.stats.get(container)
+	if err != nil {
+		return job.Errorf("Cannot get the I/O stats of %s: %s", name, err)
+	}
+
+	ioDevices := make(map[string]*ioDevice)
+	if dev, err := daemon.rwLayerDevice(container); err != nil {
+		log.Debugf("Cannot find the device of the rw layer of %s: %s", name, err)
+	} else {
+		ioDevices[dev] = &ioDevice{Device: dev, RootFs: true}
+	}
+	for volume, source := range container.Volumes {
+		dev, err := hostDevice(source)
+		if err != nil {
+			log.Debugf("Cannot find the device of volume %s of %s: %s", volume, name, err)
+			continue
+		}
+		if ioDevices[dev] == nil {
+			ioDevices[dev] = &ioDevice{Device: dev}
+		}
+		ioDevices[dev].Volumes = append(ioDevices[dev].Volumes, volume)
+	}
+	addBlkioStats(ioDevices, &sample.Stats.BlkioStats)
+
+	var devs []string
+	for dev := range ioDevices {
+		devs = append(devs, dev)
+	}
+	sort.Strings(devs)
+
+	outs := engine.NewTable("", 0)
+	for _, dev := range devs {
+		sort.Strings(ioDevices[dev].Volumes)
+		out := &engine.Env{}
+		if err := out.Import(ioDevices[dev]); err != nil {
+			return job.Error(err)
+		}
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// addBlkioStats adds the bytes and operations of stats to ioDevices, by
+// device. The devices the container keeps nothing on are added too, they
+// are typically the devices of the image layers or of the swap.
+func addBlkioStats(ioDevices map[string]*ioDevice, stats *cgroups.BlkioStats) {
+	serviceBytes, serviced := stats.IoServiceBytesRecursive, stats.IoServicedRecursive
+	if len(serviceBytes) == 0 {
+		// only the throttling policy accounts the I/O when the devices
+		// don't use the CFQ scheduler
+		serviceBytes, serviced = stats.ThrottleIoServiceBytes, stats.ThrottleIoServiced
+	}
+
+	get := func(entry cgroups.BlkioStatEntry) *ioDevice {
+		dev := fmt.Sprintf("%d:%d", entry.Major, entry.Minor)
+		if ioDevices[dev] == nil {
+			ioDevices[dev] = &ioDevice{Device: dev}
+		}
+		return ioDevices[dev]
+	}
+	for _, entry := range serviceBytes {
+		switch entry.Op {
+		case "Read":
+			get(entry).Read += entry.Value
+		case "Write":
+			get(entry).Write += entry.Value
+		}
+	}
+	for _, entry := range serviced {
+		switch entry.Op {
+		case "Read":
+			get(entry).ReadOps += entry.Value
+		case "Write":
+			get(entry).WriteOps += entry.Value
+		}
+	}
+}
+
+// rwLayerDevice returns the block device holding the rw layer of container.
+func (daemon *Daemon) rwLayerDevice(container *Container) (string, error) {
+	if dev, err := hostDevice(container.basefs); err == nil {
+		return dev, nil
+	}
+	// aufs and vfs are not mounted from a block device, the rw layer is
+	// stored in the home of the graph driver
+	return hostDevice(path.Join(daemon.config.Root, daemon.driver.String()))
+}
+
+// hostDevice returns the major:minor of the block device holding p. The
+// partitions are resolved to their disk, which is where the blkio cgroup
+// accounts their I/O.
+func hostDevice(p string) (string, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(p, &stat); err != nil {
+		return "", err
+	}
+	major, minor := devices.Major(int(stat.Dev)), devices.Minor(int(stat.Dev))
+	if major == 0 {
+		// btrfs and the union file systems have an anonymous device, look
+		// for the block device they are mounted from
+		var err error
+		if major, minor, err = mountSource(major, minor); err != nil {
+			return "", err
+		}
+	}
+	return diskDevice(major, minor), nil
+}
+
+// mountSource returns the device number of the block device mounted as the
+// anonymous device major:minor.
+func mountSource(major, minor int64) (int64, int64, error) {
+	mounts, err := mount.GetMounts()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, m := range mounts {
+		if int64(m.Major) != major || int64(m.Minor) != minor || !strings.HasPrefix(m.Source, "/dev/") {
+			continue
+		}
+		var stat syscall.Stat_t
+		if err := syscall.Stat(m.Source, &stat); err != nil {
+			return 0, 0, err
+		}
+		return devices.Major(int(stat.Rdev)), devices.Minor(int(stat.Rdev)), nil
+	}
+	return 0, 0, fmt.Errorf("no block device is mounted as %d:%d", major, minor)
+}
+
+// diskDevice returns the disk of the partition major:minor, or the device
+// itself if it is not a partition.
+func diskDevice(major, minor int64) string {
+	dev := fmt.Sprintf("%d:%d", major, minor)
+	sysPath, err := filepath.EvalSymlinks(filepath.Join("/sys/dev/block", dev))
+	if err != nil {
+		return dev
+	}
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err != nil {
+		return dev
+	}
+	disk, err := ioutil.ReadFile(filepath.Join(filepath.Dir(sysPath), "dev"))
+	if err != nil {
+		return dev
+	}
+	return strings.TrimSpace(string(disk))
+}
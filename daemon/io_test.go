@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/libcontainer/cgroups"
+)
+
+func TestAddBlkioStats(t *testing.T) {
+	ioDevices := map[string]*ioDevice{
+		"8:0": {Device: "8:0", RootFs: true},
+	}
+	stats := &cgroups.BlkioStats{
+		IoServiceBytesRecursive: []cgroups.BlkioStatEntry{
+			{Major: 8, Minor: 0, Op: "Read", Value: 4096},
+			{Major: 8, Minor: 0, Op: "Write", Value: 8192},
+			{Major: 8, Minor: 0, Op: "Total", Value: 12288},
+			{Major: 8, Minor: 16, Op: "Write", Value: 1024},
+		},
+		IoServicedRecursive: []cgroups.BlkioStatEntry{
+			{Major: 8, Minor: 0, Op: "Write", Value: 2},
+			{Major: 8, Minor: 16, Op: "Write", Value: 1},
+		},
+	}
+	addBlkioStats(ioDevices, stats)
+
+	if len(ioDevices) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(ioDevices))
+	}
+	rootfs := ioDevices["8:0"]
+	if !rootfs.RootFs || rootfs.Read != 4096 || rootfs.Write != 8192 || rootfs.WriteOps != 2 {
+		t.Fatalf("Unexpected rw layer device stats %+v", rootfs)
+	}
+	other := ioDevices["8:16"]
+	if other == nil || other.RootFs || other.Write != 1024 || other.WriteOps != 1 {
+		t.Fatalf("Unexpected stats for a device with no layer or volume %+v", other)
+	}
+}
+
+func TestAddBlkioStatsThrottle(t *testing.T) {
+	ioDevices := make(map[string]*ioDevice)
+	stats := &cgroups.BlkioStats{
+		ThrottleIoServiceBytes: []cgroups.BlkioStatEntry{
+			{Major: 253, Minor: 0, Op: "Read", Value: 4096},
+		},
+		ThrottleIoServiced: []cgroups.BlkioStatEntry{
+			{Major: 253, Minor: 0, Op: "Read", Value: 1},
+		},
+	}
+	addBlkioStats(ioDevices, stats)
+
+	dev := ioDevices["253:0"]
+	if dev == nil || dev.Read != 4096 || dev.ReadOps != 1 {
+		t.Fatalf("Expected the throttling stats without the scheduler ones, got %+v", dev)
+	}
+}
@@ -0,0 +1,308 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+)
+
+// lifecycleStats counts the starts, restarts, OOM kills and exit codes of a
+// container, or of all the containers of an image, since the daemon started.
+type lifecycleStats struct {
+	Starts    int
+	Restarts  int
+	OOMKills  int
+	ExitCodes map[int]int
+	LastExit  time.Time
+}
+
+func newLifecycleStats() *lifecycleStats {
+	return &lifecycleStats{ExitCodes: make(map[int]int)}
+}
+
+// lifecycleMetrics are the lifecycle stats of the containers, by id, and of
+// the images, by id. The stats of an image outlive its containers, for the
+// workloads crash looping by recreating their containers.
+type lifecycleMetrics struct {
+	sync.Mutex
+	containers map[string]*lifecycleStats
+	images     map[string]*lifecycleStats
+}
+
+func newLifecycleMetrics() *lifecycleMetrics {
+	return &lifecycleMetrics{
+		containers: make(map[string]*lifecycleStats),
+		images:     make(map[string]*lifecycleStats),
+	}
+}
+
+// record applies update to the stats of container and of its image.
+func (m *lifecycleMetrics) record(container *Container, update func(*lifecycleStats)) {
+	m.Lock()
+	defer m.Unlock()
+	for _, c := range []struct {
+		stats map[string]*lifecycleStats
+		id    string
+	}{{m.containers, container.ID}, {m.images, container.Image}} {
+		stats, exists := c.stats[c.id]
+		if !exists {
+			stats = newLifecycleStats()
+			c.stats[c.id] = stats
+		}
+		update(stats)
+	}
+}
+
+func (m *lifecycleMetrics) recordStart(container *Container, restart bool) {
+	m.record(container, func(stats *lifecycleStats) {
+		stats.Starts++
+		if restart {
+			stats.Restarts++
+		}
+	})
+}
+
+func (m *lifecycleMetrics) recordExit(container *Container, exitCode int) {
+	now := time.Now().UTC()
+	m.record(container, func(stats *lifecycleStats) {
+		stats.ExitCodes[exitCode]++
+		stats.LastExit = now
+	})
+}
+
+func (m *lifecycleMetrics) recordOOMKill(container *Container) {
+	m.record(container, func(stats *lifecycleStats) {
+		stats.OOMKills++
+	})
+}
+
+// forget drops the stats of a destroyed container, the ones of its image
+// are kept.
+func (m *lifecycleMetrics) forget(id string) {
+	m.Lock()
+	delete(m.containers, id)
+	m.Unlock()
+}
+
+// watchOOM counts the OOM kills in the memory cgroup of the running
+// container, and logs an oom event for each of them, along with the memory
+// limit in effect for the kills to be told apart from the ones before a
+// limit change. It returns once the cgroup is removed.
+func (daemon *Daemon) watchOOM(container *Container) {
+	oom, err := notifyOnOOM(container.ID, daemon.cgroupParent())
+	if err != nil {
+		log.Debugf("Cannot watch the OOM kills of %s: %s", container.ID, err)
+		return
+	}
+	go func() {
+		for _ = range oom {
+			container.Lock()
+			memory := container.Config.Memory
+			container.Unlock()
+			log.Infof("Container %s was OOM killed with a memory limit of %d bytes", container.ID, memory)
+			daemon.lifecycle.recordOOMKill(container)
+			container.LogEvent("oom")
+		}
+	}()
+}
+
+// watchMemoryUsage logs a warning, and a memory-warning event, each time the
+// memory usage of the running container grows over --memory-warning percent
+// of the memory limit it started with. The cgroup notifies the crossings of
+// the threshold both ways, the usage tells which one it is. It returns once
+// the cgroup is removed.
+func (daemon *Daemon) watchMemoryUsage(container *Container) {
+	percent := daemon.config.MemoryWarning
+	if percent == 0 || container.Config.Memory <= 0 {
+		return
+	}
+	threshold := container.Config.Memory / 100 * int64(percent)
+	events, err := notifyOnMemoryThreshold(container.ID, daemon.cgroupParent(), threshold)
+	if err != nil {
+		log.Debugf("Cannot watch the memory usage of %s: %s", container.ID, err)
+		return
+	}
+	go func() {
+		for _ = range events {
+			sample, err := daemon.stats.sample(container)
+			if err != nil || sample.Stats.MemoryStats.Usage < uint64(threshold) {
+				continue
+			}
+			log.Infof("Container %s uses %d bytes of memory, over %d%% of its limit of %d bytes", container.ID, sample.Stats.MemoryStats.Usage, percent, container.Config.Memory)
+			container.LogEvent("memory-warning")
+		}
+	}()
+}
+
+// memoryPressureInterval is the least time between two memory-pressure
+// events of the same level for a container, the kernel reporting the
+// pressure on each reclaim.
+const memoryPressureInterval = time.Second
+
+// watchMemoryPressure logs a memory-pressure-LEVEL event each time the
+// memory pressure of the running container reaches one of the levels of
+// --memory-pressure, for the tools scaling the containers to react to the
+// pressure before the limits are reached. It returns once the cgroup is
+// removed.
+func (daemon *Daemon) watchMemoryPressure(container *Container) {
+	for _, level := range daemon.config.MemoryPressure {
+		events, err := notifyOnMemoryPressure(container.ID, daemon.cgroupParent(), level)
+		if err != nil {
+			log.Debugf("Cannot watch the %s memory pressure of %s: %s", level, container.ID, err)
+			continue
+		}
+		go func(level string) {
+			var last time.Time
+			for _ = range events {
+				if time.Since(last) < memoryPressureInterval {
+					continue
+				}
+				last = time.Now()
+				container.LogEvent("memory-pressure-" + level)
+			}
+		}(level)
+	}
+}
+
+// lifecycleEnv returns stats as an engine.Env, the exit codes by their
+// string.
+func lifecycleEnv(stats *lifecycleStats) *engine.Env {
+	exitCodes := make(map[string]int, len(stats.ExitCodes))
+	for code, count := range stats.ExitCodes {
+		exitCodes[strconv.Itoa(code)] = count
+	}
+	out := &engine.Env{}
+	out.SetInt("Starts", stats.Starts)
+	out.SetInt("Restarts", stats.Restarts)
+	out.SetInt("OOMKills", stats.OOMKills)
+	out.SetJson("ExitCodes", exitCodes)
+	if !stats.LastExit.IsZero() {
+		out.SetInt64("LastExit", stats.LastExit.Unix())
+	}
+	return out
+}
+
+// LifecycleStats lists the lifecycle stats of the containers, or of the
+// images with "images" as argument, most restarted first.
+func (daemon *Daemon) LifecycleStats(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 || (job.Args[0] != "containers" && job.Args[0] != "images") {
+		return job.Errorf("Usage: %s containers|images", job.Name)
+	}
+	daemon.lifecycle.Lock()
+	defer daemon.lifecycle.Unlock()
+
+	var outs *engine.Table
+	if job.Args[0] == "containers" {
+		outs = engine.NewTable("Restarts", len(daemon.lifecycle.containers))
+		for id, stats := range daemon.lifecycle.containers {
+			out := lifecycleEnv(stats)
+			out.Set("Id", id)
+			if container := daemon.Get(id); container != nil {
+				out.Set("Name", container.Name)
+				out.Set("Image", daemon.Repositories().ImageName(container.Image))
+			}
+			outs.Add(out)
+		}
+	} else {
+		outs = engine.NewTable("Restarts", len(daemon.lifecycle.images))
+		for id, stats := range daemon.lifecycle.images {
+			out := lifecycleEnv(stats)
+			out.Set("Id", id)
+			out.Set("Image", daemon.Repositories().ImageName(id))
+			outs.Add(out)
+		}
+	}
+	outs.ReverseSort()
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Metrics writes the lifecycle stats of the containers and of the images in
+// the Prometheus text format.
+func (daemon *Daemon) Metrics(job *engine.Job) engine.Status {
+	daemon.lifecycle.Lock()
+	defer daemon.lifecycle.Unlock()
+
+	for _, metric := range []struct {
+		scope, name, help string
+		value             func(*lifecycleStats) int
+	}{
+		{"container", "starts", "Number of starts of the container", func(s *lifecycleStats) int { return s.Starts }},
+		{"container", "restarts", "Number of restarts of the container by its restart policy", func(s *lifecycleStats) int { return s.Restarts }},
+		{"container", "oom_kills", "Number of processes of the container killed by the OOM killer", func(s *lifecycleStats) int { return s.OOMKills }},
+		{"image", "starts", "Number of starts of the containers of the image", func(s *lifecycleStats) int { return s.Starts }},
+		{"image", "restarts", "Number of restarts of the containers of the image by their restart policy", func(s *lifecycleStats) int { return s.Restarts }},
+		{"image", "oom_kills", "Number of processes of the containers of the image killed by the OOM killer", func(s *lifecycleStats) int { return s.OOMKills }},
+	} {
+		name := fmt.Sprintf("docker_%s_%s_total", metric.scope, metric.name)
+		writeMetricHeader(job.Stdout, name, metric.help)
+		for _, labels := range daemon.metricLabels(metric.scope) {
+			fmt.Fprintf(job.Stdout, "%s{%s} %d\n", name, labels.labels, metric.value(labels.stats))
+		}
+	}
+	for _, metric := range []struct{ scope, help string }{
+		{"container", "Number of exits of the container by exit code"},
+		{"image", "Number of exits of the containers of the image by exit code"},
+	} {
+		name := fmt.Sprintf("docker_%s_exits_total", metric.scope)
+		writeMetricHeader(job.Stdout, name, metric.help)
+		for _, labels := range daemon.metricLabels(metric.scope) {
+			codes := make([]int, 0, len(labels.stats.ExitCodes))
+			for code := range labels.stats.ExitCodes {
+				codes = append(codes, code)
+			}
+			sort.Ints(codes)
+			for _, code := range codes {
+				fmt.Fprintf(job.Stdout, "%s{%s,code=\"%d\"} %d\n", name, labels.labels, code, labels.stats.ExitCodes[code])
+			}
+		}
+	}
+	return engine.StatusOK
+}
+
+type metricLabels struct {
+	labels string
+	stats  *lifecycleStats
+}
+
+// metricLabels returns the stats of the containers or of the images, with
+// their labels, sorted for the output to be stable.
+func (daemon *Daemon) metricLabels(scope string) []metricLabels {
+	stats := daemon.lifecycle.containers
+	if scope == "image" {
+		stats = daemon.lifecycle.images
+	}
+	ids := make([]string, 0, len(stats))
+	for id := range stats {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	all := make([]metricLabels, len(ids))
+	for i, id := range ids {
+		image := id
+		if scope == "container" {
+			if container := daemon.Get(id); container != nil {
+				image = container.Image
+			}
+		}
+		labels := fmt.Sprintf("image=%q", daemon.Repositories().ImageName(image))
+		if scope == "container" {
+			labels = fmt.Sprintf("container=%q,", id) + labels
+		}
+		all[i] = metricLabels{labels, stats[id]}
+	}
+	return all
+}
+
+func writeMetricHeader(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+}
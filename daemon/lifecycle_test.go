@@ -0,0 +1,36 @@
+package daemon
+
+import "testing"
+
+func TestLifecycleMetrics(t *testing.T) {
+	var (
+		metrics = newLifecycleMetrics()
+		web1    = &Container{ID: "web1", Image: "img"}
+		web2    = &Container{ID: "web2", Image: "img"}
+	)
+	metrics.recordStart(web1, false)
+	metrics.recordExit(web1, 137)
+	metrics.recordOOMKill(web1)
+	metrics.recordStart(web1, true)
+	metrics.recordExit(web1, 137)
+	metrics.recordStart(web2, false)
+	metrics.recordExit(web2, 0)
+
+	stats := metrics.containers["web1"]
+	if stats.Starts != 2 || stats.Restarts != 1 || stats.OOMKills != 1 || stats.ExitCodes[137] != 2 || stats.LastExit.IsZero() {
+		t.Fatalf("Unexpected stats of the container %+v", stats)
+	}
+	stats = metrics.images["img"]
+	if stats.Starts != 3 || stats.Restarts != 1 || stats.OOMKills != 1 || stats.ExitCodes[137] != 2 || stats.ExitCodes[0] != 1 {
+		t.Fatalf("Unexpected stats of the image %+v", stats)
+	}
+
+	// the stats of the image outlive its containers
+	metrics.forget("web1")
+	if _, exists := metrics.containers["web1"]; exists {
+		t.Fatal("Expected the stats of the destroyed container to be dropped")
+	}
+	if metrics.images["img"].Starts != 3 {
+		t.Fatal("Expected the stats of the image to be kept")
+	}
+}
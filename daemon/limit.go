@@ -0,0 +1,1144 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/parsers/filters"
+	"github.com/docker/docker/pkg/units"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/cgroups/fs"
+	"github.com/docker/libcontainer/cgroups/systemd"
+)
+
+// ContainerLimit changes the resource limits of a running container by
+// writing them to its cgroups. A paused container stays frozen meanwhile.
+//
+// saveChanges also stores them in its configuration, to survive a restart.
+// A stopped container gets its saved limits when it starts.
+// persistOnly saves the limits of a running container without writing them.
+// cgroup changes the limits of that child cgroup of the container instead.
+// dryRun validates the limits and reports the file writes they would make.
+// report reads the changed files back and reports their values.
+// many, or several containers, changes each container and reports each one.
+// filters changes the running containers matching them instead.
+func (daemon *Daemon) ContainerLimit(job *engine.Job) engine.Status {
+	if param := job.Getenv("filters"); param != "" {
+		if len(job.Args) != 0 {
+			return job.Errorf("Bad parameter, the containers are given either by name or by filters")
+		}
+		names, err := daemon.filterLimitContainers(param)
+		if err != nil {
+			return job.Error(err)
+		}
+		return daemon.limitContainers(job, names)
+	}
+	if len(job.Args) > 1 || (len(job.Args) == 1 && job.GetenvBool("many")) {
+		return daemon.limitContainers(job, job.Args)
+	}
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER [CONTAINER...]", job.Name)
+	}
+	if err := daemon.resolveLimitDeltas(job); err != nil {
+		return job.Error(err)
+	}
+	var (
+		name         = job.Args[0]
+		swappiness   = job.Getenv("memorySwappiness")
+		oomKill      = job.Getenv("oomKillDisable")
+		cpuShares    = job.GetenvInt64("cpuShares")
+		cpuQuota     = job.GetenvInt64("cpuQuota")
+		cpuPeriod    = job.GetenvInt64("cpuPeriod")
+		cpuBurst     = job.GetenvInt64("cpuBurst")
+		cpuRtRuntime = job.GetenvInt64("cpuRtRuntime")
+		cpuRtPeriod  = job.GetenvInt64("cpuRtPeriod")
+		cpuset       = job.Getenv("cpuset")
+		cpusetMems   = job.Getenv("cpusetMems")
+		pidsLimit    = job.GetenvInt64("pidsLimit")
+		blkioWeight  = job.GetenvInt64("blkioWeight")
+		readIOps     = job.GetenvList("blkioDeviceReadIOps")
+		writeIOps    = job.GetenvList("blkioDeviceWriteIOps")
+		hugetlb      = job.GetenvList("hugetlbLimit")
+		netCls       = job.Getenv("netClsClassid")
+		netPrio      = job.GetenvList("netPrio")
+		saveChanges  = job.GetenvBool("saveChanges")
+		childCgroup  = job.Getenv("cgroup")
+		dryRun       = job.GetenvBool("dryRun")
+
+		memory, reservation, memorySwap, kmem, kmemTCP int64
+	)
+	// the memory limits are in bytes or with a unit, 512m or 2g
+	for key, size := range map[string]*int64{
+		"memory":            &memory,
+		"memoryReservation": &reservation,
+		"memorySwap":        &memorySwap,
+		"kernelMemory":      &kmem,
+		"kernelMemoryTCP":   &kmemTCP,
+	} {
+		value, err := units.RAMLimitInBytes(job.Getenv(key))
+		if err != nil {
+			return job.Errorf("Bad parameter, invalid %s %s: %s", key, job.Getenv(key), err)
+		}
+		*size = value
+	}
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	// the limits of a stopped container can only be saved, it gets them
+	// when it starts, and with persistOnly the ones of a running container
+	// are only saved too, for it to get them when it restarts
+	persistOnly := job.GetenvBool("persistOnly")
+	if !container.State.IsRunning() && !saveChanges && !persistOnly {
+		return job.Errorf("Container %s is not running, its limits can only be saved", name)
+	}
+	live := container.State.IsRunning() && !persistOnly
+	saveChanges = saveChanges || persistOnly
+	cgroupId := container.ID
+	if childCgroup != "" {
+		// the container creates its child cgroups, there is nothing to
+		// restore the limits of when it restarts
+		if saveChanges {
+			return job.Errorf("Bad parameter, the limits of the child cgroup %s can not be saved", childCgroup)
+		}
+		cgroupId += "/" + childCgroup
+	}
+	if (memory != 0 || reservation != 0 || oomKill != "") && !daemon.SystemConfig().MemoryLimit {
+		return job.Errorf("Your kernel does not support memory limit capabilities")
+	}
+	if memorySwap != 0 && !daemon.SystemConfig().SwapLimit {
+		return job.Errorf("Your kernel does not support swap limit capabilities")
+	}
+	memswLimit, err := validateMemoryLimits(memory, memorySwap, reservation, container.Config.Memory, container.Config.MemorySwap, daemon.SystemConfig().SwapLimit)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	// a limit above the memory of the host is likely a typo, 512g for 512m
+	if memory > 0 {
+		if err := daemon.checkMemoryCapacity(container, childCgroup, memory, job.GetenvBool("force")); err != nil {
+			return job.Error(err)
+		}
+	}
+
+	var memorySwappiness int64
+	if swappiness != "" {
+		s, err := strconv.ParseInt(swappiness, 10, 64)
+		if err != nil || s < 0 || s > 100 {
+			return job.Errorf("Invalid memory swappiness %s, must be between 0 and 100", swappiness)
+		}
+		memorySwappiness = s
+	}
+
+	if err := validateCpuBandwidth(cpuQuota, cpuPeriod); err != nil {
+		return job.Error(err)
+	}
+	if cpuBurst != 0 && !daemon.SystemConfig().CpuCfsBurst {
+		return job.Errorf("Your kernel does not support CFS burst")
+	}
+	// -1 disables the burst, the kernel checks it against the quota in
+	// effect when only the burst is changed
+	if cpuBurst < -1 {
+		return job.Errorf("Invalid CPU burst %d", cpuBurst)
+	}
+	if cpuBurst > 0 && cpuQuota != 0 {
+		if err := validateCpuBurst(cpuBurst, cpuQuota); err != nil {
+			return job.Error(err)
+		}
+	}
+	if err := validateCpuRt(cpuRtRuntime, cpuRtPeriod); err != nil {
+		return job.Error(err)
+	}
+	if err := validateBlkioWeight(blkioWeight); err != nil {
+		return job.Error(err)
+	}
+	readIOpsRates, err := getBlkioThrottleRates(readIOps)
+	if err != nil {
+		return job.Error(err)
+	}
+	writeIOpsRates, err := getBlkioThrottleRates(writeIOps)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	hugetlbLimit, err := getHugetlbLimits(hugetlb)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	netClsClassid, err := getNetClsClassid(netCls)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	netPrioIfpriomap, err := getNetPrioIfpriomap(netPrio)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	// the limits are written as a whole, nothing is changed when one of them
+	// is refused
+	values := make(map[string]string)
+	// systemd reverts the cgroups of the scopes it manages to the properties
+	// of the scopes when it reloads, the limits it has a property for are
+	// changed through it. It leaves alone the child cgroups of the scopes.
+	viaSystemd := daemon.usesSystemdCgroups() && childCgroup == ""
+	if !viaSystemd {
+		if memory != 0 {
+			values["memory.limit_in_bytes"] = strconv.FormatInt(memory, 10)
+		}
+		if memswLimit != 0 {
+			values["memory.memsw.limit_in_bytes"] = strconv.FormatInt(memswLimit, 10)
+		}
+		if cpuShares != 0 {
+			values["cpu.shares"] = strconv.FormatInt(cpuShares, 10)
+		}
+		if blkioWeight != 0 {
+			values["blkio.weight"] = strconv.FormatInt(blkioWeight, 10)
+		}
+	}
+	if reservation != 0 {
+		values["memory.soft_limit_in_bytes"] = strconv.FormatInt(reservation, 10)
+	}
+	if kmem != 0 {
+		values["memory.kmem.limit_in_bytes"] = strconv.FormatInt(kmem, 10)
+	}
+	if kmemTCP != 0 {
+		values["memory.kmem.tcp.limit_in_bytes"] = strconv.FormatInt(kmemTCP, 10)
+	}
+	if swappiness != "" {
+		values["memory.swappiness"] = strconv.FormatInt(memorySwappiness, 10)
+	}
+	oomKillDisable := job.GetenvBool("oomKillDisable")
+	if oomKill != "" {
+		values["memory.oom_control"] = "0"
+		if oomKillDisable {
+			values["memory.oom_control"] = "1"
+		}
+	}
+	for key, value := range map[string]int64{
+		"cpu.cfs_quota_us":  cpuQuota,
+		"cpu.cfs_period_us": cpuPeriod,
+		"cpu.rt_runtime_us": cpuRtRuntime,
+		"cpu.rt_period_us":  cpuRtPeriod,
+	} {
+		if value != 0 {
+			values[key] = strconv.FormatInt(value, 10)
+		}
+	}
+	if cpuBurst != 0 {
+		values["cpu.cfs_burst_us"] = "0"
+		if cpuBurst > 0 {
+			values["cpu.cfs_burst_us"] = strconv.FormatInt(cpuBurst, 10)
+		}
+	}
+	if cpuset != "" {
+		values["cpuset.cpus"] = cpuset
+	}
+	if cpusetMems != "" {
+		values["cpuset.mems"] = cpusetMems
+	}
+	if pidsLimit != 0 {
+		values["pids.max"] = "max"
+		if pidsLimit > 0 {
+			values["pids.max"] = strconv.FormatInt(pidsLimit, 10)
+		}
+	}
+	// the entries of several devices or interfaces are written a line at a
+	// time
+	if len(readIOpsRates) != 0 {
+		values["blkio.throttle.read_iops_device"] = strings.Join(throttleEntries(readIOpsRates), "\n")
+	}
+	if len(writeIOpsRates) != 0 {
+		values["blkio.throttle.write_iops_device"] = strings.Join(throttleEntries(writeIOpsRates), "\n")
+	}
+	if netClsClassid != "" {
+		values["net_cls.classid"] = netClsClassid
+	}
+	for pageSize, limit := range hugetlbLimit {
+		values["hugetlb."+pageSize+".limit_in_bytes"] = strconv.FormatInt(limit, 10)
+	}
+	if len(netPrioIfpriomap) != 0 {
+		var entries []string
+		for iface, prio := range netPrioIfpriomap {
+			entries = append(entries, fmt.Sprintf("%s %d", iface, prio))
+		}
+		values["net_prio.ifpriomap"] = strings.Join(entries, "\n")
+	}
+
+	parent := daemon.cgroupParent()
+	// a memory limit under the usage makes the kernel kill the processes of
+	// the container, with force the memory is reclaimed first
+	if memory > 0 && live {
+		if err := checkMemoryUsage(name, cgroupId, parent, memory, job.GetenvBool("force") && !dryRun); err != nil {
+			return job.Error(err)
+		}
+	}
+	if dryRun {
+		// nothing is written to the cgroups of a stopped container, nor with
+		// persistOnly
+		if !live {
+			if _, err := engine.NewTable("", 0).WriteListTo(job.Stdout); err != nil {
+				return job.Error(err)
+			}
+			return engine.StatusOK
+		}
+		return planLimits(job, cgroupId, parent, values, viaSystemd, memory, memswLimit, cpuShares, blkioWeight)
+	}
+	// the files changed, along with the ones changed through systemd, and
+	// their values before the change, for the update event to tell them
+	changed := make(map[string]string, len(values))
+	for key, value := range values {
+		changed[key] = value
+	}
+	if viaSystemd {
+		addSystemdValues(changed, memory, memswLimit, cpuShares, blkioWeight)
+	}
+	var before map[string]string
+	if !live {
+		container.Lock()
+		before = savedLimits(container, changed)
+		container.Unlock()
+	}
+	if live {
+		// the limits of a paused container are written while its processes
+		// are frozen, it isn't unpaused meanwhile: its tasks move to a new
+		// cpuset once thawed, the safest time to change it
+		container.freezer.Lock()
+		defer container.freezer.Unlock()
+		before = readLimits(cgroupId, parent, changed)
+		if container.State.IsPaused() {
+			log.Debugf("Changing the limits of %s while it is paused", name)
+		}
+		if viaSystemd {
+			previous, err := getSystemdLimits(container.ID, parent, memory, memswLimit, cpuShares, blkioWeight)
+			if err != nil {
+				return job.Errorf("Cannot set the limits of %s through systemd: %s", name, cgroupError(err))
+			}
+			if err := setSystemdLimits(container.ID, parent, memory, memswLimit, cpuShares, blkioWeight); err != nil {
+				// the memory+swap limit may have been written before systemd
+				// failed, it is restored along with the properties
+				if err := setSystemdLimits(container.ID, parent, previous[0], previous[1], previous[2], previous[3]); err != nil {
+					log.Errorf("Cannot restore the limits of %s through systemd: %s", name, err)
+				}
+				return job.Errorf("Cannot set the limits of %s through systemd: %s", name, cgroupError(err))
+			}
+			if err := fs.SetMany(container.ID, parent, values); err != nil {
+				// the limits changed through systemd are restored with the others
+				if err := setSystemdLimits(container.ID, parent, previous[0], previous[1], previous[2], previous[3]); err != nil {
+					log.Errorf("Cannot restore the limits of %s through systemd: %s", name, err)
+				}
+				return job.Errorf("Cannot set the limits of %s: %s", name, cgroupError(err))
+			}
+		} else if err := fs.SetMany(cgroupId, parent, values); err != nil {
+			return job.Errorf("Cannot set the limits of %s: %s", name, cgroupError(err))
+		}
+		// a higher limit than the one of an ancestor of the cgroup has no effect
+		if memory > 0 {
+			if limit, err := fs.GetEffectiveLimit(cgroupId, parent, "memory.limit_in_bytes"); err == nil && limit.Effective != -1 && limit.Effective < memory {
+				log.Infof("The memory limit of %d bytes of %s has no effect, the cgroup %s limits it to %d bytes", memory, name, limit.Cgroup, limit.Effective)
+			}
+		}
+	}
+
+	if saveChanges {
+		container.Lock()
+		// a memory limit of -1 is saved as no limit, 0, as the container
+		// config gives it
+		if memory != 0 {
+			container.Config.Memory = unlimitedAsZero(memory)
+			if memory == -1 && memorySwap == 0 {
+				container.Config.MemorySwap = 0
+			}
+		}
+		if memorySwap != 0 {
+			container.Config.MemorySwap = memorySwap
+		}
+		if reservation != 0 {
+			container.hostConfig.MemoryReservation = unlimitedAsZero(reservation)
+		}
+		if kmem != 0 {
+			container.hostConfig.KernelMemory = unlimitedAsZero(kmem)
+		}
+		if kmemTCP != 0 {
+			container.hostConfig.KernelMemoryTCP = unlimitedAsZero(kmemTCP)
+		}
+		if swappiness != "" {
+			container.hostConfig.MemorySwappiness = &memorySwappiness
+		}
+		if oomKill != "" {
+			container.hostConfig.OomKillDisable = oomKillDisable
+		}
+		if cpuShares != 0 {
+			container.Config.CpuShares = cpuShares
+		}
+		if cpuQuota != 0 {
+			container.hostConfig.CpuQuota = cpuQuota
+		}
+		if cpuPeriod != 0 {
+			container.hostConfig.CpuPeriod = cpuPeriod
+		}
+		if cpuBurst != 0 {
+			container.hostConfig.CpuBurst = 0
+			if cpuBurst > 0 {
+				container.hostConfig.CpuBurst = cpuBurst
+			}
+		}
+		if cpuRtRuntime != 0 {
+			container.hostConfig.CpuRtRuntime = cpuRtRuntime
+		}
+		if cpuRtPeriod != 0 {
+			container.hostConfig.CpuRtPeriod = cpuRtPeriod
+		}
+		if cpuset != "" {
+			container.Config.Cpuset = cpuset
+		}
+		if cpusetMems != "" {
+			container.hostConfig.CpusetMems = cpusetMems
+		}
+		if pidsLimit != 0 {
+			container.hostConfig.PidsLimit = pidsLimit
+		}
+		if netCls != "" {
+			container.hostConfig.NetClsClassid = netCls
+		}
+		if blkioWeight != 0 {
+			container.hostConfig.BlkioWeight = blkioWeight
+		}
+		if len(readIOps) != 0 {
+			container.hostConfig.BlkioDeviceReadIOps = mergeSpecs(container.hostConfig.BlkioDeviceReadIOps, readIOpsRates, readIOps, getBlkioThrottleRates)
+		}
+		if len(writeIOps) != 0 {
+			container.hostConfig.BlkioDeviceWriteIOps = mergeSpecs(container.hostConfig.BlkioDeviceWriteIOps, writeIOpsRates, writeIOps, getBlkioThrottleRates)
+		}
+		if len(hugetlb) != 0 {
+			container.hostConfig.HugetlbLimits = mergeSpecs(container.hostConfig.HugetlbLimits, hugetlbLimit, hugetlb, getHugetlbLimits)
+		}
+		if len(netPrio) != 0 {
+			container.hostConfig.NetPrioIfpriomap = mergeSpecs(container.hostConfig.NetPrioIfpriomap, netPrioIfpriomap, netPrio, getNetPrioIfpriomap)
+		}
+		err := container.toDisk()
+		container.Unlock()
+		if err != nil {
+			return job.Error(err)
+		}
+	}
+	// nothing is read from the cgroups of a stopped container, nor with
+	// persistOnly, the update event only tells the saved values which
+	// changed. Limits written or saved back unchanged are no update.
+	if live {
+		changed = readLimits(cgroupId, parent, changed)
+	} else {
+		container.Lock()
+		changed = savedLimits(container, changed)
+		container.Unlock()
+	}
+	if changes := limitChanges(before, changed); len(changes) != 0 {
+		container.LogEventAttributes("update", changes)
+	}
+	if job.GetenvBool("report") {
+		if !live {
+			values = nil
+		}
+		if viaSystemd && live {
+			addSystemdValues(values, memory, memswLimit, cpuShares, blkioWeight)
+		}
+		return reportLimits(job, cgroupId, parent, values)
+	}
+	return engine.StatusOK
+}
+
+// limitDeltas are the limits which can be given as a change of their current
+// value, such as +256m or -128, with the cgroup file holding it.
+var limitDeltas = map[string]string{
+	"memory":            "memory.limit_in_bytes",
+	"memoryReservation": "memory.soft_limit_in_bytes",
+	"memorySwap":        "memory.memsw.limit_in_bytes",
+	"kernelMemory":      "memory.kmem.limit_in_bytes",
+	"kernelMemoryTCP":   "memory.kmem.tcp.limit_in_bytes",
+	"cpuShares":         "cpu.shares",
+	"cpuQuota":          "cpu.cfs_quota_us",
+	"pidsLimit":         "pids.max",
+	"blkioWeight":       "blkio.weight",
+}
+
+// resolveLimitDeltas replaces the limits of job given as a change, +N or -N,
+// with their new value, the current one read from the cgroup of the
+// container changed by N, for it to be validated and saved as any other.
+// The memory limits change by a size, such as +256m.
+func (daemon *Daemon) resolveLimitDeltas(job *engine.Job) error {
+	var (
+		name     = job.Args[0]
+		cgroupId string
+	)
+	for key, file := range limitDeltas {
+		delta := job.Getenv(key)
+		if !isLimitDelta(delta) {
+			continue
+		}
+		if cgroupId == "" {
+			container := daemon.Get(name)
+			if container == nil {
+				return fmt.Errorf("No such container: %s", name)
+			}
+			// the current limits are read from the cgroups
+			if !container.State.IsRunning() {
+				return fmt.Errorf("Container %s is not running, %s can't be changed by %s", name, key, delta)
+			}
+			cgroupId = container.ID
+			if childCgroup := job.Getenv("cgroup"); childCgroup != "" {
+				cgroupId += "/" + childCgroup
+			}
+		}
+		current, err := currentLimit(cgroupId, daemon.cgroupParent(), file)
+		if err != nil {
+			return fmt.Errorf("Cannot read the current %s of %s: %s", key, name, cgroupError(err))
+		}
+		value, err := applyLimitDelta(key, delta, current)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Changing %s of %s by %s, from %d to %d", key, name, delta, current, value)
+		job.Setenv(key, strconv.FormatInt(value, 10))
+	}
+	return nil
+}
+
+// isLimitDelta returns whether value is a change of the current limit, +N or
+// -N. -1 is left out: it is unlimited, as when a limit is given as a value.
+func isLimitDelta(value string) bool {
+	return value != "" && value != "-1" && (value[0] == '+' || value[0] == '-')
+}
+
+// applyLimitDelta returns the limit key changed by delta, +N or -N, from
+// current, -1 for unlimited.
+func applyLimitDelta(key, delta string, current int64) (int64, error) {
+	if current == -1 {
+		return 0, fmt.Errorf("Bad parameter, %s is unlimited, it can't be changed by %s", key, delta)
+	}
+	var (
+		n   int64
+		err error
+	)
+	if strings.HasPrefix(limitDeltas[key], "memory.") {
+		n, err = units.RAMInBytes(delta[1:])
+	} else {
+		n, err = strconv.ParseInt(delta[1:], 10, 64)
+	}
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Bad parameter, invalid change %s of %s", delta, key)
+	}
+	if delta[0] == '-' {
+		n = -n
+	}
+	if current+n <= 0 {
+		return 0, fmt.Errorf("Bad parameter, %s %s makes it %d", key, delta, current+n)
+	}
+	return current + n, nil
+}
+
+// currentLimit returns the limit of the cgroup file of id, -1 for unlimited.
+// The memory and pids limits are read in the unified hierarchy too.
+func currentLimit(id, parent, file string) (int64, error) {
+	if limit, err := fs.GetEffectiveLimit(id, parent, file); err == nil {
+		return limit.Own, nil
+	} else if fs.Cause(err) != fs.ErrNotAccessible {
+		return 0, err
+	}
+	value, err := fs.Get(id, parent, file)
+	if err != nil {
+		return 0, err
+	}
+	if value == "max" {
+		return -1, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	// the v1 hierarchy reports the largest memory limit for unlimited
+	if n >= math.MaxInt64&^int64(os.Getpagesize()-1) {
+		return -1, nil
+	}
+	return n, nil
+}
+
+// validateMemoryLimits checks the memory, memory+swap and reservation limits
+// given to limit, -1 being unlimited, against each other and the saved
+// memory and memory+swap limits of the container. It returns the memory+swap
+// limit to write: as when the container started, twice the memory limit by
+// default, and unlimited along with the memory.
+func validateMemoryLimits(memory, memorySwap, reservation, savedMemory, savedSwap int64, swapLimit bool) (int64, error) {
+	if memory < -1 || memory > 0 && memory < 524288 {
+		return 0, fmt.Errorf("Minimum memory limit allowed is 512k")
+	}
+	if memorySwap < -1 {
+		return 0, fmt.Errorf("Invalid memory+swap limit %d", memorySwap)
+	}
+	if reservation < -1 {
+		return 0, fmt.Errorf("Invalid memory reservation %d", reservation)
+	}
+	limit := memory
+	if limit == 0 {
+		limit = savedMemory
+	}
+	if memorySwap > 0 && (limit == -1 || memorySwap < limit) {
+		return 0, fmt.Errorf("Minimum memory+swap limit should be larger than the memory limit")
+	}
+	if reservation > 0 && limit > 0 && reservation > limit {
+		return 0, fmt.Errorf("Memory reservation must be lower than the memory limit")
+	}
+	memswLimit := memorySwap
+	if memorySwap == 0 && swapLimit {
+		switch {
+		case memory == -1:
+			// the kernel keeps the memory+swap limit above the memory one
+			memswLimit = -1
+		case memory > 0 && savedSwap == 0:
+			memswLimit = memory * 2
+		}
+	}
+	return memswLimit, nil
+}
+
+// unlimitedAsZero returns 0, no limit in the container config, for a limit of
+// -1.
+func unlimitedAsZero(limit int64) int64 {
+	if limit == -1 {
+		return 0
+	}
+	return limit
+}
+
+// checkMemoryCapacity refuses a memory limit of container above the memory
+// of the host, or making the limits of the running containers add up to
+// more than it. With force, the limit is only logged about, for the hosts
+// overcommitting their memory on purpose. The limit of a child cgroup is
+// within the one of the container, it is only checked against the memory of
+// the host.
+func (daemon *Daemon) checkMemoryCapacity(container *Container, childCgroup string, memory int64, force bool) error {
+	memTotal := daemon.SystemConfig().MemTotal
+	if memTotal == 0 {
+		return nil
+	}
+	var committed int64
+	if childCgroup == "" {
+		for _, c := range daemon.List() {
+			if c.ID == container.ID || !c.State.IsRunning() {
+				continue
+			}
+			// the limits changed without being saved are the ones in force
+			if limit, err := fs.GetEffectiveLimit(c.ID, daemon.cgroupParent(), "memory.limit_in_bytes"); err == nil && limit.Own != -1 {
+				committed += limit.Own
+			}
+		}
+	}
+	err := validateMemoryCapacity(memory, memTotal, committed)
+	if err != nil && force {
+		log.Infof("Forcing the memory limit of %s: %s", container.ID, err)
+		return nil
+	}
+	return err
+}
+
+// checkMemoryUsage refuses a memory limit of the cgroup id of the container
+// name under its memory usage, unless reclaim makes the kernel reclaim the
+// memory over it first.
+func checkMemoryUsage(name, id, parent string, memory int64, reclaim bool) error {
+	usage, err := fs.GetMemoryUsage(id, parent)
+	if err != nil {
+		// without the memory controller the limit can't be written anyway
+		log.Debugf("Cannot read the memory usage of %s: %s", name, err)
+		return nil
+	}
+	if usage > memory && reclaim {
+		log.Debugf("Reclaiming %d bytes of the memory of %s", usage-memory, name)
+		if usage, err = fs.ReclaimMemory(id, parent, memory); err != nil {
+			return fmt.Errorf("Cannot reclaim the memory of %s: %s", name, cgroupError(err))
+		}
+	}
+	if usage > memory {
+		if reclaim {
+			return fmt.Errorf("Conflict, the memory usage of %s is still %s once reclaimed, above the new memory limit of %s", name, units.HumanSize(usage), units.HumanSize(memory))
+		}
+		return fmt.Errorf("Conflict, the memory usage of %s is %s, above the new memory limit of %s, force it to reclaim the memory first", name, units.HumanSize(usage), units.HumanSize(memory))
+	}
+	return nil
+}
+
+// validateMemoryCapacity checks a memory limit against memTotal, the memory
+// of the host, and against committed, the sum of the memory limits of the
+// other running containers.
+func validateMemoryCapacity(memory, memTotal, committed int64) error {
+	if memory > memTotal {
+		return fmt.Errorf("Bad parameter, the memory limit of %s is above the %s of memory of the host, force it to set it anyway", units.HumanSize(memory), units.HumanSize(memTotal))
+	}
+	if memory+committed > memTotal {
+		return fmt.Errorf("Bad parameter, the memory limits of the running containers would add up to %s, above the %s of memory of the host, force it to set it anyway", units.HumanSize(memory+committed), units.HumanSize(memTotal))
+	}
+	return nil
+}
+
+// limitContainers changes the limits of each of the containers names the
+// way job says, in a limit job of its own, and reports the outcome for each
+// of them in order:
+// its Name, along with the Error which made it fail or, with report or
+// dryRun, the Output of its job. A container failing stops neither the
+// others nor undoes their changes.
+func (daemon *Daemon) limitContainers(job *engine.Job, names []string) engine.Status {
+	outs := engine.NewTable("", 0)
+	for _, name := range names {
+		var (
+			stdout bytes.Buffer
+			limit  = job.Eng.Job("limit", name)
+			out    = &engine.Env{}
+		)
+		for key, value := range job.Environ() {
+			// the request token is claimed for all the containers at once
+			if key != "RequestToken" && key != "many" && key != "filters" {
+				limit.Setenv(key, value)
+			}
+		}
+		limit.Stdout.Add(&stdout)
+		out.Set("Name", name)
+		if err := limit.Run(); err != nil {
+			out.Set("Error", err.Error())
+		} else if output := bytes.TrimSpace(stdout.Bytes()); len(output) != 0 {
+			out.Set("Output", string(output))
+		}
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// filterLimitContainers returns the names of the running containers matching
+// the filters param: image, the image they run, and name, a prefix of their
+// name. The values of a filter are alternatives, a container has to match
+// all the filters.
+func (daemon *Daemon) filterLimitContainers(param string) ([]string, error) {
+	limitFilters, err := filters.FromParam(param)
+	if err != nil {
+		return nil, fmt.Errorf("Bad parameter, invalid filters: %s", err)
+	}
+	if len(limitFilters) == 0 {
+		return nil, fmt.Errorf("Bad parameter, no filter to select the containers with")
+	}
+	images := make(map[string]bool)
+	for key, values := range limitFilters {
+		switch key {
+		case "image":
+			for _, name := range values {
+				img, err := daemon.repositories.LookupImage(name)
+				if err != nil || img == nil {
+					return nil, fmt.Errorf("No such image: %s", name)
+				}
+				images[img.ID] = true
+			}
+		case "name":
+		case "label":
+			return nil, fmt.Errorf("Bad parameter, the containers have no labels to filter on")
+		default:
+			return nil, fmt.Errorf("Bad parameter, unknown filter %s, must be image or name", key)
+		}
+	}
+	var names []string
+	for _, container := range daemon.List() {
+		name := strings.TrimPrefix(container.Name, "/")
+		if !container.State.IsRunning() {
+			continue
+		}
+		if _, exists := limitFilters["image"]; exists && !images[container.Image] {
+			continue
+		}
+		if prefixes, exists := limitFilters["name"]; exists && !hasAnyPrefix(name, prefixes) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// hasAnyPrefix returns whether s starts with one of prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// systemdFiles are the cgroup files systemd writes the properties of the
+// scopes of the containers to.
+var systemdFiles = map[string]bool{
+	"memory.limit_in_bytes": true,
+	"cpu.shares":            true,
+	"blkio.weight":          true,
+	"io.weight":             true,
+}
+
+// planLimits reports the file writes of values to the cgroup id, along with
+// the ones of the limits changed through systemd when viaSystemd, marked as
+// such as systemd makes them.
+func planLimits(job *engine.Job, id, parent string, values map[string]string, viaSystemd bool, memory, memorySwap, cpuShares, blkioWeight int64) engine.Status {
+	if viaSystemd {
+		addSystemdValues(values, memory, memorySwap, cpuShares, blkioWeight)
+	}
+	writes, err := fs.PlanMany(id, parent, values)
+	if err != nil {
+		return job.Errorf("Cannot set the limits of %s: %s", job.Args[0], cgroupError(err))
+	}
+	outs := engine.NewTable("", 0)
+	for _, w := range writes {
+		out := &engine.Env{}
+		out.Set("File", w.Path)
+		out.Set("Value", w.Value)
+		out.SetBool("Systemd", viaSystemd && systemdFiles[filepath.Base(w.Path)])
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// addSystemdValues adds to values the files of the limits changed through
+// systemd, with the values systemd writes to them.
+func addSystemdValues(values map[string]string, memory, memorySwap, cpuShares, blkioWeight int64) {
+	for key, value := range map[string]int64{
+		"memory.limit_in_bytes":       memory,
+		"memory.memsw.limit_in_bytes": memorySwap,
+		"cpu.shares":                  cpuShares,
+		"blkio.weight":                blkioWeight,
+	} {
+		if value != 0 {
+			values[key] = strconv.FormatInt(value, 10)
+		}
+	}
+}
+
+// reportLimits reports the values the kernel holds, once written, for the
+// files of values in the cgroup id, which may differ from the ones written:
+// the kernel rounds the memory limits to pages, for instance. The limits are
+// changed whatever, a file which can't be read back is left out.
+func reportLimits(job *engine.Job, id, parent string, values map[string]string) engine.Status {
+	out := &engine.Env{}
+	for key := range values {
+		value, err := fs.Get(id, parent, key)
+		if err != nil {
+			log.Errorf("Cannot read back the limit %s of %s: %s", key, job.Args[0], cgroupError(err))
+			continue
+		}
+		out.Set(key, strings.TrimSpace(value))
+	}
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// readLimits returns the values the kernel holds for the files of values in
+// the cgroup id. A file which can't be read is left out.
+func readLimits(id, parent string, values map[string]string) map[string]string {
+	current := make(map[string]string, len(values))
+	for key := range values {
+		value, err := fs.Get(id, parent, key)
+		if err != nil {
+			continue
+		}
+		current[key] = strings.TrimSpace(value)
+	}
+	return current
+}
+
+// savedLimits returns the limits saved in the config of container for the
+// cgroup files of values, joining the specs of the files holding one entry per
+// device. The caller locks the container.
+func savedLimits(container *Container, values map[string]string) map[string]string {
+	config, hostConfig := container.Config, container.hostConfig
+	saved := map[string]string{
+		"memory.limit_in_bytes":            strconv.FormatInt(config.Memory, 10),
+		"memory.memsw.limit_in_bytes":      strconv.FormatInt(config.MemorySwap, 10),
+		"memory.soft_limit_in_bytes":       strconv.FormatInt(hostConfig.MemoryReservation, 10),
+		"memory.kmem.limit_in_bytes":       strconv.FormatInt(hostConfig.KernelMemory, 10),
+		"memory.kmem.tcp.limit_in_bytes":   strconv.FormatInt(hostConfig.KernelMemoryTCP, 10),
+		"memory.oom_control":               strconv.FormatBool(hostConfig.OomKillDisable),
+		"cpu.shares":                       strconv.FormatInt(config.CpuShares, 10),
+		"cpu.cfs_quota_us":                 strconv.FormatInt(hostConfig.CpuQuota, 10),
+		"cpu.cfs_period_us":                strconv.FormatInt(hostConfig.CpuPeriod, 10),
+		"cpu.cfs_burst_us":                 strconv.FormatInt(hostConfig.CpuBurst, 10),
+		"cpu.rt_runtime_us":                strconv.FormatInt(hostConfig.CpuRtRuntime, 10),
+		"cpu.rt_period_us":                 strconv.FormatInt(hostConfig.CpuRtPeriod, 10),
+		"cpuset.cpus":                      config.Cpuset,
+		"cpuset.mems":                      hostConfig.CpusetMems,
+		"pids.max":                         strconv.FormatInt(hostConfig.PidsLimit, 10),
+		"blkio.weight":                     strconv.FormatInt(hostConfig.BlkioWeight, 10),
+		"blkio.throttle.read_iops_device":  joinSpecs(hostConfig.BlkioDeviceReadIOps),
+		"blkio.throttle.write_iops_device": joinSpecs(hostConfig.BlkioDeviceWriteIOps),
+		"net_cls.classid":                  hostConfig.NetClsClassid,
+		"net_prio.ifpriomap":               joinSpecs(hostConfig.NetPrioIfpriomap),
+	}
+	if hostConfig.MemorySwappiness != nil {
+		saved["memory.swappiness"] = strconv.FormatInt(*hostConfig.MemorySwappiness, 10)
+	}
+	if limits, err := getHugetlbLimits(hostConfig.HugetlbLimits); err == nil {
+		for pageSize, limit := range limits {
+			saved["hugetlb."+pageSize+".limit_in_bytes"] = strconv.FormatInt(limit, 10)
+		}
+	}
+	limits := make(map[string]string, len(values))
+	for key := range values {
+		if value, exists := saved[key]; exists {
+			limits[key] = value
+		}
+	}
+	return limits
+}
+
+// joinSpecs returns specs sorted and joined by lines, for the order in which
+// they were saved not to tell them apart.
+func joinSpecs(specs []string) string {
+	sorted := append([]string(nil), specs...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\n")
+}
+
+// limitChanges returns the files of after whose value differs from the one
+// in before, as OLD->NEW, OLD being empty when before doesn't hold it. The
+// lines of the files holding one entry per device are joined by "; ".
+func limitChanges(before, after map[string]string) map[string]string {
+	changes := make(map[string]string)
+	for key, value := range after {
+		if old, exists := before[key]; !exists || old != value {
+			changes[key] = strings.Replace(old, "\n", "; ", -1) + "->" + strings.Replace(value, "\n", "; ", -1)
+		}
+	}
+	return changes
+}
+
+// mergeSpecs returns the specs of current whose key, as returned by parse,
+// is not in updated, followed by the new specs.
+func mergeSpecs(current []string, updated map[string]int64, specs []string, parse func([]string) (map[string]int64, error)) []string {
+	var merged []string
+	for _, spec := range current {
+		values, err := parse([]string{spec})
+		if err != nil {
+			continue
+		}
+		for key := range values {
+			if _, exists := updated[key]; !exists {
+				merged = append(merged, spec)
+			}
+		}
+	}
+	return append(merged, specs...)
+}
+
+// getBlkioThrottleRates resolves /path/to/device:rate throttling specs into
+// rates keyed by the major:minor of the devices.
+func getBlkioThrottleRates(specs []string) (map[string]int64, error) {
+	entries, err := getBlkioThrottleDevices(specs)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		fields := strings.Fields(entry)
+		rate, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		rates[fields[0]] = rate
+	}
+	return rates, nil
+}
+
+// throttleEntries returns the "major:minor rate" entries of rates.
+func throttleEntries(rates map[string]int64) []string {
+	var entries []string
+	for device, rate := range rates {
+		entries = append(entries, fmt.Sprintf("%s %d", device, rate))
+	}
+	return entries
+}
+
+// ContainerLimits reports the limits in effect in the cgroups of a running
+// container, by cgroup file, the ones limit can change. With cgroup, the
+// ones of that child cgroup of the container are reported. With effective,
+// the memory and pids limits are reported along with the lowest of the
+// limits of the ancestors of the cgroup, the one the kernel enforces.
+func (daemon *Daemon) ContainerLimits(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+	cgroupId := container.ID
+	if childCgroup := job.Getenv("cgroup"); childCgroup != "" {
+		cgroupId += "/" + childCgroup
+	}
+	out := &engine.Env{}
+	if job.GetenvBool("effective") {
+		limits, err := fs.GetEffectiveLimits(cgroupId, daemon.cgroupParent())
+		if err != nil {
+			return job.Errorf("Cannot get the limits of %s: %s", name, cgroupError(err))
+		}
+		for file, limit := range limits {
+			out.SetJson(file, limit)
+		}
+		if _, err := out.WriteTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	}
+	values, err := fs.GetAll(cgroupId, daemon.cgroupParent())
+	if err != nil {
+		return job.Errorf("Cannot get the limits of %s: %s", name, cgroupError(err))
+	}
+	for file, value := range values {
+		out.Set(file, value)
+	}
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// getSystemdLimits returns the memory limit, the memory+swap limit, the cpu
+// shares and the blkio weight of the container id, to be restored by
+// setSystemdLimits; only the ones given are read, the others are 0.
+func getSystemdLimits(id, parent string, memory, memorySwap, cpuShares, blkioWeight int64) ([4]int64, error) {
+	var previous [4]int64
+	for i, limit := range []struct {
+		key   string
+		value int64
+	}{
+		{"memory.limit_in_bytes", memory},
+		{"memory.memsw.limit_in_bytes", memorySwap},
+		{"cpu.shares", cpuShares},
+		{"blkio.weight", blkioWeight},
+	} {
+		if limit.value == 0 {
+			continue
+		}
+		value, err := fs.Get(id, parent, limit.key)
+		if err != nil {
+			return previous, err
+		}
+		if previous[i], err = strconv.ParseInt(value, 10, 64); err != nil {
+			return previous, err
+		}
+	}
+	return previous, nil
+}
+
+// setSystemdLimits changes the memory limit, the cpu shares and the blkio
+// weight of the container id through the properties of its systemd scope.
+// systemd has no property for the memory+swap limit, it is written to the
+// cgroup in the order the kernel accepts it with the memory limit.
+func setSystemdLimits(id, parent string, memory, memorySwap, cpuShares, blkioWeight int64) error {
+	swapFirst := false
+	if memory != 0 && memorySwap != 0 {
+		value, err := fs.Get(id, parent, "memory.limit_in_bytes")
+		if err != nil {
+			return err
+		}
+		current, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		swapFirst = memory == -1 || uint64(memory) > current
+	}
+	if swapFirst {
+		if err := fs.SetMemory(id, parent, 0, memorySwap); err != nil {
+			return err
+		}
+	}
+	unit := &cgroups.Cgroup{
+		Name:        id,
+		Parent:      parent,
+		Memory:      memory,
+		CpuShares:   cpuShares,
+		BlkioWeight: blkioWeight,
+	}
+	if err := systemd.SetUnitResources(unit); err != nil {
+		return err
+	}
+	if memorySwap != 0 && !swapFirst {
+		return fs.SetMemory(id, parent, 0, memorySwap)
+	}
+	return nil
+}
+
+// usesSystemdCgroups reports whether the cgroups of the containers are the
+// scopes systemd creates for the native driver.
+func (daemon *Daemon) usesSystemdCgroups() bool {
+	return daemon.cgroupParent() == "docker" && systemd.UseSystemd()
+}
+
+// cgroupParent returns the cgroup the exec driver creates the cgroups of
+// the containers in.
+func (daemon *Daemon) cgroupParent() string {
+	if strings.HasPrefix(daemon.execDriver.Name(), "lxc") {
+		return "lxc"
+	}
+	return "docker"
+}
+
+// cgroupError words the errors of the access to the cgroups of a container
+// for the users, and for the API to answer them with their status code.
+func cgroupError(err error) error {
+	if rollbackErr, ok := err.(*fs.RollbackError); ok {
+		err := cgroupError(rollbackErr.Err)
+		if len(rollbackErr.Unrestored) == 0 {
+			return fmt.Errorf("%s, the %s cgroup refused the change and the other limits were restored", err, rollbackErr.Subsystem)
+		}
+		var keys []string
+		for _, restoreErr := range rollbackErr.Unrestored {
+			keys = append(keys, restoreErr.Key)
+		}
+		return fmt.Errorf("%s, the %s cgroup refused the change and %s could not be restored", err, rollbackErr.Subsystem, strings.Join(keys, ", "))
+	}
+	e, ok := err.(*fs.AccessError)
+	if !ok {
+		return err
+	}
+	if valueErr, ok := e.Err.(*fs.ValueError); ok {
+		return fmt.Errorf("Bad parameter, invalid value %q of %s: %s", valueErr.Value, e.Key, valueErr.Reason)
+	}
+	switch e.Err {
+	case fs.ErrSubsystemNotMounted:
+		return fmt.Errorf("Impossible, the %s cgroup is not mounted on the host", strings.SplitN(e.Key, ".", 2)[0])
+	case fs.ErrCgroupNotFound:
+		if parts := strings.SplitN(e.Id, "/", 2); len(parts) == 2 {
+			return fmt.Errorf("No such child cgroup %s of the %s cgroup of the container", parts[1], strings.SplitN(e.Key, ".", 2)[0])
+		}
+		return fmt.Errorf("No such cgroup %s, the container stopped", e.Key)
+	case fs.ErrInvalidCgroupPath:
+		return fmt.Errorf("Bad parameter, %s", e.Err)
+	case fs.ErrNotAccessible, fs.ErrNotRestorable:
+		return fmt.Errorf("Bad parameter, %s can not be changed on a running container", e.Key)
+	case fs.ErrInvalidValue:
+		return fmt.Errorf("Bad parameter, the kernel refused the value of %s", e.Key)
+	case fs.ErrPermission:
+		return fmt.Errorf("Forbidden, the daemon is not allowed to change %s", e.Key)
+	}
+	return err
+}
@@ -0,0 +1,273 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/libcontainer/cgroups/fs"
+)
+
+func TestMergeSpecs(t *testing.T) {
+	current := []string{"eth0:5", "docker0:1"}
+	specs := []string{"eth0:7"}
+	updated, err := getNetPrioIfpriomap(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := mergeSpecs(current, updated, specs, getNetPrioIfpriomap)
+	if len(merged) != 2 || merged[0] != "docker0:1" || merged[1] != "eth0:7" {
+		t.Fatalf("Unexpected merged specs: %v", merged)
+	}
+}
+
+func TestApplyLimitDelta(t *testing.T) {
+	for _, c := range []struct {
+		key, delta string
+		current    int64
+		expected   int64
+	}{
+		{"memory", "+256m", 256 << 20, 512 << 20},
+		{"memory", "-128m", 512 << 20, 384 << 20},
+		{"cpuShares", "-128", 1024, 896},
+		{"pidsLimit", "+100", 200, 300},
+	} {
+		value, err := applyLimitDelta(c.key, c.delta, c.current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != c.expected {
+			t.Fatalf("Expected %s %s from %d to make %d, got %d", c.key, c.delta, c.current, c.expected, value)
+		}
+	}
+	for _, c := range []struct {
+		key, delta string
+		current    int64
+	}{
+		{"memory", "+256m", -1},
+		{"cpuShares", "-2048", 1024},
+		{"cpuShares", "+1k", 1024},
+		{"memory", "+-5", 1024},
+	} {
+		if _, err := applyLimitDelta(c.key, c.delta, c.current); err == nil || !strings.Contains(err.Error(), "Bad parameter") {
+			t.Fatalf("Expected %s %s from %d to be refused, got %v", c.key, c.delta, c.current, err)
+		}
+	}
+	// -1 is unlimited, not a change of the current limit
+	for value, expected := range map[string]bool{
+		"-1":   false,
+		"-2":   true,
+		"+1":   true,
+		"-1m":  true,
+		"1024": false,
+		"":     false,
+	} {
+		if isLimitDelta(value) != expected {
+			t.Fatalf("Expected %q to be a change: %v, got %v", value, expected, !expected)
+		}
+	}
+}
+
+func TestLimitChanges(t *testing.T) {
+	changes := limitChanges(map[string]string{
+		"memory.limit_in_bytes": "67108864",
+		"cpu.shares":            "1024",
+	}, map[string]string{
+		"memory.limit_in_bytes":           "134217728",
+		"cpu.shares":                      "1024",
+		"blkio.throttle.read_iops_device": "8:0 100\n8:16 200",
+	})
+	expected := map[string]string{
+		"memory.limit_in_bytes":           "67108864->134217728",
+		"blkio.throttle.read_iops_device": "->8:0 100; 8:16 200",
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected the changes %v, got %v", expected, changes)
+	}
+}
+
+func TestSavedLimitChanges(t *testing.T) {
+	container := &Container{
+		Config: &runconfig.Config{Memory: 64 << 20, CpuShares: 512},
+		hostConfig: &runconfig.HostConfig{
+			BlkioDeviceReadIOps: []string{"/dev/sdb:200", "/dev/sda:100"},
+		},
+	}
+	values := map[string]string{
+		"memory.limit_in_bytes":           "67108864",
+		"cpu.shares":                      "512",
+		"blkio.throttle.read_iops_device": "8:0 100",
+	}
+	before := savedLimits(container, values)
+	// saved back unchanged, with the specs in another order
+	container.hostConfig.BlkioDeviceReadIOps = []string{"/dev/sda:100", "/dev/sdb:200"}
+	if changes := limitChanges(before, savedLimits(container, values)); len(changes) != 0 {
+		t.Fatalf("Expected no change of the saved limits, got %v", changes)
+	}
+	container.Config.Memory = 128 << 20
+	changes := limitChanges(before, savedLimits(container, values))
+	if expected := map[string]string{"memory.limit_in_bytes": "67108864->134217728"}; !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected the changes %v, got %v", expected, changes)
+	}
+}
+
+func TestValidateMemoryCapacity(t *testing.T) {
+	const gb = 1 << 30
+	for _, c := range []struct {
+		memory, committed int64
+		valid             bool
+	}{
+		{512 << 20, 0, true},
+		{32 * gb, 16 * gb, true},
+		{512 * gb, 0, false},
+		{32 * gb, 40 * gb, false},
+	} {
+		err := validateMemoryCapacity(c.memory, 64*gb, c.committed)
+		if c.valid && err != nil {
+			t.Fatalf("Expected the memory limit %d to be accepted with %d committed, got %s", c.memory, c.committed, err)
+		}
+		if !c.valid && (err == nil || !strings.Contains(err.Error(), "Bad parameter")) {
+			t.Fatalf("Expected the memory limit %d to be refused with %d committed, got %v", c.memory, c.committed, err)
+		}
+	}
+}
+
+func TestValidateMemoryLimits(t *testing.T) {
+	const mb = 1 << 20
+	for _, c := range []struct {
+		memory, memorySwap, reservation, savedMemory, savedSwap int64
+		memswLimit                                              int64
+		valid                                                   bool
+	}{
+		{-1, 0, 0, 512 * mb, 1024 * mb, -1, true},
+		{-1, 0, 256 * mb, 512 * mb, 0, -1, true},
+		{-1, 1024 * mb, 0, 512 * mb, 0, 0, false},
+		{256 << 10, 0, 0, 0, 0, 0, false},
+		{0, 0, -1, 512 * mb, 0, 0, true},
+		{0, -1, 0, 512 * mb, 0, -1, true},
+		{512 * mb, 0, 0, 0, 0, 1024 * mb, true},
+		{512 * mb, 0, 0, 256 * mb, 768 * mb, 0, true},
+		{0, 256 * mb, 0, 512 * mb, 0, 0, false},
+		{0, 0, 1024 * mb, 512 * mb, 0, 0, false},
+		{-2, 0, 0, 0, 0, 0, false},
+	} {
+		memswLimit, err := validateMemoryLimits(c.memory, c.memorySwap, c.reservation, c.savedMemory, c.savedSwap, true)
+		if c.valid && err != nil {
+			t.Fatalf("Expected the memory limits %+v to be accepted, got %s", c, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Expected the memory limits %+v to be refused", c)
+		}
+		if c.valid && memswLimit != c.memswLimit {
+			t.Fatalf("Expected the memory+swap limit %d for %+v, got %d", c.memswLimit, c, memswLimit)
+		}
+	}
+}
+
+func TestCgroupError(t *testing.T) {
+	for err, expected := range map[error]string{
+		&fs.AccessError{Key: "memory.limit_in_bytes", Err: fs.ErrSubsystemNotMounted}: "Impossible, the memory cgroup",
+		&fs.AccessError{Key: "pids.max", Err: fs.ErrCgroupNotFound}:                   "No such cgroup pids.max",
+		&fs.AccessError{Key: "memory.stat", Err: fs.ErrNotAccessible}:                 "Bad parameter",
+		&fs.AccessError{Key: "cpuset.cpus", Err: fs.ErrInvalidValue}:                  "Bad parameter",
+		&fs.AccessError{Key: "devices.allow", Err: fs.ErrPermission}:                  "Forbidden",
+		&fs.AccessError{Key: "cpu", Err: fmt.Errorf("unexpected")}:                    "cpu: unexpected",
+		fmt.Errorf("not cgroups"):                                                     "not cgroups",
+		&fs.RollbackError{
+			Subsystem: "cpuset",
+			Err:       &fs.AccessError{Key: "cpuset.cpus", Err: fs.ErrInvalidValue},
+			Restored:  []string{"cpuset.cpus", "memory.limit_in_bytes"},
+		}: "Bad parameter, the kernel refused the value of cpuset.cpus, the cpuset cgroup refused the change and the other limits were restored",
+		&fs.RollbackError{
+			Subsystem:  "cpuset",
+			Err:        &fs.AccessError{Key: "cpuset.cpus", Err: fs.ErrPermission},
+			Unrestored: []*fs.AccessError{{Key: "memory.limit_in_bytes", Err: fs.ErrPermission}},
+		}: "Forbidden, the daemon is not allowed to change cpuset.cpus, the cpuset cgroup refused the change and memory.limit_in_bytes could not be restored",
+	} {
+		if msg := cgroupError(err).Error(); !strings.HasPrefix(msg, expected) {
+			t.Fatalf("Expected %q to start with %q", msg, expected)
+		}
+	}
+}
+
+func TestFilterLimitContainers(t *testing.T) {
+	daemon := &Daemon{containers: &contStore{s: make(map[string]*Container)}}
+	for _, name := range []string{"batch-1", "batch-2", "batch-3", "web"} {
+		container := &Container{ID: name, Name: "/" + name, State: NewState()}
+		if name != "batch-3" {
+			container.State.SetRunning(1)
+		}
+		daemon.containers.Add(name, container)
+	}
+	names, err := daemon.filterLimitContainers(`{"name":["batch-"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if expected := []string{"batch-1", "batch-2"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected the running containers %v, got %v", expected, names)
+	}
+	for _, param := range []string{`{}`, `{"label":["tier=batch"]}`, `{"state":["running"]}`, `not json`} {
+		if _, err := daemon.filterLimitContainers(param); err == nil || !strings.Contains(err.Error(), "Bad parameter") {
+			t.Fatalf("Expected the filters %s to be refused, got %v", param, err)
+		}
+	}
+}
+
+func TestLimitContainers(t *testing.T) {
+	var (
+		daemon = &Daemon{}
+		eng    = engine.New()
+	)
+	if err := eng.Register("limit", func(job *engine.Job) engine.Status {
+		if len(job.Args) > 1 {
+			return daemon.limitContainers(job, job.Args)
+		}
+		if job.Getenv("RequestToken") != "" {
+			t.Fatal("Expected the request token to be left to the job of all the containers")
+		}
+		if job.Args[0] == "db" {
+			return job.Errorf("Container db is not running")
+		}
+		job.Printf("{\"cpu.shares\":\"%s\"}\n", job.Getenv("cpuShares"))
+		return engine.StatusOK
+	}); err != nil {
+		t.Fatal(err)
+	}
+	job := eng.Job("limit", "web", "db", "worker")
+	job.Setenv("cpuShares", "512")
+	job.Setenv("RequestToken", "abc")
+	out := bytes.NewBuffer(nil)
+	job.Stdout.Add(out)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	results := engine.NewTable("", 0)
+	if _, err := results.ReadListFrom(out.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Data) != 3 {
+		t.Fatalf("Expected the outcome of 3 containers, got %d", len(results.Data))
+	}
+	for i, expected := range []struct{ name, err, shares string }{
+		{"web", "", "512"},
+		{"db", "Container db is not running", ""},
+		{"worker", "", "512"},
+	} {
+		result := results.Data[i]
+		if result.Get("Name") != expected.name || result.Get("Error") != expected.err {
+			t.Fatalf("Expected %s to fail with %q, got %s with %q", expected.name, expected.err, result.Get("Name"), result.Get("Error"))
+		}
+		if expected.shares == "" {
+			continue
+		}
+		if shares := result.GetSubEnv("Output").Get("cpu.shares"); shares != expected.shares {
+			t.Fatalf("Expected the cpu shares %s of %s, got %s", expected.shares, expected.name, shares)
+		}
+	}
+}
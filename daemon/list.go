@@ -21,15 +21,20 @@ func (daemon *Daemon) Containers(job *engine.Job) engine.Status {
 	var (
 		foundBefore bool
 		displayed   int
+		skipped     int
 		all         = job.GetenvBool("all")
 		since       = job.Getenv("since")
 		before      = job.Getenv("before")
 		n           = job.GetenvInt("limit")
+		offset      = job.GetenvInt("offset")
 		size        = job.GetenvBool("size")
 		psFilters   filters.Args
 		filt_exited []int
 	)
 	outs := engine.NewTable("Created", 0)
+	if offset < 0 {
+		return job.Errorf("Bad parameter, invalid offset %d", offset)
+	}
 
 	psFilters, err := filters.FromParam(job.Getenv("filters"))
 	if err != nil {
@@ -99,6 +104,12 @@ func (daemon *Daemon) Containers(job *engine.Job) engine.Status {
 				return nil
 			}
 		}
+		// the containers are listed from the newest, a page of the listing
+		// starts offset containers after the first one
+		if skipped < offset {
+			skipped++
+			return nil
+		}
 		displayed++
 		out := &engine.Env{}
 		out.Set("Id", container.ID)
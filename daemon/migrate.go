@@ -0,0 +1,364 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/log"
+)
+
+// stateVersionFile is the file of the root of the daemon storing the version
+// of the state found under it.
+const stateVersionFile = "state-version"
+
+// stateMigration upgrades the state written by the previous version of the
+// daemon. migrate returns what it changed, or only what it would change when
+// dryRun is set. It is run again when the daemon stops in the middle of it,
+// and leaves alone the state already migrated.
+type stateMigration struct {
+	description string
+	migrate     func(root string, dryRun bool) ([]string, error)
+}
+
+// stateMigrations are the migrations of the state in order, the state is of
+// version N once the N first ones are done. New migrations are appended, the
+// ones released are never changed nor reordered.
+var stateMigrations = []stateMigration{
+	{"move the port specs of the container configs to their host configs", migrateContainerPortSpecs},
+	{"convert the deprecated port mappings of the network settings of the containers", migrateNetworkPortMappings},
+	{"store the size of the images given in their json in their layersize", migrateImageSizes},
+}
+
+// readStateVersion returns the version of the state under root, and whether
+// it was recorded. The state of the daemons older than the versions is of
+// version 0, and an empty root is of the current version.
+func readStateVersion(root string) (int, bool, error) {
+	data, err := ioutil.ReadFile(path.Join(root, stateVersionFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return 0, false, err
+		}
+		for _, dir := range []string{"containers", "graph"} {
+			entries, err := ioutil.ReadDir(path.Join(root, dir))
+			if err != nil && !os.IsNotExist(err) {
+				return 0, false, err
+			}
+			if len(entries) > 0 {
+				return 0, false, nil
+			}
+		}
+		return len(stateMigrations), false, nil
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || version < 0 {
+		return 0, false, fmt.Errorf("Invalid state version %q in %s", data, path.Join(root, stateVersionFile))
+	}
+	return version, true, nil
+}
+
+func writeStateVersion(root string, version int) error {
+	return writeStateFile(path.Join(root, stateVersionFile), []byte(strconv.Itoa(version)+"\n"))
+}
+
+// writeStateFile replaces the file p with data, for a daemon stopping midway
+// to find either the old file or the new one.
+func writeStateFile(p string, data []byte) error {
+	tmp := p + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// runStateMigrations brings the state under root to the current version,
+// calling report after each migration. The state written by a newer daemon
+// is refused, as this one would drop what it doesn't know of.
+func runStateMigrations(root string, dryRun bool, report func(version int, description string, changes []string)) error {
+	version, recorded, err := readStateVersion(root)
+	if err != nil {
+		return err
+	}
+	if version > len(stateMigrations) {
+		return fmt.Errorf("The state in %s is of version %d, written by a newer daemon: this one only knows up to version %d", root, version, len(stateMigrations))
+	}
+	for ; version < len(stateMigrations); version++ {
+		m := stateMigrations[version]
+		changes, err := m.migrate(root, dryRun)
+		if err != nil {
+			return fmt.Errorf("Error migrating the state to version %d, %s: %s", version+1, m.description, err)
+		}
+		report(version+1, m.description, changes)
+		if dryRun {
+			continue
+		}
+		if err := writeStateVersion(root, version+1); err != nil {
+			return err
+		}
+		recorded = true
+	}
+	if !recorded && !dryRun {
+		return writeStateVersion(root, version)
+	}
+	return nil
+}
+
+// migrateState runs the migrations of the state under root at startup.
+func migrateState(root string) error {
+	return runStateMigrations(root, false, func(version int, description string, changes []string) {
+		log.Infof("Migrated the state to version %d, %s: %d changes", version, description, len(changes))
+		for _, change := range changes {
+			log.Debugf("Migrated %s", change)
+		}
+	})
+}
+
+// CheckStateMigrations writes the migrations the daemon would run on the
+// state under root, and what they would change, without running them.
+func CheckStateMigrations(root string, out io.Writer) error {
+	pending := 0
+	err := runStateMigrations(root, true, func(version int, description string, changes []string) {
+		pending++
+		fmt.Fprintf(out, "Version %d: %s, %d changes\n", version, description, len(changes))
+		for _, change := range changes {
+			fmt.Fprintf(out, "  %s\n", change)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if pending == 0 {
+		fmt.Fprintf(out, "The state in %s is up to date\n", root)
+	}
+	return nil
+}
+
+// rawObject is a JSON object decoded only as far as a migration needs, for
+// the fields it doesn't know of to be written back as they were.
+type rawObject map[string]*json.RawMessage
+
+func (o rawObject) get(key string, v interface{}) error {
+	raw, exists := o[key]
+	if !exists || raw == nil {
+		return nil
+	}
+	return json.Unmarshal(*raw, v)
+}
+
+func (o rawObject) set(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(data)
+	o[key] = &raw
+	return nil
+}
+
+func readRawObject(p string) (rawObject, error) {
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	o := rawObject{}
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("Error decoding %s: %s", p, err)
+	}
+	return o, nil
+}
+
+func writeRawObject(p string, o rawObject) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return writeStateFile(p, data)
+}
+
+// migrateContainers applies migrate to the config and the host config of
+// each container, writing them back when it changed them. The containers the
+// daemon can't load are left alone, it skips them when restoring.
+func migrateContainers(root string, dryRun bool, migrate func(config, hostConfig rawObject) (bool, error)) ([]string, error) {
+	dir := path.Join(root, "containers")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var changes []string
+	for _, entry := range entries {
+		var (
+			configPath     = path.Join(dir, entry.Name(), "config.json")
+			hostConfigPath = path.Join(dir, entry.Name(), "hostconfig.json")
+		)
+		config, err := readRawObject(configPath)
+		if err != nil {
+			log.Errorf("Not migrating the container %s: %s", entry.Name(), err)
+			continue
+		}
+		hostConfig, err := readRawObject(hostConfigPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Errorf("Not migrating the container %s: %s", entry.Name(), err)
+				continue
+			}
+			hostConfig = rawObject{}
+		}
+		changed, err := migrate(config, hostConfig)
+		if err != nil {
+			return nil, fmt.Errorf("container %s: %s", entry.Name(), err)
+		}
+		if !changed {
+			continue
+		}
+		changes = append(changes, "container "+entry.Name())
+		if dryRun {
+			continue
+		}
+		// the host config first: the port specs are gone from the config once
+		// it is written
+		if err := writeRawObject(hostConfigPath, hostConfig); err != nil {
+			return nil, err
+		}
+		if err := writeRawObject(configPath, config); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// migrateContainerPortSpecs moves the PortSpecs of the configs of the
+// containers created before 0.6.5 to their ExposedPorts and to the
+// PortBindings of their host configs, as migratePortMappings does when they
+// start.
+func migrateContainerPortSpecs(root string, dryRun bool) ([]string, error) {
+	return migrateContainers(root, dryRun, func(container, hostConfig rawObject) (bool, error) {
+		config := rawObject{}
+		if err := container.get("Config", &config); err != nil {
+			return false, err
+		}
+		var portSpecs []string
+		if err := config.get("PortSpecs", &portSpecs); err != nil {
+			return false, err
+		}
+		if len(portSpecs) == 0 {
+			return false, nil
+		}
+		ports, bindings, err := nat.ParsePortSpecs(portSpecs)
+		if err != nil {
+			return false, err
+		}
+		exposedPorts := make(nat.PortSet)
+		if err := config.get("ExposedPorts", &exposedPorts); err != nil {
+			return false, err
+		}
+		for port := range ports {
+			exposedPorts[port] = struct{}{}
+		}
+		if err := config.set("ExposedPorts", exposedPorts); err != nil {
+			return false, err
+		}
+		if err := config.set("PortSpecs", nil); err != nil {
+			return false, err
+		}
+		if len(bindings) > 0 {
+			if err := hostConfig.set("PortBindings", bindings); err != nil {
+				return false, err
+			}
+		}
+		return true, container.set("Config", config)
+	})
+}
+
+// migrateNetworkPortMappings converts the PortMapping of the network
+// settings of the containers created before 0.6.5, by protocol then private
+// port, to their Ports. The ports of a running container are allocated again
+// when it starts, the ones of a stopped container are shown by inspect.
+func migrateNetworkPortMappings(root string, dryRun bool) ([]string, error) {
+	return migrateContainers(root, dryRun, func(container, hostConfig rawObject) (bool, error) {
+		settings := rawObject{}
+		if err := container.get("NetworkSettings", &settings); err != nil {
+			return false, err
+		}
+		raw, exists := settings["PortMapping"]
+		if !exists || raw == nil {
+			return false, nil
+		}
+		mapping := make(map[string]map[string]string)
+		if err := json.Unmarshal(*raw, &mapping); err != nil {
+			// the first port mappings were of tcp only, by private port
+			tcp := make(map[string]string)
+			if err := json.Unmarshal(*raw, &tcp); err != nil {
+				return false, err
+			}
+			mapping = map[string]map[string]string{"tcp": tcp}
+		}
+		ports := make(nat.PortMap)
+		if err := settings.get("Ports", &ports); err != nil {
+			return false, err
+		}
+		for proto, bindings := range mapping {
+			for private, public := range bindings {
+				port := nat.NewPort(strings.ToLower(proto), private)
+				if _, exists := ports[port]; !exists {
+					ports[port] = []nat.PortBinding{{HostPort: public}}
+				}
+			}
+		}
+		if err := settings.set("Ports", ports); err != nil {
+			return false, err
+		}
+		delete(settings, "PortMapping")
+		return true, container.set("NetworkSettings", settings)
+	})
+}
+
+// migrateImageSizes stores in the layersize of the images pulled or built
+// before 0.7 the size given in their json, instead of computing it again
+// from the layer the first time the image is looked up.
+func migrateImageSizes(root string, dryRun bool) ([]string, error) {
+	dir := path.Join(root, "graph")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var changes []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), "_") {
+			continue
+		}
+		sizePath := path.Join(dir, entry.Name(), "layersize")
+		if _, err := os.Stat(sizePath); err == nil || !os.IsNotExist(err) {
+			continue
+		}
+		img, err := readRawObject(path.Join(dir, entry.Name(), "json"))
+		if err != nil {
+			log.Errorf("Not migrating the image %s: %s", entry.Name(), err)
+			continue
+		}
+		var size int64
+		if err := img.get("Size", &size); err != nil || size <= 0 {
+			// a size of 0 is the default of the old images, not their size
+			continue
+		}
+		changes = append(changes, "image "+entry.Name())
+		if dryRun {
+			continue
+		}
+		if err := writeStateFile(sizePath, []byte(strconv.FormatInt(size, 10))); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestStateMigrations(t *testing.T) {
+	root, err := ioutil.TempDir("", "state_migrations_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// the state of a daemon older than the versions
+	var (
+		containerDir = filepath.Join(root, "containers", "abc")
+		imageDir     = filepath.Join(root, "graph", "img")
+	)
+	for _, dir := range []string{containerDir, imageDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for p, data := range map[string]string{
+		filepath.Join(containerDir, "config.json"):     `{"ID":"abc","Config":{"Image":"busybox","PortSpecs":["80:8080"]},"NetworkSettings":{"IPAddress":"172.17.0.2","PortMapping":{"Tcp":{"443":"49153"}}},"Unknown":"kept"}`,
+		filepath.Join(containerDir, "hostconfig.json"): `{"Privileged":true}`,
+		filepath.Join(imageDir, "json"):                `{"id":"img","Size":42}`,
+	} {
+		if err := ioutil.WriteFile(p, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if err := CheckStateMigrations(root, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Version 1:", "Version 2:", "Version 3:", "  container abc\n", "  image img\n"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("Expected %q in the dry run, got %s", expected, out.String())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, stateVersionFile)); !os.IsNotExist(err) {
+		t.Fatal("Expected the dry run not to record the version")
+	}
+	if _, err := os.Stat(filepath.Join(imageDir, "layersize")); !os.IsNotExist(err) {
+		t.Fatal("Expected the dry run not to change the state")
+	}
+
+	if err := migrateState(root); err != nil {
+		t.Fatal(err)
+	}
+	if version, recorded, err := readStateVersion(root); err != nil || !recorded || version != len(stateMigrations) {
+		t.Fatalf("Expected the state to be of version %d, got %d (%v, %v)", len(stateMigrations), version, recorded, err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(containerDir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := &Container{}
+	if err := json.Unmarshal(data, container); err != nil {
+		t.Fatal(err)
+	}
+	if container.Config.PortSpecs != nil {
+		t.Fatalf("Expected the port specs to be moved, got %v", container.Config.PortSpecs)
+	}
+	if _, exists := container.Config.ExposedPorts["8080/tcp"]; !exists {
+		t.Fatalf("Expected 8080/tcp to be exposed, got %v", container.Config.ExposedPorts)
+	}
+	if container.NetworkSettings.PortMapping != nil || container.NetworkSettings.IPAddress != "172.17.0.2" {
+		t.Fatalf("Unexpected network settings %+v", container.NetworkSettings)
+	}
+	if bindings := container.NetworkSettings.Ports["443/tcp"]; len(bindings) != 1 || bindings[0].HostPort != "49153" {
+		t.Fatalf("Expected 443/tcp to be mapped to 49153, got %v", container.NetworkSettings.Ports)
+	}
+	if !strings.Contains(string(data), `"Unknown":"kept"`) {
+		t.Fatalf("Expected the unknown fields to be kept, got %s", data)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(containerDir, "hostconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostConfig := &runconfig.HostConfig{}
+	if err := json.Unmarshal(data, hostConfig); err != nil {
+		t.Fatal(err)
+	}
+	if bindings := hostConfig.PortBindings["8080/tcp"]; !hostConfig.Privileged || len(bindings) != 1 || bindings[0].HostPort != "80" {
+		t.Fatalf("Unexpected host config %s", data)
+	}
+
+	if data, err := ioutil.ReadFile(filepath.Join(imageDir, "layersize")); err != nil || string(data) != "42" {
+		t.Fatalf("Expected the size of the image to be stored, got %q (%v)", data, err)
+	}
+
+	// nothing is left to migrate
+	out.Reset()
+	if err := CheckStateMigrations(root, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "up to date") {
+		t.Fatalf("Expected the state to be up to date, got %s", out.String())
+	}
+
+	if err := writeStateVersion(root, len(stateMigrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateState(root); err == nil {
+		t.Fatal("Expected the state of a newer daemon to be refused")
+	}
+}
+
+func TestStateMigrationsEmptyRoot(t *testing.T) {
+	root, err := ioutil.TempDir("", "state_migrations_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := migrateState(root); err != nil {
+		t.Fatal(err)
+	}
+	if version, recorded, err := readStateVersion(root); err != nil || !recorded || version != len(stateMigrations) {
+		t.Fatalf("Expected a new root to be of the current version, got %d (%v, %v)", version, recorded, err)
+	}
+}
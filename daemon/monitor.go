@@ -127,6 +127,18 @@ func (m *containerMonitor) Start() error {
 			return err
 		}
 
+		if err := m.prestart(); err != nil {
+			// a failing prestart hook or host service aborts the start of the
+			// container, on a restart the container is left stopped
+			if m.container.RestartCount != 0 {
+				log.Errorf("%s", err)
+				m.container.State.SetStopped(exitStatus)
+			}
+			m.resetContainer()
+
+			return err
+		}
+
 		pipes := execdriver.NewPipes(m.container.stdin, m.container.stdout, m.container.stderr, m.container.Config.OpenStdin)
 
 		m.container.LogEvent("start")
@@ -137,6 +149,8 @@ func (m *containerMonitor) Start() error {
 			// if we receive an internal error from the initial start of a container then lets
 			// return it instead of entering the restart loop
 			if m.container.RestartCount == 0 {
+				m.stopHostServices(exitStatus)
+
 				m.resetContainer()
 
 				return err
@@ -148,6 +162,11 @@ func (m *containerMonitor) Start() error {
 		// here container.Lock is already lost
 		underLock = false
 
+		m.container.stopWatchdog()
+
+		m.container.daemon.lifecycle.recordExit(m.container, exitStatus)
+		m.container.daemon.stats.forget(m.container.ID)
+
 		m.resetMonitor(err == nil && exitStatus == 0)
 
 		if m.shouldRestart(exitStatus) {
@@ -157,6 +176,8 @@ func (m *containerMonitor) Start() error {
 
 			m.resetContainer()
 
+			m.poststop(exitStatus)
+
 			// sleep with a small time increment between each restart to help avoid issues cased by quickly
 			// restarting the container because of some types of errors ( networking cut out, etc... )
 			m.waitForNextRestart()
@@ -178,6 +199,8 @@ func (m *containerMonitor) Start() error {
 
 		m.resetContainer()
 
+		m.poststop(exitStatus)
+
 		break
 	}
 
@@ -267,6 +290,48 @@ func (m *containerMonitor) callback(command *execdriver.Command) {
 	if err := m.container.ToDisk(); err != nil {
 		log.Debugf("%s", err)
 	}
+
+	m.container.startWatchdog()
+
+	m.container.daemon.lifecycle.recordStart(m.container, m.container.RestartCount > 0)
+	m.container.daemon.watchOOM(m.container)
+	m.container.daemon.watchMemoryUsage(m.container)
+	m.container.daemon.watchMemoryPressure(m.container)
+
+	// the poststart hooks run once the start was signaled, a failure does
+	// not stop the container
+	if err := m.container.runHooks(hookPoststart, 0); err != nil {
+		log.Errorf("%s", err)
+	}
+}
+
+// prestart runs the prestart hooks of the container, then starts its host
+// services
+func (m *containerMonitor) prestart() error {
+	// checked again at each start, the daemon may have restarted without
+	// --host-commands since the container was given its host config
+	if err := m.container.daemon.checkHostCommands(m.container.hostConfig); err != nil {
+		return err
+	}
+	if err := m.container.runHooks(hookPrestart, 0); err != nil {
+		return err
+	}
+	return m.container.startHostServices()
+}
+
+// poststop stops the host services of the container after its process exited
+// with exitStatus, then runs its poststop hooks, a failure is only logged
+func (m *containerMonitor) poststop(exitStatus int) {
+	m.stopHostServices(exitStatus)
+	if err := m.container.runHooks(hookPoststop, exitStatus); err != nil {
+		log.Errorf("%s", err)
+	}
+}
+
+func (m *containerMonitor) stopHostServices(exitStatus int) {
+	if m.container.hostConfig != nil {
+		m.container.stopHostServices(m.container.hostConfig.HostServices, exitStatus)
+	}
 }
 
 // resetContainer resets the container's IO and ensures that the command is able to be executed again
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// notifySocketPath is where the notify socket is mounted in the containers
+// started with --notify, it is given to them in NOTIFY_SOCKET.
+const notifySocketPath = "/.dockernotify"
+
+// linkReadyTimeout is how long a container waits for the linked containers
+// using a notify socket to be ready before it fails to start.
+const linkReadyTimeout = 60 * time.Second
+
+// notifySocketHostPath returns the path of the notify socket of the
+// container on the host.
+func (container *Container) notifySocketHostPath() string {
+	return filepath.Join(container.root, "notify.sock")
+}
+
+// listenNotify creates the notify socket of the container and starts
+// reading the sd_notify style messages the container sends on it. The
+// socket is kept across the restarts of the container.
+func (container *Container) listenNotify() error {
+	if container.notifyConn != nil {
+		return nil
+	}
+	path := container.notifySocketHostPath()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	// the processes of the container may not run as root
+	if err := os.Chmod(path, 0666); err != nil {
+		conn.Close()
+		return err
+	}
+	container.notifyConn = conn
+	go container.readNotify(conn)
+	return nil
+}
+
+func (container *Container) readNotify(conn *net.UnixConn) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			// the socket was closed by closeNotify
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			switch {
+			case line == "READY=1":
+				if !container.State.IsReady() {
+					container.State.SetReady()
+					container.LogEvent("ready")
+				}
+			case line == "WATCHDOG=1":
+				container.pingWatchdog()
+			case strings.HasPrefix(line, "STATUS="):
+				log.Debugf("%s: %s", container.ID, strings.TrimPrefix(line, "STATUS="))
+			}
+		}
+	}
+}
+
+// closeNotify closes and removes the notify socket of the container.
+func (container *Container) closeNotify() {
+	if container.notifyConn == nil {
+		return
+	}
+	container.notifyConn.Close()
+	container.notifyConn = nil
+	if err := os.Remove(container.notifySocketHostPath()); err != nil && !os.IsNotExist(err) {
+		log.Errorf("%s: Error removing notify socket: %s", container.ID, err)
+	}
+}
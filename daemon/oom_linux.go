@@ -0,0 +1,20 @@
+// +build linux
+
+package daemon
+
+import (
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/cgroups/fs"
+)
+
+func notifyOnOOM(id, parent string) (<-chan struct{}, error) {
+	return fs.NotifyOnOOM(&cgroups.Cgroup{Name: id, Parent: parent})
+}
+
+func notifyOnMemoryThreshold(id, parent string, threshold int64) (<-chan struct{}, error) {
+	return fs.NotifyOnMemoryThreshold(&cgroups.Cgroup{Name: id, Parent: parent}, threshold)
+}
+
+func notifyOnMemoryPressure(id, parent, level string) (<-chan struct{}, error) {
+	return fs.NotifyOnMemoryPressure(&cgroups.Cgroup{Name: id, Parent: parent}, level)
+}
@@ -0,0 +1,17 @@
+// +build !linux
+
+package daemon
+
+import "fmt"
+
+func notifyOnOOM(id, parent string) (<-chan struct{}, error) {
+	return nil, fmt.Errorf("Watching the OOM kills is only supported on linux")
+}
+
+func notifyOnMemoryThreshold(id, parent string, threshold int64) (<-chan struct{}, error) {
+	return nil, fmt.Errorf("Watching the memory usage is only supported on linux")
+}
+
+func notifyOnMemoryPressure(id, parent, level string) (<-chan struct{}, error) {
+	return nil, fmt.Errorf("Watching the memory pressure is only supported on linux")
+}
@@ -0,0 +1,316 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cpuSet is a set of cpus or of memory nodes, in the form of the files of
+// the cpuset cgroup: 0-3,6.
+type cpuSet map[int]bool
+
+func parseCpuSet(s string) (cpuSet, error) {
+	set := make(cpuSet)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return set, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("Invalid cpuset %q", s)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("Invalid cpuset %q", s)
+			}
+		}
+		for i := first; i <= last; i++ {
+			set[i] = true
+		}
+	}
+	return set, nil
+}
+
+// String returns the set with its consecutive members as ranges.
+func (s cpuSet) String() string {
+	members := make([]int, 0, len(s))
+	for i := range s {
+		members = append(members, i)
+	}
+	sort.Ints(members)
+	var parts []string
+	for i := 0; i < len(members); {
+		j := i
+		for j+1 < len(members) && members[j+1] == members[j]+1 {
+			j++
+		}
+		if j == i {
+			parts = append(parts, strconv.Itoa(members[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", members[i], members[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s cpuSet) intersect(o cpuSet) cpuSet {
+	set := make(cpuSet)
+	for i := range s {
+		if o[i] {
+			set[i] = true
+		}
+	}
+	return set
+}
+
+func (s cpuSet) minus(o cpuSet) cpuSet {
+	set := make(cpuSet)
+	for i := range s {
+		if !o[i] {
+			set[i] = true
+		}
+	}
+	return set
+}
+
+// contains reports whether o is a subset of s.
+func (s cpuSet) contains(o cpuSet) bool {
+	return len(o.minus(s)) == 0
+}
+
+// numaTopology are the cpus of the NUMA nodes of the host, by node.
+type numaTopology map[int]cpuSet
+
+// readNumaTopology reads the NUMA nodes of the host from the sysfs mounted
+// at sysfs. A host without NUMA is a single node with all the cpus online.
+func readNumaTopology(sysfs string) (numaTopology, error) {
+	dirs, err := filepath.Glob(filepath.Join(sysfs, "devices/system/node/node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	topology := make(numaTopology)
+	for _, dir := range dirs {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		if topology[node], err = parseCpuSet(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	if len(topology) == 0 {
+		data, err := ioutil.ReadFile(filepath.Join(sysfs, "devices/system/cpu/online"))
+		if err != nil {
+			return nil, err
+		}
+		if topology[0], err = parseCpuSet(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	return topology, nil
+}
+
+// cpus returns the cpus of nodes, of all the nodes when nodes is nil.
+func (t numaTopology) cpus(nodes cpuSet) cpuSet {
+	set := make(cpuSet)
+	for node, cpus := range t {
+		if nodes == nil || nodes[node] {
+			for i := range cpus {
+				set[i] = true
+			}
+		}
+	}
+	return set
+}
+
+// nodes returns the nodes having any of cpus, all the nodes when cpus is
+// nil.
+func (t numaTopology) nodes(cpus cpuSet) cpuSet {
+	set := make(cpuSet)
+	for node, nodeCpus := range t {
+		if cpus == nil || len(nodeCpus.intersect(cpus)) > 0 {
+			set[node] = true
+		}
+	}
+	return set
+}
+
+// placementTarget is a running container the placement of another one is
+// constrained by, with the cpus and memory nodes it runs on.
+type placementTarget struct {
+	name       string
+	cpus, mems cpuSet
+}
+
+// placeCpuset returns the cpuset and the cpuset of memory nodes of a
+// container on the NUMA nodes of the affinity targets and on none of the
+// cpus of the anti-affinity targets. What the container was given is only
+// checked, what it wasn't is computed, "" being left for all of them. The
+// violations are all returned at once.
+func placeCpuset(cpuset, cpusetMems string, topology numaTopology, affinity, antiAffinity []placementTarget) (string, string, error) {
+	var (
+		cpus       = topology.cpus(nil)
+		mems       = topology.nodes(nil)
+		violations []string
+		err        error
+	)
+	if cpuset != "" {
+		if cpus, err = parseCpuSet(cpuset); err != nil {
+			return "", "", err
+		}
+	}
+	if cpusetMems != "" {
+		if mems, err = parseCpuSet(cpusetMems); err != nil {
+			return "", "", err
+		}
+	}
+
+	for _, target := range affinity {
+		nodeCpus := topology.cpus(target.mems)
+		if cpuset == "" {
+			cpus = cpus.intersect(nodeCpus)
+		} else if !nodeCpus.contains(cpus) {
+			violations = append(violations, fmt.Sprintf("the cpus %s are not on the NUMA nodes %s of %s", cpus.minus(nodeCpus), target.mems, target.name))
+		}
+		if cpusetMems == "" {
+			mems = mems.intersect(target.mems)
+		} else if !target.mems.contains(mems) {
+			violations = append(violations, fmt.Sprintf("the memory nodes %s are not NUMA nodes of %s", mems.minus(target.mems), target.name))
+		}
+	}
+	for _, target := range antiAffinity {
+		if cpuset == "" {
+			cpus = cpus.minus(target.cpus)
+		} else if shared := cpus.intersect(target.cpus); len(shared) > 0 {
+			violations = append(violations, fmt.Sprintf("the cpus %s are shared with %s", shared, target.name))
+		}
+	}
+	if len(violations) == 0 && len(cpus) == 0 {
+		violations = append(violations, "no cpu is left")
+	}
+	if len(violations) == 0 && len(mems) == 0 {
+		violations = append(violations, "no memory node is left")
+	}
+	if len(violations) > 0 {
+		return "", "", fmt.Errorf("%s", strings.Join(violations, ", "))
+	}
+
+	if cpuset == "" && len(cpus) < len(topology.cpus(nil)) {
+		cpuset = cpus.String()
+	}
+	if cpusetMems == "" && len(mems) < len(topology) {
+		cpusetMems = mems.String()
+	}
+	return cpuset, cpusetMems, nil
+}
+
+// placementTarget returns the cpus and memory nodes container runs on.
+func (container *Container) placementTarget(topology numaTopology) (placementTarget, error) {
+	target := placementTarget{name: strings.TrimPrefix(container.Name, "/")}
+	cpuset, cpusetMems := container.Config.Cpuset, container.hostConfig.CpusetMems
+	if cpuset == "" {
+		cpuset = container.PlacedCpuset
+	}
+	if cpusetMems == "" {
+		cpusetMems = container.PlacedCpusetMems
+	}
+
+	var err error
+	target.cpus = topology.cpus(nil)
+	if cpuset != "" {
+		if target.cpus, err = parseCpuSet(cpuset); err != nil {
+			return target, err
+		}
+	}
+	target.mems = topology.nodes(target.cpus)
+	if cpusetMems != "" {
+		if target.mems, err = parseCpuSet(cpusetMems); err != nil {
+			return target, err
+		}
+	}
+	return target, nil
+}
+
+// place computes the cpuset of container from its affinities to the running
+// containers, and from the anti-affinities of the running containers to it,
+// before it starts. The cpusets it was given are checked instead, and the
+// start is refused when they violate them.
+func (daemon *Daemon) place(container *Container) error {
+	container.PlacedCpuset, container.PlacedCpusetMems = "", ""
+
+	var affinity, antiAffinity []*Container
+	for _, name := range container.hostConfig.Affinity {
+		target := daemon.Get(name)
+		if target == nil || !target.State.IsRunning() {
+			return fmt.Errorf("Conflict, cannot place %s on the NUMA nodes of %s: it is not running", container.ID, name)
+		}
+		affinity = append(affinity, target)
+	}
+	for _, name := range container.hostConfig.AntiAffinity {
+		if target := daemon.Get(name); target != nil && target.State.IsRunning() {
+			antiAffinity = append(antiAffinity, target)
+		}
+	}
+	// the anti-affinities are kept both ways, a container started after the
+	// ones keeping their cpus from it stays off them
+	for _, c := range daemon.List() {
+		if c.ID == container.ID || !c.State.IsRunning() || c.hostConfig == nil {
+			continue
+		}
+		for _, name := range c.hostConfig.AntiAffinity {
+			if target := daemon.Get(name); target != nil && target.ID == container.ID {
+				antiAffinity = append(antiAffinity, c)
+				break
+			}
+		}
+	}
+	if len(affinity) == 0 && len(antiAffinity) == 0 {
+		return nil
+	}
+
+	topology, err := readNumaTopology("/sys")
+	if err != nil {
+		return fmt.Errorf("Cannot read the NUMA nodes of the host: %s", err)
+	}
+	targets := func(containers []*Container) ([]placementTarget, error) {
+		all := make([]placementTarget, len(containers))
+		for i, c := range containers {
+			if all[i], err = c.placementTarget(topology); err != nil {
+				return nil, err
+			}
+		}
+		return all, nil
+	}
+	affinityTargets, err := targets(affinity)
+	if err != nil {
+		return err
+	}
+	antiAffinityTargets, err := targets(antiAffinity)
+	if err != nil {
+		return err
+	}
+
+	cpuset, cpusetMems, err := placeCpuset(container.Config.Cpuset, container.hostConfig.CpusetMems, topology, affinityTargets, antiAffinityTargets)
+	if err != nil {
+		return fmt.Errorf("Conflict, cannot place %s: %s", container.ID, err)
+	}
+	if container.Config.Cpuset == "" {
+		container.PlacedCpuset = cpuset
+	}
+	if container.hostConfig.CpusetMems == "" {
+		container.PlacedCpusetMems = cpusetMems
+	}
+	return nil
+}
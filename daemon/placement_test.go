@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCpuSet(t *testing.T) {
+	for s, expected := range map[string]string{"": "", "3": "3", "0-3,6": "0-3,6", "5,0,1,2,7-8": "0-2,5,7-8"} {
+		set, err := parseCpuSet(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set.String() != expected {
+			t.Fatalf("Expected %q to be %q, got %q", s, expected, set)
+		}
+	}
+	for _, s := range []string{"a", "3-1", "-1", "0,,1"} {
+		if _, err := parseCpuSet(s); err == nil {
+			t.Fatalf("Expected %q to be an invalid cpuset", s)
+		}
+	}
+}
+
+func TestReadNumaTopology(t *testing.T) {
+	sysfs, err := ioutil.TempDir("", "docker-numa-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sysfs)
+	if err := os.MkdirAll(filepath.Join(sysfs, "devices/system/cpu"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sysfs, "devices/system/cpu/online"), []byte("0-3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	topology, err := readNumaTopology(sysfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topology) != 1 || topology[0].String() != "0-3" {
+		t.Fatalf("Expected a single node of the cpus online, got %v", topology)
+	}
+
+	for node, cpus := range map[string]string{"node0": "0-1\n", "node1": "2-3\n"} {
+		dir := filepath.Join(sysfs, "devices/system/node", node)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "cpulist"), []byte(cpus), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if topology, err = readNumaTopology(sysfs); err != nil {
+		t.Fatal(err)
+	}
+	if len(topology) != 2 || topology[0].String() != "0-1" || topology[1].String() != "2-3" {
+		t.Fatalf("Expected the cpus of the 2 nodes, got %v", topology)
+	}
+}
+
+func TestPlaceCpuset(t *testing.T) {
+	topology := numaTopology{0: {0: true, 1: true}, 1: {2: true, 3: true}}
+	target := func(name, cpus string) placementTarget {
+		set, _ := parseCpuSet(cpus)
+		return placementTarget{name: name, cpus: set, mems: topology.nodes(set)}
+	}
+	db, cache := target("db", "2"), target("cache", "3")
+
+	for _, c := range []struct {
+		cpuset, mems                 string
+		affinity, antiAffinity       []placementTarget
+		expectedCpuset, expectedMems string
+	}{
+		// computed
+		{"", "", nil, nil, "", ""},
+		{"", "", []placementTarget{db}, nil, "2-3", "1"},
+		{"", "", []placementTarget{db}, []placementTarget{cache}, "2", "1"},
+		{"", "", nil, []placementTarget{db, cache}, "0-1", ""},
+		// checked
+		{"3", "", []placementTarget{db}, nil, "3", "1"},
+		{"0", "0", nil, []placementTarget{db}, "0", "0"},
+	} {
+		cpuset, mems, err := placeCpuset(c.cpuset, c.mems, topology, c.affinity, c.antiAffinity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cpuset != c.expectedCpuset || mems != c.expectedMems {
+			t.Fatalf("Expected the placement %q %q of %+v, got %q %q", c.expectedCpuset, c.expectedMems, c, cpuset, mems)
+		}
+	}
+
+	for _, c := range []struct {
+		cpuset, mems           string
+		affinity, antiAffinity []placementTarget
+		violation              string
+	}{
+		{"0-2", "", []placementTarget{db}, nil, "the cpus 0-1 are not on the NUMA nodes 1 of db"},
+		{"", "0", []placementTarget{db}, nil, "the memory nodes 0 are not NUMA nodes of db"},
+		{"1-2", "", nil, []placementTarget{db}, "the cpus 2 are shared with db"},
+		{"", "", []placementTarget{db}, []placementTarget{target("batch", "2-3")}, "no cpu is left"},
+	} {
+		_, _, err := placeCpuset(c.cpuset, c.mems, topology, c.affinity, c.antiAffinity)
+		if err == nil || !strings.Contains(err.Error(), c.violation) {
+			t.Fatalf("Expected the violation %q, got %v", c.violation, err)
+		}
+	}
+}
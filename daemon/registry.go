@@ -0,0 +1,33 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// serveRegistry serves the local images read-only over the v2 registry
+// protocol on addr, for the other hosts to pull from the daemon without a
+// registry. The blobs are kept under the registry directory of the graph
+// until their image is deleted.
+func (daemon *Daemon) serveRegistry(addr string) error {
+	handler, err := daemon.repositories.RegistryHandler(path.Join(daemon.config.Root, "registry"))
+	if err != nil {
+		return err
+	}
+	daemon.registry = handler
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("Couldn't listen on --registry-addr %s: %s", addr, err)
+	}
+	log.Infof("Serving the local images over the v2 registry protocol on %s", addr)
+	go func() {
+		if err := http.Serve(l, handler); err != nil {
+			log.Errorf("Error serving the registry on %s: %s", addr, err)
+		}
+	}()
+	return nil
+}
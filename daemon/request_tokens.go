@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/engine"
+)
+
+// requestTokenTTL is how long the result of a request is kept by its token,
+// for the client to retry it.
+const requestTokenTTL = time.Hour
+
+// tokenRequest is a request run with a request token, and its output once
+// it succeeded.
+type tokenRequest struct {
+	fingerprint string
+	// done is closed once the request is over
+	done      chan struct{}
+	succeeded bool
+	stdout    []byte
+	stderr    []byte
+	expires   time.Time
+}
+
+// requestTokens are the requests run with a request token, by token.
+type requestTokens struct {
+	sync.Mutex
+	requests map[string]*tokenRequest
+}
+
+func newRequestTokens() *requestTokens {
+	return &requestTokens{requests: make(map[string]*tokenRequest)}
+}
+
+// claim returns the request of token, and whether the caller is to run it:
+// the same request was not run yet, or it failed. It waits for the request
+// while it runs, and refuses a token given to another request.
+func (t *requestTokens) claim(token, fingerprint string) (*tokenRequest, bool, error) {
+	for {
+		t.Lock()
+		now := time.Now()
+		for token, request := range t.requests {
+			if !request.expires.IsZero() && request.expires.Before(now) {
+				delete(t.requests, token)
+			}
+		}
+		request, exists := t.requests[token]
+		if !exists {
+			request = &tokenRequest{fingerprint: fingerprint, done: make(chan struct{})}
+			t.requests[token] = request
+		}
+		t.Unlock()
+
+		if !exists {
+			return request, true, nil
+		}
+		if request.fingerprint != fingerprint {
+			return nil, false, fmt.Errorf("Conflict, the request token %s was given to another request", token)
+		}
+		<-request.done
+		if request.succeeded {
+			return request, false, nil
+		}
+		// the request failed and was forgotten, it is run again
+	}
+}
+
+// finish records the output of the request of token, the requests failing
+// are forgotten for the client to retry them.
+func (t *requestTokens) finish(token string, request *tokenRequest, succeeded bool, stdout, stderr []byte) {
+	t.Lock()
+	request.succeeded = succeeded
+	request.stdout = stdout
+	request.stderr = stderr
+	request.expires = time.Now().Add(requestTokenTTL)
+	if !succeeded {
+		delete(t.requests, token)
+	}
+	t.Unlock()
+	close(request.done)
+}
+
+// requestFingerprint identifies the request of a job, by its name, its
+// arguments and its environment.
+func requestFingerprint(job *engine.Job) string {
+	env := job.Environ()
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		if key != "RequestToken" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	fingerprint := []string{job.Name, strings.Join(job.Args, "\x00")}
+	for _, key := range keys {
+		fingerprint = append(fingerprint, key+"="+env[key])
+	}
+	return strings.Join(fingerprint, "\x00")
+}
+
+// idempotent runs handler once for all the jobs given the same RequestToken,
+// for a client to retry a request after a network error without creating a
+// container or changing it twice. The jobs after the first one get its
+// output, once it succeeded, instead of running again.
+func (daemon *Daemon) idempotent(handler engine.Handler) engine.Handler {
+	return func(job *engine.Job) engine.Status {
+		token := job.Getenv("RequestToken")
+		if token == "" {
+			return handler(job)
+		}
+		request, run, err := daemon.requestTokens.claim(token, requestFingerprint(job))
+		if err != nil {
+			return job.Error(err)
+		}
+		if !run {
+			job.Stdout.Write(request.stdout)
+			job.Stderr.Write(request.stderr)
+			return engine.StatusOK
+		}
+		var stdout, stderr bytes.Buffer
+		job.Stdout.Add(&stdout)
+		job.Stderr.Add(&stderr)
+		status := handler(job)
+		daemon.requestTokens.finish(token, request, status == engine.StatusOK, stdout.Bytes(), stderr.Bytes())
+		return status
+	}
+}
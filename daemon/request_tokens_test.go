@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/engine"
+)
+
+func TestIdempotentRequests(t *testing.T) {
+	var (
+		daemon = &Daemon{requestTokens: newRequestTokens()}
+		eng    = engine.New()
+		runs   = 0
+	)
+	if err := eng.Register("count", daemon.idempotent(func(job *engine.Job) engine.Status {
+		runs++
+		if job.GetenvBool("fail") {
+			return job.Errorf("failed")
+		}
+		job.Printf("%d\n", runs)
+		return engine.StatusOK
+	})); err != nil {
+		t.Fatal(err)
+	}
+	run := func(token string, fail bool, args ...string) (string, error) {
+		job := eng.Job("count", args...)
+		job.Setenv("RequestToken", token)
+		job.SetenvBool("fail", fail)
+		out := bytes.NewBuffer(nil)
+		job.Stdout.Add(out)
+		err := job.Run()
+		return strings.TrimSpace(out.String()), err
+	}
+
+	for i := 0; i < 2; i++ {
+		if out, err := run("abc", false, "web"); err != nil || out != "1" {
+			t.Fatalf("Expected the output of the first request, got %q (%v)", out, err)
+		}
+	}
+	if runs != 1 {
+		t.Fatalf("Expected the request to run once, ran %d times", runs)
+	}
+	if _, err := run("abc", false, "db"); err == nil || !strings.Contains(err.Error(), "Conflict") {
+		t.Fatalf("Expected the token of another request to be refused, got %v", err)
+	}
+
+	// the failed requests are run again
+	if _, err := run("def", true, "web"); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if out, err := run("def", false, "web"); err != nil || out != "3" {
+		t.Fatalf("Expected the retried request to run again, got %q (%v)", out, err)
+	}
+	if runs != 3 {
+		t.Fatalf("Expected the failed request to run again, ran %d times", runs)
+	}
+
+	// without a token, every request runs
+	run("", false, "web")
+	run("", false, "web")
+	if runs != 5 {
+		t.Fatalf("Expected the requests without token to run, ran %d times", runs)
+	}
+}
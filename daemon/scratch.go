@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/docker/docker/pkg/units"
+	"github.com/docker/docker/utils"
+)
+
+// scratchQuota counts the bytes an operation writes in its scratch area,
+// the directories it creates under the TMPDIR of the daemon, against the
+// --tmpdir-size of the daemon. A limit of 0 leaves it unlimited. The stages
+// of a parallel build share it.
+type scratchQuota struct {
+	sync.Mutex
+	limit int64
+	used  int64
+}
+
+func (daemon *Daemon) newScratchQuota() *scratchQuota {
+	return &scratchQuota{limit: daemon.scratchSize}
+}
+
+// add counts size more bytes, failing once the limit is exceeded.
+func (q *scratchQuota) add(size int64) error {
+	if q == nil || q.limit <= 0 {
+		return nil
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.used += size
+	if q.used > q.limit {
+		return fmt.Errorf("The scratch data exceeds the limit of %s set by --tmpdir-size", units.HumanSize(q.limit))
+	}
+	return nil
+}
+
+// addTree counts the size of the files under dir.
+func (q *scratchQuota) addTree(dir string) error {
+	if q == nil || q.limit <= 0 {
+		return nil
+	}
+	size, err := utils.TreeSize(dir)
+	if err != nil {
+		return err
+	}
+	return q.add(size)
+}
+
+// reader counts the bytes read from r, which are written in the scratch
+// area by the caller.
+func (q *scratchQuota) reader(r io.Reader) io.Reader {
+	if q == nil || q.limit <= 0 {
+		return r
+	}
+	return &scratchReader{r: r, q: q}
+}
+
+type scratchReader struct {
+	r io.Reader
+	q *scratchQuota
+}
+
+func (r *scratchReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if qerr := r.q.add(int64(n)); qerr != nil {
+		return n, qerr
+	}
+	return n, err
+}
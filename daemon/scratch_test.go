@@ -0,0 +1,22 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestScratchQuotaReader(t *testing.T) {
+	q := &scratchQuota{limit: 10}
+	if _, err := ioutil.ReadAll(q.reader(strings.NewReader("0123456789"))); err != nil {
+		t.Fatalf("Expected data at the limit to be accepted: %s", err)
+	}
+	if _, err := ioutil.ReadAll(q.reader(strings.NewReader("0"))); err == nil {
+		t.Fatal("Expected data over the limit to be rejected")
+	}
+
+	unlimited := &scratchQuota{}
+	if _, err := ioutil.ReadAll(unlimited.reader(strings.NewReader("0123456789"))); err != nil {
+		t.Fatalf("Expected no limit without a size: %s", err)
+	}
+}
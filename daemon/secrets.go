@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/pkg/mount"
+)
+
+// buildSecretsPath is where the secrets given to a build are mounted in the
+// containers of its RUN instructions.
+const buildSecretsPath = "/run/secrets"
+
+// sshAgentPath is where the SSH agent socket forwarded to a build is mounted
+// in the containers of its RUN instructions, it is given to them in
+// SSH_AUTH_SOCK.
+const sshAgentPath = "/run/ssh-agent.sock"
+
+// validateSecretName checks that name is a file name of buildSecretsPath.
+func validateSecretName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("Invalid secret name %q, it must be a file name", name)
+	}
+	return nil
+}
+
+// secretsHostPath returns the directory holding the build secrets of the
+// container on the host.
+func (container *Container) secretsHostPath() string {
+	return filepath.Join(container.root, "secrets")
+}
+
+// mountSecrets writes secrets into a tmpfs which is mounted read-only at
+// buildSecretsPath by the next start of the container, so they are never
+// written on disk nor into the layer of the container.
+func (container *Container) mountSecrets(secrets map[string][]byte) error {
+	dir := container.secretsHostPath()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := mount.Mount("tmpfs", dir, "tmpfs", "nosuid,nodev,noexec,mode=0755"); err != nil {
+		return fmt.Errorf("Cannot mount the build secrets: %s", err)
+	}
+	container.secrets = true
+
+	for name, data := range secrets {
+		if err := validateSecretName(name); err != nil {
+			container.unmountSecrets()
+			return err
+		}
+		// the user of the RUN instructions may not be root
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0444); err != nil {
+			container.unmountSecrets()
+			return err
+		}
+	}
+	return nil
+}
+
+// unmountSecrets discards the build secrets of the container.
+func (container *Container) unmountSecrets() error {
+	container.secrets = false
+	dir := container.secretsHostPath()
+	if err := mount.Unmount(dir); err != nil {
+		return err
+	}
+	return os.Remove(dir)
+}
+
+// validateSSHAgent checks that sock is the socket of an SSH agent on the
+// host of the daemon, and that rules let it be bind mounted: any socket of
+// the host, such as the one of the daemon, could be given otherwise.
+func validateSSHAgent(rules []bindRule, sock string) error {
+	if !filepath.IsAbs(sock) {
+		return fmt.Errorf("Invalid SSH agent socket %s, it must be an absolute path", sock)
+	}
+	if err := checkBindSource(rules, sock); err != nil {
+		return err
+	}
+	fi, err := os.Stat(sock)
+	if err != nil {
+		return fmt.Errorf("Cannot forward the SSH agent, the daemon must run on the host of the client: %s", err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("Invalid SSH agent socket %s, it is not a socket", sock)
+	}
+	return nil
+}
+
+// missingPaths returns p and its parent directories, from the deepest up,
+// which don't exist in rootfs. They are the ones created to mount p.
+func missingPaths(rootfs, p string) []string {
+	var paths []string
+	for p = filepath.Clean(p); p != "/" && p != "."; p = filepath.Dir(p) {
+		if _, err := os.Lstat(filepath.Join(rootfs, p)); err == nil {
+			break
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// removeMountpoints removes paths from rootfs once the container which had
+// something mounted on them has stopped, so they don't end up in its layer.
+// A directory something else was written into is kept.
+func removeMountpoints(rootfs string, paths []string) {
+	// a path sorts before the paths under it
+	sorted := append([]string{}, paths...)
+	sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+	for _, p := range sorted {
+		os.Remove(filepath.Join(rootfs, p))
+	}
+}
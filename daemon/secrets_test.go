@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateSecretName(t *testing.T) {
+	for _, valid := range []string{"npmrc", ".netrc", "id_rsa.pub"} {
+		if err := validateSecretName(valid); err != nil {
+			t.Fatalf("Expected secret name %q to be valid: %s", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", ".", "..", "a/b", "/npmrc"} {
+		if err := validateSecretName(invalid); err == nil {
+			t.Fatalf("Expected secret name %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestSecretsMountpoints(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "docker-test-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	if err := os.Mkdir(filepath.Join(rootfs, "run"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mountpoints := missingPaths(rootfs, buildSecretsPath)
+	if len(mountpoints) != 1 || mountpoints[0] != buildSecretsPath {
+		t.Fatalf("Expected only %s to be missing, got %v", buildSecretsPath, mountpoints)
+	}
+
+	// what the exec driver does to mount the secrets
+	if err := os.MkdirAll(filepath.Join(rootfs, buildSecretsPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	removeMountpoints(rootfs, mountpoints)
+	if _, err := os.Stat(filepath.Join(rootfs, buildSecretsPath)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the mountpoint to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootfs, "run")); err != nil {
+		t.Fatalf("Expected /run of the image to be kept: %s", err)
+	}
+
+	// a directory written into by the RUN instruction is kept
+	mountpoints = missingPaths(rootfs, "/data/secrets")
+	if err := os.MkdirAll(filepath.Join(rootfs, "data", "secrets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "data", "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	removeMountpoints(rootfs, mountpoints)
+	if _, err := os.Stat(filepath.Join(rootfs, "data", "file")); err != nil {
+		t.Fatalf("Expected the files of the RUN instruction to be kept: %s", err)
+	}
+}
+
+func TestValidateSSHAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-test-ssh-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := validateSSHAgent(nil, sock); err != nil {
+		t.Fatalf("Expected %s to be a valid SSH agent socket: %s", sock, err)
+	}
+	if err := validateSSHAgent([]bindRule{{path: dir, origin: "denied by the test"}}, sock); err == nil {
+		t.Fatalf("Expected %s to be refused under a denied path", sock)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []string{"agent.sock", file, filepath.Join(dir, "missing.sock")} {
+		if err := validateSSHAgent(nil, invalid); err == nil {
+			t.Fatalf("Expected %s to be an invalid SSH agent socket", invalid)
+		}
+	}
+}
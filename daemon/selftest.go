@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/image"
+)
+
+// StorageSelfTest exercises the graph driver of the daemon with throwaway
+// layers and writes the time taken by each operation, along with the known
+// issues of the driver in this setup.
+func (daemon *Daemon) StorageSelfTest(job *engine.Job) engine.Status {
+	if len(job.Args) != 0 {
+		return job.Errorf("Usage: %s", job.Name)
+	}
+	opts := graphdriver.SelfTestOptions{
+		Layers:      10,
+		Concurrency: 8,
+		Size:        64 * 1024 * 1024,
+		MaxDepth:    image.MaxImageDepth,
+	}
+	if job.Getenv("layers") != "" {
+		opts.Layers = job.GetenvInt("layers")
+	}
+	if job.Getenv("concurrency") != "" {
+		opts.Concurrency = job.GetenvInt("concurrency")
+	}
+	if job.Getenv("size") != "" {
+		opts.Size = job.GetenvInt64("size")
+	}
+	if opts.Layers+2 > image.MaxImageDepth {
+		return job.Errorf("Cannot test more than %d layers", image.MaxImageDepth-2)
+	}
+
+	report, err := graphdriver.SelfTest(daemon.driver, opts)
+	if err != nil {
+		return job.Errorf("Storage self-test of %s failed: %s", daemon.driver, err)
+	}
+
+	out := &engine.Env{}
+	out.Set("Driver", report.Driver)
+	if err := out.SetJson("Results", report.Results); err != nil {
+		return job.Error(err)
+	}
+	out.SetList("Warnings", report.Warnings)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
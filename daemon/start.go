@@ -41,12 +41,20 @@ func (daemon *Daemon) ContainerStart(job *engine.Job) engine.Status {
 }
 
 func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.HostConfig) error {
+	if err := daemon.checkHostCommands(hostConfig); err != nil {
+		return err
+	}
 	// Validate the HostConfig binds. Make sure that:
+	// the source may be bind mounted
 	// the source exists
 	for _, bind := range hostConfig.Binds {
 		splitBind := strings.Split(bind, ":")
 		source := splitBind[0]
 
+		if err := checkBindSource(daemon.bindRules(), source); err != nil {
+			return err
+		}
+
 		// ensure the source exists on the host
 		_, err := os.Stat(source)
 		if err != nil && os.IsNotExist(err) {
@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// slowStartupStep is how long a step of the startup of the daemon takes
+// before it is reported as slow.
+const slowStartupStep = 10 * time.Second
+
+// The steps of the startup --startup-defer can run in the background, the
+// daemon serving the API without waiting for them.
+const (
+	// the restart of the containers by their restart policies
+	startupRestarts = "restarts"
+	// the first collection of the exec driver state and of the expired
+	// volumes
+	startupGC = "gc"
+)
+
+// startupStep is how long a step of the startup of the daemon took, and
+// whether it ran in the background.
+type startupStep struct {
+	Name     string
+	Seconds  float64
+	Deferred bool
+}
+
+// startupProfile records the steps of the startup of the daemon, for docker
+// info and the logs to tell where a slow startup spends its time.
+type startupProfile struct {
+	sync.Mutex
+	start    time.Time
+	total    time.Duration
+	steps    []startupStep
+	deferred map[string]bool
+}
+
+// newStartupProfile returns the profile of a startup running in the
+// background the steps of deferred.
+func newStartupProfile(deferred []string) (*startupProfile, error) {
+	p := &startupProfile{start: time.Now(), deferred: make(map[string]bool)}
+	for _, step := range deferred {
+		if step != startupRestarts && step != startupGC {
+			return nil, fmt.Errorf("Invalid --startup-defer %s, must be %s or %s", step, startupRestarts, startupGC)
+		}
+		p.deferred[step] = true
+	}
+	return p, nil
+}
+
+func (p *startupProfile) record(name string, duration time.Duration, deferred bool) {
+	p.Lock()
+	p.steps = append(p.steps, startupStep{Name: name, Seconds: duration.Seconds(), Deferred: deferred})
+	p.Unlock()
+	if duration >= slowStartupStep {
+		log.Infof("Slow startup step %s took %s", name, duration)
+	} else {
+		log.Debugf("Startup step %s took %s", name, duration)
+	}
+}
+
+// time runs the step name of the startup and records how long it took.
+func (p *startupProfile) time(name string, step func() error) error {
+	start := time.Now()
+	err := step()
+	p.record(name, time.Since(start), false)
+	return err
+}
+
+// run runs the step name, in the background when it is deferred, in which
+// case its error is only logged.
+func (p *startupProfile) run(name string, step func() error) error {
+	if !p.deferred[name] {
+		return p.time(name, step)
+	}
+	go func() {
+		start := time.Now()
+		if err := step(); err != nil {
+			log.Errorf("Error in the deferred startup step %s: %s", name, err)
+		}
+		p.record(name, time.Since(start), true)
+	}()
+	return nil
+}
+
+// done records the end of the startup, the deferred steps possibly still
+// running, and logs how long each step took.
+func (p *startupProfile) done() {
+	p.Lock()
+	p.total = time.Since(p.start)
+	breakdown := make([]string, len(p.steps))
+	for i, step := range p.steps {
+		breakdown[i] = fmt.Sprintf("%s %.2fs", step.Name, step.Seconds)
+	}
+	p.Unlock()
+	log.Infof("Daemon started in %s: %s", p.total, strings.Join(breakdown, ", "))
+}
+
+// profile returns the total startup time, 0 while the daemon starts, and
+// the steps recorded so far.
+func (p *startupProfile) profile() (time.Duration, []startupStep) {
+	p.Lock()
+	defer p.Unlock()
+	return p.total, append([]startupStep{}, p.steps...)
+}
@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStartupProfile(t *testing.T) {
+	if _, err := newStartupProfile([]string{"restarts", "network"}); err == nil {
+		t.Fatal("Expected the network to be refused as a deferred step")
+	}
+	p, err := newStartupProfile([]string{"gc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.time("graph driver", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.run("restarts", func() error { return fmt.Errorf("failed") }); err == nil {
+		t.Fatal("Expected the error of the step run in the foreground")
+	}
+	done := make(chan struct{})
+	if err := p.run("gc", func() error {
+		<-done
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p.done()
+	total, steps := p.profile()
+	if total <= 0 || len(steps) != 2 {
+		t.Fatalf("Expected the startup to be over with the deferred step still running, got %s and %v", total, steps)
+	}
+
+	close(done)
+	for i := 0; i < 100; i++ {
+		if _, steps = p.profile(); len(steps) == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(steps) != 3 || steps[2].Name != "gc" || !steps[2].Deferred || steps[0].Deferred {
+		t.Fatalf("Expected the deferred step to be recorded once over, got %v", steps)
+	}
+}
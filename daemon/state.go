@@ -14,16 +14,19 @@ type State struct {
 	Running    bool
 	Paused     bool
 	Restarting bool
+	Ready      bool // the container sent READY=1 on its notify socket
 	Pid        int
 	ExitCode   int
 	StartedAt  time.Time
 	FinishedAt time.Time
 	waitChan   chan struct{}
+	readyChan  chan struct{}
 }
 
 func NewState() *State {
 	return &State{
-		waitChan: make(chan struct{}),
+		waitChan:  make(chan struct{}),
+		readyChan: make(chan struct{}),
 	}
 }
 
@@ -39,6 +42,9 @@ func (s *State) String() string {
 		if s.Restarting {
 			return fmt.Sprintf("Restarting (%d) %s ago", s.ExitCode, units.HumanDuration(time.Now().UTC().Sub(s.FinishedAt)))
 		}
+		if s.Ready {
+			return fmt.Sprintf("Up %s (Ready)", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)))
+		}
 
 		return fmt.Sprintf("Up %s", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)))
 	}
@@ -109,6 +115,39 @@ func (s *State) WaitStop(timeout time.Duration) (int, error) {
 	return s.GetExitCode(), nil
 }
 
+// WaitReady waits until the running container sent READY=1 on its notify
+// socket. It fails if the container stops first. If you want wait forever
+// you must supply negative timeout.
+func (s *State) WaitReady(timeout time.Duration) error {
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		s.RLock()
+		if s.Ready {
+			s.RUnlock()
+			return nil
+		}
+		if !s.Running || s.Restarting {
+			s.RUnlock()
+			return fmt.Errorf("Container is not running")
+		}
+		readyChan := s.readyChan
+		s.RUnlock()
+
+		remaining := timeout
+		if timeout >= 0 {
+			if remaining = deadline.Sub(time.Now()); remaining < 0 {
+				remaining = 0
+			}
+		}
+		if err := wait(readyChan, remaining); err != nil {
+			return err
+		}
+	}
+}
+
 func (s *State) IsRunning() bool {
 	s.RLock()
 	res := s.Running
@@ -116,6 +155,13 @@ func (s *State) IsRunning() bool {
 	return res
 }
 
+func (s *State) IsReady() bool {
+	s.RLock()
+	res := s.Ready
+	s.RUnlock()
+	return res
+}
+
 func (s *State) GetPid() int {
 	s.RLock()
 	res := s.Pid
@@ -135,6 +181,7 @@ func (s *State) SetRunning(pid int) {
 	s.Running = true
 	s.Paused = false
 	s.Restarting = false
+	s.Ready = false
 	s.ExitCode = 0
 	s.Pid = pid
 	s.StartedAt = time.Now().UTC()
@@ -143,15 +190,35 @@ func (s *State) SetRunning(pid int) {
 	s.Unlock()
 }
 
+// SetReady is when the container signaled on its notify socket that it is
+// ready
+func (s *State) SetReady() {
+	s.Lock()
+	if s.Running && !s.Ready {
+		s.Ready = true
+		s.fireReady()
+	}
+	s.Unlock()
+}
+
+// fireReady wakes the waiters for readiness, it must be called with the lock
+// held
+func (s *State) fireReady() {
+	close(s.readyChan)
+	s.readyChan = make(chan struct{})
+}
+
 func (s *State) SetStopped(exitCode int) {
 	s.Lock()
 	s.Running = false
 	s.Restarting = false
+	s.Ready = false
 	s.Pid = 0
 	s.FinishedAt = time.Now().UTC()
 	s.ExitCode = exitCode
 	close(s.waitChan) // fire waiters for stop
 	s.waitChan = make(chan struct{})
+	s.fireReady()
 	s.Unlock()
 }
 
@@ -163,11 +230,13 @@ func (s *State) SetRestarting(exitCode int) {
 	// all the checks in docker around rm/stop/etc
 	s.Running = true
 	s.Restarting = true
+	s.Ready = false
 	s.Pid = 0
 	s.FinishedAt = time.Now().UTC()
 	s.ExitCode = exitCode
 	close(s.waitChan) // fire waiters for stop
 	s.waitChan = make(chan struct{})
+	s.fireReady()
 	s.Unlock()
 }
 
@@ -100,3 +100,48 @@ func TestStateTimeoutWait(t *testing.T) {
 	}
 
 }
+
+func TestStateReady(t *testing.T) {
+	s := NewState()
+	if err := s.WaitReady(-1 * time.Second); err == nil {
+		t.Fatal("WaitReady should fail on a stopped container")
+	}
+
+	s.SetRunning(42)
+	if err := s.WaitReady(50 * time.Millisecond); err == nil {
+		t.Fatal("WaitReady should time out on a container that is not ready")
+	}
+
+	ready := make(chan error)
+	go func() {
+		ready <- s.WaitReady(-1 * time.Second)
+	}()
+	s.SetReady()
+	select {
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Ready callback doesn't fire in 100 milliseconds")
+	case err := <-ready:
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !s.IsReady() {
+		t.Fatal("State not ready")
+	}
+
+	s.SetRunning(43)
+	if s.IsReady() {
+		t.Fatal("State is still ready after a restart")
+	}
+	go func() {
+		ready <- s.WaitReady(-1 * time.Second)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.SetStopped(0)
+	if err := <-ready; err == nil {
+		t.Fatal("WaitReady should fail when the container stops")
+	}
+	if s.IsReady() {
+		t.Fatal("State is ready after the container stopped")
+	}
+}
@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/cgroups/fs"
+)
+
+// statsBatchSize is how many containers a worker of the stats collector
+// samples at once.
+const statsBatchSize = 32
+
+// statsSample is the cgroup stats of a running container, read at Time.
+type statsSample struct {
+	Time  time.Time
+	Stats *cgroups.Stats
+}
+
+// statsReader reads the stats of a running container from its cgroups,
+// resolved once.
+type statsReader interface {
+	Read() (*cgroups.Stats, error)
+}
+
+// statsCollector keeps the last stats of the running containers, sampled
+// every interval by a pool of workers reading them in batches, each
+// container with the reader of its cgroups kept from a sample to the next.
+// With an interval of 0 it is on demand only: the cgroup files are read when
+// a client asks for the stats and never else, for the hosts running
+// thousands of idle containers. The generation counts the containers
+// forgotten, for a sample read meanwhile not to be kept.
+type statsCollector struct {
+	sync.Mutex
+	interval   time.Duration
+	workers    int
+	samples    map[string]*statsSample
+	readers    map[string]statsReader
+	generation uint64
+	open       func(*Container) (statsReader, error)
+}
+
+func newStatsCollector(interval time.Duration, open func(*Container) (statsReader, error)) *statsCollector {
+	return &statsCollector{
+		interval: interval,
+		workers:  runtime.NumCPU(),
+		samples:  make(map[string]*statsSample),
+		readers:  make(map[string]statsReader),
+		open:     open,
+	}
+}
+
+// sample reads the stats of container, and keeps them along with its reader
+// unless on demand or a container was forgotten while they were read: it may
+// be this one, which stopped. A reader failing is dropped, for the cgroups to
+// be resolved again the next time.
+func (s *statsCollector) sample(container *Container) (*statsSample, error) {
+	s.Lock()
+	r, exists := s.readers[container.ID]
+	generation := s.generation
+	s.Unlock()
+	if !exists {
+		var err error
+		if r, err = s.open(container); err != nil {
+			return nil, err
+		}
+	}
+	stats, err := r.Read()
+	if err != nil {
+		s.forget(container.ID)
+		return nil, err
+	}
+	sample := &statsSample{Time: time.Now().UTC(), Stats: stats}
+	if s.interval > 0 {
+		s.Lock()
+		if s.generation == generation {
+			s.samples[container.ID] = sample
+			s.readers[container.ID] = r
+		}
+		s.Unlock()
+	}
+	return sample, nil
+}
+
+// get returns the last sample of the running container, at most an interval
+// old, or reads its stats when it has none yet or the collector is on
+// demand.
+func (s *statsCollector) get(container *Container) (*statsSample, error) {
+	s.Lock()
+	sample, exists := s.samples[container.ID]
+	s.Unlock()
+	if exists {
+		return sample, nil
+	}
+	return s.sample(container)
+}
+
+// collect samples the running containers, in batches of statsBatchSize
+// spread over the workers, and drops the samples and the readers of the
+// others once they are all sampled.
+func (s *statsCollector) collect(containers []*Container) {
+	var (
+		running = make(map[string]bool)
+		batches = make(chan []*Container)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				for _, container := range batch {
+					if _, err := s.sample(container); err != nil {
+						log.Debugf("Cannot sample the stats of %s: %s", container.ID, err)
+					}
+				}
+			}
+		}()
+	}
+	var batch []*Container
+	for _, container := range containers {
+		if !container.State.IsRunning() {
+			continue
+		}
+		running[container.ID] = true
+		if batch = append(batch, container); len(batch) == statsBatchSize {
+			batches <- batch
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		batches <- batch
+	}
+	close(batches)
+	wg.Wait()
+
+	s.Lock()
+	for id := range s.samples {
+		if !running[id] {
+			delete(s.samples, id)
+		}
+	}
+	for id := range s.readers {
+		if !running[id] {
+			delete(s.readers, id)
+		}
+	}
+	s.Unlock()
+}
+
+// forget drops the sample and the reader of a container which stopped, for
+// its stats not to be served, nor its old cgroups read, once it starts
+// again.
+func (s *statsCollector) forget(id string) {
+	s.Lock()
+	delete(s.samples, id)
+	delete(s.readers, id)
+	s.generation++
+	s.Unlock()
+}
+
+func (daemon *Daemon) openStats(container *Container) (statsReader, error) {
+	return fs.NewStatsReader(container.ID, daemon.cgroupParent())
+}
+
+// startStatsCollector samples the stats of the running containers on a
+// single ticker for the lifetime of the daemon, unless it is on demand. A
+// tick is skipped while the previous sample is still being collected.
+func (daemon *Daemon) startStatsCollector() {
+	if daemon.stats.interval <= 0 {
+		return
+	}
+	go func() {
+		for _ = range time.Tick(daemon.stats.interval) {
+			daemon.stats.collect(daemon.List())
+		}
+	}()
+}
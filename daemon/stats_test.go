@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/libcontainer/cgroups"
+)
+
+type fakeStatsReader func() (*cgroups.Stats, error)
+
+func (r fakeStatsReader) Read() (*cgroups.Stats, error) {
+	return r()
+}
+
+func TestStatsCollector(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		reads, opens int
+	)
+	read := func(container *Container) (statsReader, error) {
+		mu.Lock()
+		opens++
+		mu.Unlock()
+		return fakeStatsReader(func() (*cgroups.Stats, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reads++
+			stats := cgroups.NewStats()
+			stats.PidsStats.Current = uint64(reads)
+			return stats, nil
+		}), nil
+	}
+	running := &Container{ID: "running", State: NewState()}
+	running.State.SetRunning(1)
+	stopped := &Container{ID: "stopped", State: NewState()}
+
+	// on demand, the stats are read each time they are requested
+	s := newStatsCollector(0, read)
+	for i := 1; i <= 2; i++ {
+		sample, err := s.get(running)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sample.Stats.PidsStats.Current != uint64(i) {
+			t.Fatalf("Expected the stats to be read for request %d", i)
+		}
+	}
+	if len(s.samples) != 0 {
+		t.Fatalf("Expected no sample to be kept on demand, got %v", s.samples)
+	}
+
+	// sampling, the requests are served the last sample
+	reads = 0
+	s = newStatsCollector(time.Second, read)
+	s.collect([]*Container{running, stopped})
+	if reads != 1 {
+		t.Fatalf("Expected only the running container to be sampled, got %d reads", reads)
+	}
+	for i := 0; i < 2; i++ {
+		sample, err := s.get(running)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reads != 1 || sample.Stats.PidsStats.Current != 1 {
+			t.Fatalf("Expected the request to be served the sample, got %d reads", reads)
+		}
+	}
+	s.collect([]*Container{running})
+	if sample, _ := s.get(running); sample.Stats.PidsStats.Current != 2 {
+		t.Fatalf("Expected the sample to be refreshed, got %d", sample.Stats.PidsStats.Current)
+	}
+	s.forget(running.ID)
+	if sample, _ := s.get(running); sample.Stats.PidsStats.Current != 3 {
+		t.Fatalf("Expected the stats of the restarted container to be read again, got %d", sample.Stats.PidsStats.Current)
+	}
+	running.State.SetStopped(0)
+	s.collect([]*Container{running})
+	if len(s.samples) != 0 || len(s.readers) != 0 {
+		t.Fatalf("Expected the samples of the stopped containers to be dropped, got %v", s.samples)
+	}
+
+	// the batches of all the containers are sampled, reusing their readers
+	var many []*Container
+	for i := 0; i < 3*statsBatchSize+1; i++ {
+		c := &Container{ID: fmt.Sprintf("c%d", i), State: NewState()}
+		c.State.SetRunning(1)
+		many = append(many, c)
+	}
+	opens, reads = 0, 0
+	s.collect(many)
+	s.collect(many)
+	if len(s.samples) != len(many) || opens != len(many) || reads != 2*len(many) {
+		t.Fatalf("Expected %d containers sampled twice with a reader each, got %d samples, %d readers opened and %d reads", len(many), len(s.samples), opens, reads)
+	}
+}
+
+func TestStatsCollectorForgetWhileSampling(t *testing.T) {
+	var (
+		reading = make(chan struct{})
+		resume  = make(chan struct{})
+	)
+	s := newStatsCollector(time.Second, func(container *Container) (statsReader, error) {
+		return fakeStatsReader(func() (*cgroups.Stats, error) {
+			close(reading)
+			<-resume
+			return cgroups.NewStats(), nil
+		}), nil
+	})
+	container := &Container{ID: "stopping", State: NewState()}
+	container.State.SetRunning(1)
+	done := make(chan error)
+	go func() {
+		_, err := s.sample(container)
+		done <- err
+	}()
+	// the container stops while its stats are read
+	<-reading
+	s.forget(container.ID)
+	close(resume)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(s.samples) != 0 || len(s.readers) != 0 {
+		t.Fatalf("Expected the sample read while the container was forgotten not to be kept, got %v", s.samples)
+	}
+}
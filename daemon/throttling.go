@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"strconv"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/libcontainer/cgroups/fs"
+)
+
+// cpuThrottling is how often the CFS quota of a container throttled it,
+// along with the quota and period in effect, in microseconds. Quota is -1
+// when the container is not limited.
+type cpuThrottling struct {
+	Periods          uint64
+	ThrottledPeriods uint64
+	ThrottledTime    uint64
+	Quota            int64
+	Period           int64
+}
+
+// ContainerThrottling reports the CPU throttling of a running container, so
+// its quota can be told too tight or not before it is changed with limit.
+func (daemon *Daemon) ContainerThrottling(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+	sample, err := daemon.stats.get(container)
+	if err != nil {
+		return job.Errorf("Cannot get the CPU throttling of %s: %s", name, err)
+	}
+
+	data := sample.Stats.CpuStats.ThrottlingData
+	throttling := &cpuThrottling{
+		Periods:          data.Periods,
+		ThrottledPeriods: data.ThrottledPeriods,
+		ThrottledTime:    data.ThrottledTime,
+	}
+	// the files are missing when the kernel has no CFS bandwidth control
+	for key, value := range map[string]*int64{
+		"cpu.cfs_quota_us":  &throttling.Quota,
+		"cpu.cfs_period_us": &throttling.Period,
+	} {
+		s, err := fs.Get(container.ID, daemon.cgroupParent(), key)
+		if err != nil {
+			log.Debugf("Cannot get %s of %s: %s", key, name, err)
+			continue
+		}
+		if *value, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return job.Errorf("Invalid %s of %s: %s", key, name, err)
+		}
+	}
+
+	out := &engine.Env{}
+	if err := out.Import(throttling); err != nil {
+		return job.Error(err)
+	}
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
@@ -0,0 +1,258 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/utils"
+)
+
+const (
+	defaultTraceDuration = 10 * time.Second
+	maxTraceDuration     = 5 * time.Minute
+	// traceStopTimeout is how long a tool has to write its output once it
+	// is asked to stop, before it is killed.
+	traceStopTimeout = 10 * time.Second
+)
+
+// traceBinDirs are the directories of the toolbox image the tools are
+// looked for in.
+var traceBinDirs = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+
+// traceTool is how a tracing tool of the toolbox image is run against a
+// set of pids, and where its output goes in the artifact.
+type traceTool struct {
+	args   func(pids []int) []string
+	stdout string
+	stderr string
+}
+
+var traceTools = map[string]traceTool{
+	"strace": {
+		args: func(pids []int) []string {
+			// the trace goes to stdout, the errors of strace to stderr
+			args := []string{"-f", "-tt", "-o", "/proc/self/fd/1"}
+			for _, pid := range pids {
+				args = append(args, "-p", strconv.Itoa(pid))
+			}
+			return args
+		},
+		stdout: "strace.txt",
+		stderr: "strace.log",
+	},
+	"perf": {
+		args: func(pids []int) []string {
+			return []string{"record", "-g", "-o", "-", "-p", joinPids(pids, ",")}
+		},
+		stdout: "perf.data",
+		stderr: "perf.log",
+	},
+}
+
+// ContainerTrace runs a tracing tool from the toolbox image of the daemon
+// against the processes of a running container for a limited time, and
+// streams its output as a tar archive. The tool runs on the host, chrooted
+// into the toolbox image, so nothing has to be installed in the container.
+func (daemon *Daemon) ContainerTrace(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	var (
+		name     = job.Args[0]
+		toolName = job.Getenv("tool")
+		duration = defaultTraceDuration
+	)
+	if toolName == "" {
+		toolName = "strace"
+	}
+	tool, exists := traceTools[toolName]
+	if !exists {
+		return job.Errorf("Invalid tracing tool %s, must be strace or perf", toolName)
+	}
+	if job.Getenv("duration") != "" {
+		duration = time.Duration(job.GetenvInt64("duration")) * time.Second
+		if duration <= 0 || duration > maxTraceDuration {
+			return job.Errorf("Invalid trace duration %s, must be between 1s and %s", duration, maxTraceDuration)
+		}
+	}
+	if daemon.config.TraceImage == "" {
+		return job.Errorf("No toolbox image to trace with, start the daemon with --trace-image")
+	}
+
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+	pids, err := daemon.ExecutionDriver().GetPidsForContainer(container.ID)
+	if err != nil {
+		return job.Errorf("Cannot get the processes of %s: %s", name, err)
+	}
+	if len(pids) == 0 {
+		return job.Errorf("Container %s has no process to trace", name)
+	}
+
+	img, err := daemon.repositories.LookupImage(daemon.config.TraceImage)
+	if err != nil {
+		return job.Error(err)
+	}
+	if img == nil {
+		return job.Errorf("No such image: %s", daemon.config.TraceImage)
+	}
+	layerID, rootfs, err := mountTraceLayer(daemon.driver, img.ID)
+	if err != nil {
+		return job.Errorf("Cannot mount the toolbox image %s: %s", daemon.config.TraceImage, err)
+	}
+	defer removeTraceLayer(daemon.driver, layerID)
+
+	toolPath, err := lookupTool(rootfs, toolName)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	dir, err := ioutil.TempDir("", "docker-trace-")
+	if err != nil {
+		return job.Error(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "pids"), []byte(joinPids(pids, "\n")+"\n"), 0644); err != nil {
+		return job.Error(err)
+	}
+
+	log.Debugf("Tracing %s with %s for %s", name, toolName, duration)
+	if err := runTraceTool(rootfs, toolPath, tool.args(pids), dir, tool, duration); err != nil {
+		return job.Errorf("Cannot trace %s: %s", name, err)
+	}
+
+	artifact, err := archive.Tar(dir, archive.Uncompressed)
+	if err != nil {
+		return job.Error(err)
+	}
+	defer artifact.Close()
+	if _, err := io.Copy(job.Stdout, artifact); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// mountTraceLayer creates a throwaway layer on top of the toolbox image and
+// mounts it, for the mounts and the files of a trace never to reach the image
+// layer, which is shared by the containers created from it. It returns the
+// id of the layer and where it is mounted.
+func mountTraceLayer(driver graphdriver.Driver, imageID string) (string, string, error) {
+	id := "trace-" + utils.GenerateRandomID()
+	if err := driver.Create(id, imageID); err != nil {
+		return "", "", err
+	}
+	rootfs, err := driver.Get(id, "")
+	if err != nil {
+		driver.Remove(id)
+		return "", "", err
+	}
+	return id, rootfs, nil
+}
+
+// removeTraceLayer unmounts and removes the layer of a trace.
+func removeTraceLayer(driver graphdriver.Driver, id string) {
+	driver.Put(id)
+	if err := driver.Remove(id); err != nil {
+		log.Errorf("Cannot remove the trace layer %s: %s", id, err)
+	}
+}
+
+// runTraceTool runs toolPath chrooted into rootfs, with the /proc and /sys
+// of the host the tools need, and writes its output into dir. The tool is
+// interrupted once duration has passed, as the user of strace or perf would.
+func runTraceTool(rootfs, toolPath string, args []string, dir string, tool traceTool, duration time.Duration) error {
+	for _, fs := range []string{"/proc", "/sys"} {
+		target := filepath.Join(rootfs, fs)
+		if err := mount.ForceMount(fs, target, "none", "bind"); err != nil {
+			return fmt.Errorf("Cannot mount %s in the toolbox image: %s", fs, err)
+		}
+		defer mount.ForceUnmount(target)
+	}
+
+	stdout, err := os.Create(filepath.Join(dir, tool.stdout))
+	if err != nil {
+		return err
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(dir, tool.stderr))
+	if err != nil {
+		return err
+	}
+	defer stderr.Close()
+
+	cmd := &exec.Cmd{
+		Path:        toolPath,
+		Args:        append([]string{toolPath}, args...),
+		Dir:         "/",
+		Env:         []string{"PATH=" + strings.Join(traceBinDirs, ":")},
+		Stdout:      stdout,
+		Stderr:      stderr,
+		SysProcAttr: &syscall.SysProcAttr{Chroot: rootfs},
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		// the tool gave up before the end, typically on a missing permission
+		msg, _ := ioutil.ReadFile(stderr.Name())
+		return fmt.Errorf("%s exited early: %v: %s", filepath.Base(toolPath), err, strings.TrimSpace(string(msg)))
+	case <-time.After(duration):
+	}
+	cmd.Process.Signal(syscall.SIGINT)
+	select {
+	case <-done:
+	case <-time.After(traceStopTimeout):
+		cmd.Process.Kill()
+		<-done
+	}
+	// an interrupted tool exits with an error, its output is complete anyway
+	return nil
+}
+
+// lookupTool returns the path of the binary name in the toolbox image
+// mounted at rootfs, as seen once chrooted into it.
+func lookupTool(rootfs, name string) (string, error) {
+	for _, dir := range traceBinDirs {
+		p := filepath.Join(dir, name)
+		// the links of the image point inside of it, not to the host
+		resolved, err := symlink.FollowSymlinkInScope(filepath.Join(rootfs, p), rootfs)
+		if err != nil {
+			continue
+		}
+		if fi, err := os.Stat(resolved); err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 != 0 {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("The toolbox image has no %s in %s", name, strings.Join(traceBinDirs, ":"))
+}
+
+func joinPids(pids []int, sep string) string {
+	s := make([]string, len(pids))
+	for i, pid := range pids {
+		s[i] = strconv.Itoa(pid)
+	}
+	return strings.Join(s, sep)
+}
@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/daemon/graphdriver"
+)
+
+func TestLookupTool(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "docker-test-toolbox-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+	for _, dir := range []string{"usr/bin", "bin", "usr/local/bin"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "usr/bin/strace"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "usr/bin/perf_3.16"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// an absolute link points into the image, not to the host
+	if err := os.Symlink("/usr/bin/perf_3.16", filepath.Join(rootfs, "usr/local/bin/perf")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "bin/gdb"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{"strace": "/usr/bin/strace", "perf": "/usr/local/bin/perf"} {
+		if p, err := lookupTool(rootfs, name); err != nil {
+			t.Fatal(err)
+		} else if p != expected {
+			t.Fatalf("Expected %s at %s, got %s", name, expected, p)
+		}
+	}
+	for _, name := range []string{"gdb", "ltrace"} {
+		if _, err := lookupTool(rootfs, name); err == nil {
+			t.Fatalf("Expected no executable %s to be found", name)
+		}
+	}
+}
+
+func TestTraceLayer(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-test-trace-layer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	driver, err := graphdriver.GetDriver("vfs", root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Create("toolbox", ""); err != nil {
+		t.Fatal(err)
+	}
+	image, err := driver.Get("toolbox", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(image, "strace"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	id, rootfs, err := mountTraceLayer(driver, "toolbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rootfs == image {
+		t.Fatal("Expected the trace to mount a layer of its own")
+	}
+	if _, err := os.Stat(filepath.Join(rootfs, "strace")); err != nil {
+		t.Fatalf("Expected the layer to hold the files of the toolbox image: %s", err)
+	}
+	if err := os.Mkdir(filepath.Join(rootfs, "proc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	removeTraceLayer(driver, id)
+	if driver.Exists(id) {
+		t.Fatalf("Expected the trace layer %s to be removed", id)
+	}
+	if _, err := os.Stat(filepath.Join(image, "proc")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the toolbox image to be left alone, got %v", err)
+	}
+}
+
+func TestTraceToolArgs(t *testing.T) {
+	pids := []int{12, 345}
+	if args, expected := traceTools["strace"].args(pids), []string{"-f", "-tt", "-o", "/proc/self/fd/1", "-p", "12", "-p", "345"}; !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected the strace arguments %v, got %v", expected, args)
+	}
+	if args, expected := traceTools["perf"].args(pids), []string{"record", "-g", "-o", "-", "-p", "12,345"}; !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected the perf arguments %v, got %v", expected, args)
+	}
+}
@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"github.com/docker/docker/pkg/log"
+)
+
+// transaction records how to undo each step of an operation once it is
+// done, for a failed operation not to leave anything behind it.
+type transaction struct {
+	undos []undoStep
+}
+
+type undoStep struct {
+	step string
+	undo func() error
+}
+
+// onRollback records that undo undoes step, just done.
+func (t *transaction) onRollback(step string, undo func() error) {
+	t.undos = append(t.undos, undoStep{step, undo})
+}
+
+// rollback undoes the steps done, the last one first. It goes on when a step
+// can't be undone, so as little as possible is left behind, and returns the
+// first error.
+func (t *transaction) rollback() error {
+	var first error
+	for i := len(t.undos) - 1; i >= 0; i-- {
+		if err := t.undos[i].undo(); err != nil {
+			log.Errorf("Cannot undo %s: %s", t.undos[i].step, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	t.undos = nil
+	return first
+}
@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	var (
+		tx     = &transaction{}
+		undone []string
+	)
+	for _, step := range []string{"name", "root", "layer"} {
+		step := step
+		tx.onRollback(step, func() error {
+			undone = append(undone, step)
+			if step == "root" {
+				return fmt.Errorf("busy")
+			}
+			return nil
+		})
+	}
+	if err := tx.rollback(); err == nil || err.Error() != "busy" {
+		t.Fatalf("Expected the error of the root, got %v", err)
+	}
+	// the steps after the one failing to be undone are undone too
+	if strings.Join(undone, " ") != "layer root name" {
+		t.Fatalf("Expected the steps to be undone the last first, got %v", undone)
+	}
+	undone = nil
+	if err := tx.rollback(); err != nil || len(undone) != 0 {
+		t.Fatal("Expected the steps to be undone once")
+	}
+}
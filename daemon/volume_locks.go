@@ -0,0 +1,197 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/utils"
+)
+
+// maxVolumeLockTimeout is how long a container can wait at most for the lock
+// of a volume held by another one.
+const maxVolumeLockTimeout = 5 * time.Minute
+
+// volumeLock is an advisory lock of a volume, held by the container which
+// acquired it through the path the volume has in this container.
+type volumeLock struct {
+	Container string
+	Path      string
+	Since     time.Time
+}
+
+// volumeLocks are the advisory locks of the volumes shared by containers,
+// by the host path of the volumes. Nothing enforces them, the containers
+// sharing a volume agree to take its lock before using it.
+type volumeLocks struct {
+	sync.Mutex
+	locks map[string]*volumeLock
+	// released is closed when a lock is released, to wake up the waiters
+	released chan struct{}
+}
+
+func newVolumeLocks() *volumeLocks {
+	return &volumeLocks{
+		locks:    make(map[string]*volumeLock),
+		released: make(chan struct{}),
+	}
+}
+
+// acquire takes the lock of volume for lock.Container, waiting up to timeout
+// for another container to release it. Taking a lock the container already
+// holds succeeds.
+func (l *volumeLocks) acquire(volume string, lock *volumeLock, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		l.Lock()
+		holder, exists := l.locks[volume]
+		if !exists {
+			l.locks[volume] = lock
+		}
+		released := l.released
+		l.Unlock()
+
+		if !exists || holder.Container == lock.Container {
+			return nil
+		}
+		if timeout == 0 {
+			return fmt.Errorf("Conflict, the volume %s is locked by %s since %s", lock.Path, utils.TruncateID(holder.Container), holder.Since.Format(time.RFC3339))
+		}
+		select {
+		case <-released:
+		case <-deadline:
+			return fmt.Errorf("Conflict, the volume %s is still locked by %s after %s", lock.Path, utils.TruncateID(holder.Container), timeout)
+		}
+	}
+}
+
+// release releases the lock of volume held by container.
+func (l *volumeLocks) release(volume, container string) error {
+	l.Lock()
+	defer l.Unlock()
+	holder, exists := l.locks[volume]
+	if !exists || holder.Container != container {
+		return fmt.Errorf("The volume is not locked by %s", utils.TruncateID(container))
+	}
+	delete(l.locks, volume)
+	l.wakeUp()
+	return nil
+}
+
+// releaseAll releases the locks held by container, once it stopped.
+func (l *volumeLocks) releaseAll(container string) {
+	l.Lock()
+	defer l.Unlock()
+	for volume, holder := range l.locks {
+		if holder.Container == container {
+			delete(l.locks, volume)
+		}
+	}
+	l.wakeUp()
+}
+
+func (l *volumeLocks) wakeUp() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// lockedVolume returns the host path of the volume mounted at path in
+// container, the key of its lock.
+func lockedVolume(container *Container, path string) (string, error) {
+	volume, exists := container.Volumes[filepath.Clean(path)]
+	if !exists {
+		return "", fmt.Errorf("No such volume %s in container %s", path, utils.TruncateID(container.ID))
+	}
+	return volume, nil
+}
+
+// ContainerVolumeLock takes the advisory lock of the volume mounted at PATH
+// in a running container, for cooperating containers sharing the volume.
+// The lock is released by volume_unlock or when the container stops.
+//
+// Input: 'timeout' is how many seconds to wait for another container to
+// release it, 0 fails right away.
+func (daemon *Daemon) ContainerVolumeLock(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s CONTAINER PATH", job.Name)
+	}
+	var (
+		name    = job.Args[0]
+		path    = job.Args[1]
+		timeout = time.Duration(job.GetenvInt64("timeout")) * time.Second
+	)
+	if timeout < 0 || timeout > maxVolumeLockTimeout {
+		return job.Errorf("Invalid lock timeout %s, must be between 0 and %s", timeout, maxVolumeLockTimeout)
+	}
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+	volume, err := lockedVolume(container, path)
+	if err != nil {
+		return job.Error(err)
+	}
+	lock := &volumeLock{
+		Container: container.ID,
+		Path:      filepath.Clean(path),
+		Since:     time.Now().UTC(),
+	}
+	if err := daemon.volumeLocks.acquire(volume, lock, timeout); err != nil {
+		return job.Error(err)
+	}
+	// the container may have stopped while waiting, its locks are gone then
+	if !container.State.IsRunning() {
+		daemon.volumeLocks.releaseAll(container.ID)
+		return job.Errorf("Container %s stopped before locking %s", name, path)
+	}
+	container.LogEvent("lock")
+	return engine.StatusOK
+}
+
+// ContainerVolumeUnlock releases the lock of the volume mounted at PATH in
+// a container.
+func (daemon *Daemon) ContainerVolumeUnlock(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s CONTAINER PATH", job.Name)
+	}
+	name, path := job.Args[0], job.Args[1]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	volume, err := lockedVolume(container, path)
+	if err != nil {
+		return job.Error(err)
+	}
+	if err := daemon.volumeLocks.release(volume, container.ID); err != nil {
+		return job.Errorf("Cannot unlock %s: %s", path, err)
+	}
+	container.LogEvent("unlock")
+	return engine.StatusOK
+}
+
+// VolumeLocks lists the locked volumes, with the container holding the lock
+// and the path of the volume in this container.
+func (daemon *Daemon) VolumeLocks(job *engine.Job) engine.Status {
+	daemon.volumeLocks.Lock()
+	outs := engine.NewTable("Since", len(daemon.volumeLocks.locks))
+	for volume, lock := range daemon.volumeLocks.locks {
+		out := &engine.Env{}
+		out.Set("Volume", volume)
+		out.Set("Container", lock.Container)
+		out.Set("Path", lock.Path)
+		out.SetInt64("Since", lock.Since.Unix())
+		outs.Add(out)
+	}
+	daemon.volumeLocks.Unlock()
+	outs.Sort()
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVolumeLocks(t *testing.T) {
+	locks := newVolumeLocks()
+	if err := locks.acquire("/vol", &volumeLock{Container: "c1", Path: "/data"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	// a container can take the lock it holds again, not the one of another
+	if err := locks.acquire("/vol", &volumeLock{Container: "c1", Path: "/data"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := locks.acquire("/vol", &volumeLock{Container: "c2", Path: "/data"}, 0); err == nil || !strings.Contains(err.Error(), "Conflict") {
+		t.Fatalf("Expected a conflict, got %v", err)
+	}
+	if err := locks.release("/vol", "c2"); err == nil {
+		t.Fatal("Expected to fail releasing the lock of another container")
+	}
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- locks.acquire("/vol", &volumeLock{Container: "c2", Path: "/data"}, time.Minute)
+	}()
+	select {
+	case err := <-acquired:
+		t.Fatalf("Expected to wait for the lock, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// stopping the container releases its locks
+	locks.releaseAll("c1")
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lock to be acquired once released")
+	}
+	if holder := locks.locks["/vol"]; holder == nil || holder.Container != "c2" {
+		t.Fatalf("Unexpected holder of the lock %+v", holder)
+	}
+
+	if err := locks.acquire("/vol", &volumeLock{Container: "c3", Path: "/data"}, 10*time.Millisecond); err == nil {
+		t.Fatal("Expected the lock to time out")
+	}
+	if err := locks.release("/vol", "c2"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/libcontainer/label"
+	"github.com/docker/libcontainer/user"
 )
 
 type Volume struct {
@@ -18,6 +22,12 @@ type Volume struct {
 	VolPath     string
 	Mode        string
 	isBindMount bool
+	// relabel is the z or Z option: shared or private SELinux label
+	relabel string
+	// chown is set when the contents are to be owned by uid:gid, -1 for
+	// the user or the group of the container
+	chown    bool
+	uid, gid int
 }
 
 func (v *Volume) isRw() bool {
@@ -61,6 +71,33 @@ func setupMountsForContainer(container *Container) error {
 		mounts = append(mounts, execdriver.Mount{container.HostsPath, "/etc/hosts", true, true})
 	}
 
+	if container.notifyConn != nil {
+		mounts = append(mounts, execdriver.Mount{
+			Source:      container.notifySocketHostPath(),
+			Destination: notifySocketPath,
+			Writable:    true,
+			Private:     true,
+		})
+	}
+
+	if container.sshAgent != "" {
+		mounts = append(mounts, execdriver.Mount{
+			Source:      container.sshAgent,
+			Destination: sshAgentPath,
+			Writable:    true,
+			Private:     true,
+		})
+	}
+
+	if container.secrets {
+		mounts = append(mounts, execdriver.Mount{
+			Source:      container.secretsHostPath(),
+			Destination: buildSecretsPath,
+			Writable:    false,
+			Private:     true,
+		})
+	}
+
 	// Mount user specified volumes
 	// Note, these are not private because you may want propagation of (un)mounts from host
 	// volumes. For instance if you use -v /usr:/usr and the host later mounts /usr/share you
@@ -154,7 +191,9 @@ func parseBindVolumeSpec(spec string) (Volume, error) {
 	case 3:
 		vol.HostPath = arr[0]
 		vol.VolPath = arr[1]
-		vol.Mode = arr[2]
+		if err := vol.parseOptions(arr[2]); err != nil {
+			return vol, fmt.Errorf("Invalid volume specification: %s: %s", spec, err)
+		}
 	default:
 		return vol, fmt.Errorf("Invalid volume specification: %s", spec)
 	}
@@ -166,6 +205,40 @@ func parseBindVolumeSpec(spec string) (Volume, error) {
 	return vol, nil
 }
 
+// parseOptions parses the comma separated options of a bind mount: rw or ro,
+// z or Z to relabel the contents for SELinux, and chown, uid=UID or gid=GID
+// to give them to the user of the container or to the given ids.
+func (v *Volume) parseOptions(options string) error {
+	v.Mode = "rw"
+	v.uid, v.gid = -1, -1
+	for _, opt := range strings.Split(options, ",") {
+		switch opt {
+		case "rw", "ro":
+			v.Mode = opt
+		case "z", "Z":
+			v.relabel = opt
+		case "chown":
+			v.chown = true
+		default:
+			parts := strings.SplitN(opt, "=", 2)
+			if len(parts) != 2 || (parts[0] != "uid" && parts[0] != "gid") {
+				return fmt.Errorf("unknown option %s", opt)
+			}
+			id, err := strconv.Atoi(parts[1])
+			if err != nil || id < 0 {
+				return fmt.Errorf("invalid %s %s", parts[0], parts[1])
+			}
+			if parts[0] == "uid" {
+				v.uid = id
+			} else {
+				v.gid = id
+			}
+			v.chown = true
+		}
+	}
+	return nil
+}
+
 func getBindMap(container *Container) (map[string]Volume, error) {
 	var (
 		// Create the requested bind mounts
@@ -179,6 +252,11 @@ func getBindMap(container *Container) (map[string]Volume, error) {
 		if err != nil {
 			return volumes, err
 		}
+		// the rules of the daemon may have changed since the container
+		// was given its binds
+		if err := checkBindSource(container.daemon.bindRules(), vol.HostPath); err != nil {
+			return nil, err
+		}
 		// Bail if trying to mount to an illegal destination
 		for _, illegal := range illegalDsts {
 			if vol.VolPath == illegal {
@@ -279,6 +357,19 @@ func (v *Volume) initialize(container *Container) error {
 		return err
 	}
 
+	// The contents are only relabeled and chowned when the volume is first
+	// attached, the container keeps it on its next starts
+	if v.relabel != "" {
+		if err := label.Relabel(hostPath, container.GetMountLabel(), v.relabel); err != nil {
+			return err
+		}
+	}
+	if v.chown {
+		if err := v.chownContents(container, hostPath); err != nil {
+			return err
+		}
+	}
+
 	// Do not copy or change permissions if we are mounting from the host
 	if v.isRw() && !v.isBindMount {
 		return copyExistingContents(fullVolPath, hostPath)
@@ -286,6 +377,86 @@ func (v *Volume) initialize(container *Container) error {
 	return nil
 }
 
+// chownContents gives the contents of the volume at hostPath to its uid:gid,
+// by default the user of container as found in its /etc/passwd, so that the
+// process of the container can write to the volume.
+func (v *Volume) chownContents(container *Container, hostPath string) error {
+	uid, gid := v.uid, v.gid
+	if uid < 0 || gid < 0 {
+		cuid, cgid, err := lookupContainerUser(container.basefs, container.Config.User)
+		if err != nil {
+			return fmt.Errorf("Cannot chown volume %s: %s", v.VolPath, err)
+		}
+		if uid < 0 {
+			uid = cuid
+		}
+		if gid < 0 {
+			gid = cgid
+		}
+	}
+	log.Debugf("Chowning the contents of volume %s to %d:%d", v.VolPath, uid, gid)
+	return filepath.Walk(hostPath, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, uid, gid)
+	})
+}
+
+// lookupContainerUser returns the ids of userSpec, a user[:group] given by
+// name or id, in the passwd and group files of the rootfs of a container.
+// The user defaults to root.
+func lookupContainerUser(rootfs, userSpec string) (int, int, error) {
+	var (
+		parts    = strings.SplitN(userSpec, ":", 2)
+		userArg  = parts[0]
+		groupArg string
+		uid, gid int
+	)
+	if len(parts) == 2 {
+		groupArg = parts[1]
+	}
+	if userArg == "" {
+		userArg = "0"
+	}
+
+	passwd, err := symlink.FollowSymlinkInScope(filepath.Join(rootfs, "/etc/passwd"), rootfs)
+	if err != nil {
+		return -1, -1, err
+	}
+	users, err := user.ParsePasswdFileFilter(passwd, func(u *user.User) bool {
+		return u.Name == userArg || strconv.Itoa(u.Uid) == userArg
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return -1, -1, err
+	}
+	if len(users) > 0 {
+		uid, gid = users[0].Uid, users[0].Gid
+	} else if uid, err = strconv.Atoi(userArg); err != nil {
+		return -1, -1, fmt.Errorf("Unable to find user %s", userArg)
+	}
+
+	if groupArg == "" {
+		return uid, gid, nil
+	}
+	group, err := symlink.FollowSymlinkInScope(filepath.Join(rootfs, "/etc/group"), rootfs)
+	if err != nil {
+		return -1, -1, err
+	}
+	groups, err := user.ParseGroupFileFilter(group, func(g *user.Group) bool {
+		return g.Name == groupArg || strconv.Itoa(g.Gid) == groupArg
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return -1, -1, err
+	}
+	if len(groups) > 0 {
+		gid = groups[0].Gid
+	} else if gid, err = strconv.Atoi(groupArg); err != nil {
+		return -1, -1, fmt.Errorf("Unable to find group %s", groupArg)
+	}
+	return uid, gid, nil
+}
+
 func createIfNotExists(destination string, isDir bool) error {
 	if _, err := os.Stat(destination); err == nil || !os.IsNotExist(err) {
 		return nil
@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+// volumesReaperInterval is how often the daemon looks for the orphaned
+// volumes which are not to be kept anymore.
+const volumesReaperInterval = time.Hour
+
+// orphanedVolume is stored next to a volume left behind by a container
+// removed with a keep:DAYS policy, the volume is removed once it expires.
+type orphanedVolume struct {
+	Container string
+	Since     time.Time
+	Expires   time.Time
+}
+
+// containerVolumesPolicy returns the volumes policy of container, the one of
+// the daemon unless it was given its own.
+func (daemon *Daemon) containerVolumesPolicy(container *Container) runconfig.VolumesPolicy {
+	if policy := container.HostConfig().VolumesPolicy; policy.Name != "" {
+		return policy
+	}
+	return daemon.volumesPolicy
+}
+
+// orphanVolumes marks the volumes no other container uses of the destroyed
+// container to be removed in keepDays days.
+func (daemon *Daemon) orphanVolumes(container *Container, keepDays int) {
+	volumes, err := containerVolumeIds(container)
+	if err != nil {
+		log.Errorf("Cannot get the volumes of %s: %s", container.ID, err)
+		return
+	}
+	var (
+		usedVolumes = daemon.usedVolumes()
+		now         = time.Now().UTC()
+		orphan      = &orphanedVolume{
+			Container: container.ID,
+			Since:     now,
+			Expires:   now.Add(time.Duration(keepDays) * 24 * time.Hour),
+		}
+	)
+	for volumeId := range volumes {
+		if _, exists := usedVolumes[volumeId]; exists {
+			continue
+		}
+		if err := daemon.writeOrphanedVolume(volumeId, orphan); err != nil {
+			log.Errorf("Cannot mark the volume %s as orphaned: %s", volumeId, err)
+		}
+	}
+}
+
+func (daemon *Daemon) orphanedVolumePath(volumeId string) string {
+	return filepath.Join(daemon.volumes.ImageRoot(volumeId), "orphaned")
+}
+
+func (daemon *Daemon) writeOrphanedVolume(volumeId string, orphan *orphanedVolume) error {
+	data, err := json.Marshal(orphan)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(daemon.orphanedVolumePath(volumeId), data, 0600)
+}
+
+// readOrphanedVolume returns the orphan marker of a volume, nil if it has
+// none.
+func (daemon *Daemon) readOrphanedVolume(volumeId string) (*orphanedVolume, error) {
+	data, err := ioutil.ReadFile(daemon.orphanedVolumePath(volumeId))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	orphan := &orphanedVolume{}
+	if err := json.Unmarshal(data, orphan); err != nil {
+		return nil, err
+	}
+	return orphan, nil
+}
+
+// collectVolumes removes the orphaned volumes which have expired. A volume
+// a container uses again, through a bind mount of its path, is not orphaned
+// anymore.
+func (daemon *Daemon) collectVolumes() {
+	volumes, err := daemon.volumes.Map()
+	if err != nil {
+		log.Errorf("Error listing the volumes: %s", err)
+		return
+	}
+	var (
+		usedVolumes = daemon.usedVolumes()
+		now         = time.Now()
+		removed     []string
+	)
+	for volumeId := range volumes {
+		orphan, err := daemon.readOrphanedVolume(volumeId)
+		if err != nil {
+			log.Errorf("Error reading the orphan marker of the volume %s: %s", volumeId, err)
+			continue
+		}
+		if orphan == nil {
+			continue
+		}
+		if _, exists := usedVolumes[volumeId]; exists {
+			os.Remove(daemon.orphanedVolumePath(volumeId))
+			continue
+		}
+		if now.Before(orphan.Expires) {
+			continue
+		}
+		if err := daemon.volumes.Delete(volumeId); err != nil {
+			log.Errorf("Error removing the expired volume %s: %s", volumeId, err)
+			continue
+		}
+		removed = append(removed, utils.TruncateID(volumeId))
+	}
+	if len(removed) > 0 {
+		log.Infof("Removed %d expired orphaned volumes: %s", len(removed), strings.Join(removed, ", "))
+	}
+}
+
+// startVolumesReaper collects the expired volumes once and then every
+// volumesReaperInterval for the lifetime of the daemon.
+func (daemon *Daemon) startVolumesReaper() {
+	daemon.collectVolumes()
+	go func() {
+		for _ = range time.Tick(volumesReaperInterval) {
+			daemon.collectVolumes()
+		}
+	}()
+}
+
+// VolumesOrphaned lists the volumes no container uses, with their size and,
+// for the ones left behind with a keep:DAYS policy, since when they are
+// orphaned and when they expire. The other ones are kept until removed by
+// hand.
+func (daemon *Daemon) VolumesOrphaned(job *engine.Job) engine.Status {
+	if len(job.Args) != 0 {
+		return job.Errorf("Usage: %s", job.Name)
+	}
+	volumes, err := daemon.volumes.Map()
+	if err != nil {
+		return job.Error(err)
+	}
+	var (
+		usedVolumes = daemon.usedVolumes()
+		driver      = daemon.volumes.Driver()
+		outs        = engine.NewTable("Created", len(volumes))
+	)
+	for volumeId, volume := range volumes {
+		if _, exists := usedVolumes[volumeId]; exists {
+			continue
+		}
+		out := &engine.Env{}
+		out.Set("Id", volumeId)
+		out.SetInt64("Created", volume.Created.Unix())
+		if orphan, err := daemon.readOrphanedVolume(volumeId); err != nil {
+			log.Errorf("Error reading the orphan marker of the volume %s: %s", volumeId, err)
+		} else if orphan != nil {
+			out.Set("Container", orphan.Container)
+			out.SetInt64("Orphaned", orphan.Since.Unix())
+			out.SetInt64("Expires", orphan.Expires.Unix())
+		}
+		if dir, err := driver.Get(volumeId, ""); err == nil {
+			out.Set("Path", dir)
+			if size, err := utils.TreeSize(dir); err == nil {
+				out.SetInt64("Size", size)
+			}
+			driver.Put(volumeId)
+		}
+		outs.Add(out)
+	}
+	outs.ReverseSort()
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/graph"
+)
+
+func TestCollectVolumes(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-volumes-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	driver, err := graphdriver.GetDriver("vfs", root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumes, err := graph.NewGraph(filepath.Join(root, "volumes"), driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemon := &Daemon{
+		containers: &contStore{s: make(map[string]*Container)},
+		volumes:    volumes,
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		v, err := volumes.Create(nil, "", "", "", "", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, v.ID)
+	}
+	// expired, not expired yet, and kept until removed by hand
+	now := time.Now().UTC()
+	if err := daemon.writeOrphanedVolume(ids[0], &orphanedVolume{Container: "c1", Since: now.Add(-48 * time.Hour), Expires: now.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := daemon.writeOrphanedVolume(ids[1], &orphanedVolume{Container: "c2", Since: now, Expires: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	daemon.collectVolumes()
+	if volumes.Exists(ids[0]) {
+		t.Fatal("Expected the expired volume to be removed")
+	}
+	for _, id := range ids[1:] {
+		if !volumes.Exists(id) {
+			t.Fatalf("Expected the volume %s to be kept", id)
+		}
+	}
+	orphan, err := daemon.readOrphanedVolume(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orphan == nil || orphan.Container != "c2" {
+		t.Fatalf("Unexpected orphan marker %+v", orphan)
+	}
+	if orphan, err := daemon.readOrphanedVolume(ids[2]); err != nil || orphan != nil {
+		t.Fatalf("Expected no orphan marker, got %+v: %v", orphan, err)
+	}
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseBindVolumeOptions(t *testing.T) {
+	vol, err := parseBindVolumeSpec("/host:/data:ro,Z,uid=1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.isRw() || vol.relabel != "Z" || !vol.chown || vol.uid != 1000 || vol.gid != -1 {
+		t.Fatalf("Unexpected volume %+v", vol)
+	}
+
+	vol, err = parseBindVolumeSpec("/host:/data:chown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vol.isRw() || vol.relabel != "" || !vol.chown || vol.uid != -1 || vol.gid != -1 {
+		t.Fatalf("Unexpected volume %+v", vol)
+	}
+
+	for _, spec := range []string{"/host:/data:rx", "/host:/data:uid=me", "/host:/data:gid=-1", "/host:/data:ro,"} {
+		if _, err := parseBindVolumeSpec(spec); err == nil {
+			t.Fatalf("Expected an error for %s", spec)
+		}
+	}
+}
+
+func TestLookupContainerUser(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "docker-volume-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+	if err := os.MkdirAll(filepath.Join(rootfs, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "etc", "passwd"), []byte("root:x:0:0:root:/root:/bin/sh\napp:x:1000:1001::/home/app:/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "etc", "group"), []byte("root:x:0:\nstaff:x:50:app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for spec, ids := range map[string][2]int{
+		"":          {0, 0},
+		"app":       {1000, 1001},
+		"1000":      {1000, 1001},
+		"app:staff": {1000, 50},
+		"2000:3000": {2000, 3000},
+	} {
+		uid, gid, err := lookupContainerUser(rootfs, spec)
+		if err != nil {
+			t.Fatalf("%q: %s", spec, err)
+		}
+		if uid != ids[0] || gid != ids[1] {
+			t.Fatalf("Expected %q to be %d:%d, got %d:%d", spec, ids[0], ids[1], uid, gid)
+		}
+	}
+	for _, spec := range []string{"nobody", "app:nogroup"} {
+		if _, _, err := lookupContainerUser(rootfs, spec); err == nil {
+			t.Fatalf("Expected an error for %q", spec)
+		}
+	}
+}
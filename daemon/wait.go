@@ -12,6 +12,16 @@ func (daemon *Daemon) ContainerWait(job *engine.Job) engine.Status {
 	}
 	name := job.Args[0]
 	if container := daemon.Get(name); container != nil {
+		if job.Getenv("condition") == "ready" {
+			if container.hostConfig == nil || !container.hostConfig.Notify {
+				return job.Errorf("Container %s has no notify socket", name)
+			}
+			if err := container.State.WaitReady(-1 * time.Second); err != nil {
+				return job.Errorf("Container %s stopped before it was ready", name)
+			}
+			job.Printf("%d\n", 0)
+			return engine.StatusOK
+		}
 		status, _ := container.State.WaitStop(-1 * time.Second)
 		job.Printf("%d\n", status)
 		return engine.StatusOK
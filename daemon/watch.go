@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/engine"
+)
+
+// fileChange is a change of a file seen by a fileWatcher, its path is
+// relative to the watched directory.
+type fileChange struct {
+	archive.Change
+	Time time.Time
+}
+
+// ContainerWatch streams the changes of the files in the rw layer of a
+// running container as they happen, in the form docker diff lists them,
+// until the container stops.
+//
+// Input: 'heartbeat' is how many seconds to write a newline after when no
+// file changed, for the proxies not to drop the connection.
+func (daemon *Daemon) ContainerWatch(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+
+	dir, union := daemon.rwLayerDir(container)
+	watcher, err := newFileWatcher(dir)
+	if err != nil {
+		return job.Errorf("Cannot watch the files of %s: %s", name, err)
+	}
+	defer watcher.Close()
+	go func() {
+		container.State.WaitStop(-1 * time.Second)
+		watcher.Close()
+	}()
+
+	var heartbeat <-chan time.Time
+	if interval := job.GetenvInt64("heartbeat"); interval > 0 {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	job.Stdout.Write(nil)
+	for {
+		select {
+		case change, ok := <-watcher.Changes:
+			if !ok {
+				return engine.StatusOK
+			}
+			if union {
+				if change, ok = unionChange(change); !ok {
+					continue
+				}
+			}
+			out := &engine.Env{}
+			out.Set("Path", change.Path)
+			out.SetInt("Kind", int(change.Kind))
+			out.SetInt64("Time", change.Time.Unix())
+			if _, err := out.WriteTo(job.Stdout); err != nil {
+				return job.Error(err)
+			}
+		case <-heartbeat:
+			if _, err := job.Stdout.Write([]byte{'\n'}); err != nil {
+				return job.Error(err)
+			}
+		}
+	}
+}
+
+// rwLayerDir returns the directory the files changed in container are
+// written to, and whether it is the rw branch of a union, with whiteouts.
+// The drivers which are not unions mount the rw layer itself.
+func (daemon *Daemon) rwLayerDir(container *Container) (string, bool) {
+	if upper, ok := daemon.driver.(graphdriver.UpperDirer); ok {
+		return upper.UpperDir(container.ID), true
+	}
+	return container.basefs, false
+}
+
+// unionChange translates a change of the rw branch of an aufs union to the
+// change the container sees: the creation of a whiteout is the deletion of
+// the file it hides, and the internal files of aufs are not changes. A file
+// of the image changed for the first time is copied up, and reported as
+// added to the layer.
+func unionChange(change fileChange) (fileChange, bool) {
+	if strings.HasPrefix(change.Path, "/.wh..wh.") {
+		return change, false
+	}
+	dir, base := filepath.Split(change.Path)
+	if !strings.HasPrefix(base, ".wh.") {
+		return change, true
+	}
+	// the opaque directories, and the whiteouts removed when the file is
+	// created again, which is reported on its own
+	if strings.HasPrefix(base, ".wh..wh.") || change.Kind != archive.ChangeAdd {
+		return change, false
+	}
+	change.Path = filepath.Join(dir, base[len(".wh."):])
+	change.Kind = archive.ChangeDelete
+	return change, true
+}
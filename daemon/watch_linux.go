@@ -0,0 +1,195 @@
+// +build linux
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/pkg/log"
+)
+
+const watchMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR | syscall.IN_DONT_FOLLOW
+
+var errWatcherClosed = errors.New("the watcher is closed")
+
+// fileWatcher reports the changes of the files under a directory on
+// Changes with inotify, watching the subdirectories as they are created.
+// Changes is closed once the watcher is closed or the directory is gone.
+type fileWatcher struct {
+	Changes chan fileChange
+
+	fd   int
+	root string
+	done chan struct{}
+
+	sync.Mutex
+	closed bool
+	// dirs are the watched directories by watch descriptor
+	dirs map[int32]string
+}
+
+func newFileWatcher(root string) (*fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	w := &fileWatcher{
+		Changes: make(chan fileChange),
+		fd:      fd,
+		root:    root,
+		done:    make(chan struct{}),
+		dirs:    make(map[int32]string),
+	}
+	if err := w.watchTree("/", false); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	go w.read()
+	return w, nil
+}
+
+// Close stops the watcher. Removing the watches queues an IN_IGNORED event
+// for each of them, which wakes up the read blocked on the inotify fd.
+func (w *fileWatcher) Close() {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return
+	}
+	w.closed = true
+	close(w.done)
+	for wd := range w.dirs {
+		syscall.InotifyRmWatch(w.fd, uint32(wd))
+	}
+}
+
+func (w *fileWatcher) isClosed() bool {
+	w.Lock()
+	defer w.Unlock()
+	return w.closed
+}
+
+func (w *fileWatcher) read() {
+	defer func() {
+		w.Lock()
+		w.closed = true
+		syscall.Close(w.fd)
+		w.Unlock()
+		close(w.Changes)
+	}()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := syscall.Read(w.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			log.Errorf("Error reading the inotify events of %s: %s", w.root, err)
+			return
+		}
+		if w.isClosed() {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameOffset := offset + syscall.SizeofInotifyEvent
+			offset = nameOffset + int(event.Len)
+			name := strings.TrimRight(string(buf[nameOffset:offset]), "\x00")
+			if !w.handle(event.Wd, event.Mask, name) {
+				return
+			}
+		}
+	}
+}
+
+// handle reports the change of an inotify event, it returns false once the
+// watcher is to stop.
+func (w *fileWatcher) handle(wd int32, mask uint32, name string) bool {
+	now := time.Now().UTC()
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		// events were lost, anything may have changed
+		log.Errorf("The inotify queue of %s overflowed", w.root)
+		return w.send(fileChange{archive.Change{Path: "/", Kind: archive.ChangeModify}, now}) == nil
+	}
+
+	w.Lock()
+	dir, exists := w.dirs[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		// the directory was removed, or the root of the watcher
+		delete(w.dirs, wd)
+	}
+	empty := len(w.dirs) == 0
+	w.Unlock()
+	if mask&syscall.IN_IGNORED != 0 {
+		return !empty
+	}
+	if !exists || name == "" {
+		return true
+	}
+
+	p := filepath.Join(dir, name)
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		if mask&syscall.IN_ISDIR != 0 {
+			return w.watchTree(p, true) != errWatcherClosed
+		}
+		return w.send(fileChange{archive.Change{Path: p, Kind: archive.ChangeAdd}, now}) == nil
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		return w.send(fileChange{archive.Change{Path: p, Kind: archive.ChangeDelete}, now}) == nil
+	}
+	return w.send(fileChange{archive.Change{Path: p, Kind: archive.ChangeModify}, now}) == nil
+}
+
+// watchTree watches dir and its subdirectories. When report is set, dir
+// and the files found under it are reported as added, once watched: they
+// were created before. A file created meanwhile may be reported twice.
+func (w *fileWatcher) watchTree(dir string, report bool) error {
+	top := filepath.Join(w.root, dir)
+	return filepath.Walk(top, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if p == top && !report {
+				return err
+			}
+			// removed since it was created
+			return nil
+		}
+		rel := filepath.Join(dir, p[len(top):])
+		if fi.IsDir() {
+			// watching again a directory moved around updates its path
+			wd, err := syscall.InotifyAddWatch(w.fd, p, watchMask)
+			if err != nil {
+				if p == top && !report {
+					return err
+				}
+				log.Errorf("Cannot watch %s: %s", p, err)
+				return filepath.SkipDir
+			}
+			w.Lock()
+			w.dirs[int32(wd)] = rel
+			w.Unlock()
+		}
+		if report {
+			return w.send(fileChange{archive.Change{Path: rel, Kind: archive.ChangeAdd}, time.Now().UTC()})
+		}
+		return nil
+	})
+}
+
+func (w *fileWatcher) send(change fileChange) error {
+	select {
+	case w.Changes <- change:
+		return nil
+	case <-w.done:
+		return errWatcherClosed
+	}
+}
@@ -0,0 +1,74 @@
+// +build linux
+
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/archive"
+)
+
+func TestFileWatcher(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-watch-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	watcher, err := newFileWatcher(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the files created with their directory may be reported twice
+	var last archive.Change
+	next := func() archive.Change {
+		for {
+			select {
+			case change := <-watcher.Changes:
+				if change.Change != last {
+					last = change.Change
+					return last
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Expected a change of the files")
+			}
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if change := next(); change != (archive.Change{Path: "/dir", Kind: archive.ChangeAdd}) {
+		t.Fatalf("Unexpected change %v", change)
+	}
+	// the new directories are watched too
+	if err := ioutil.WriteFile(filepath.Join(root, "dir", "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if change := next(); change != (archive.Change{Path: "/dir/file", Kind: archive.ChangeAdd}) {
+		t.Fatalf("Unexpected change %v", change)
+	}
+	if change := next(); change != (archive.Change{Path: "/dir/file", Kind: archive.ChangeModify}) {
+		t.Fatalf("Unexpected change %v", change)
+	}
+	if err := os.Remove(filepath.Join(root, "dir", "file")); err != nil {
+		t.Fatal(err)
+	}
+	if change := next(); change != (archive.Change{Path: "/dir/file", Kind: archive.ChangeDelete}) {
+		t.Fatalf("Unexpected change %v", change)
+	}
+
+	watcher.Close()
+	select {
+	case _, ok := <-watcher.Changes:
+		if ok {
+			t.Fatal("Expected no change once closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the changes to be closed")
+	}
+}
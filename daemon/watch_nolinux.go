@@ -0,0 +1,16 @@
+// +build !linux
+
+package daemon
+
+import "fmt"
+
+type fileWatcher struct {
+	Changes chan fileChange
+}
+
+func newFileWatcher(root string) (*fileWatcher, error) {
+	return nil, fmt.Errorf("Watching the files of a container is only supported on linux")
+}
+
+func (w *fileWatcher) Close() {
+}
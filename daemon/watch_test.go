@@ -0,0 +1,31 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/archive"
+)
+
+func TestUnionChange(t *testing.T) {
+	for _, c := range []struct {
+		change   archive.Change
+		expected *archive.Change
+	}{
+		{archive.Change{Path: "/etc/hosts", Kind: archive.ChangeModify}, &archive.Change{Path: "/etc/hosts", Kind: archive.ChangeModify}},
+		{archive.Change{Path: "/etc/.wh.motd", Kind: archive.ChangeAdd}, &archive.Change{Path: "/etc/motd", Kind: archive.ChangeDelete}},
+		{archive.Change{Path: "/etc/.wh.motd", Kind: archive.ChangeDelete}, nil},
+		{archive.Change{Path: "/etc/.wh..wh..opq", Kind: archive.ChangeAdd}, nil},
+		{archive.Change{Path: "/.wh..wh.plnk/12.34", Kind: archive.ChangeAdd}, nil},
+	} {
+		change, ok := unionChange(fileChange{Change: c.change})
+		if c.expected == nil {
+			if ok {
+				t.Errorf("Expected %v to be ignored, got %v", c.change, change.Change)
+			}
+			continue
+		}
+		if !ok || change.Change != *c.expected {
+			t.Errorf("Expected %v for %v, got %v", *c.expected, c.change, change.Change)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/runconfig"
+)
+
+// watchdog takes the watchdog action of a container when the container did
+// not send WATCHDOG=1 on its notify socket for an interval.
+type watchdog struct {
+	sync.Mutex
+	container *Container
+	interval  time.Duration
+	action    string
+	timer     *time.Timer
+}
+
+// startWatchdog arms the watchdog of the container if it has one.
+func (container *Container) startWatchdog() {
+	if container.hostConfig == nil || container.hostConfig.Watchdog.Interval <= 0 {
+		return
+	}
+	w := &watchdog{
+		container: container,
+		interval:  time.Duration(container.hostConfig.Watchdog.Interval) * time.Second,
+		action:    container.hostConfig.Watchdog.Action,
+	}
+	w.timer = time.AfterFunc(w.interval, w.expire)
+
+	container.watchdogLock.Lock()
+	container.watchdog = w
+	container.watchdogLock.Unlock()
+}
+
+// stopWatchdog disarms the watchdog of the container.
+func (container *Container) stopWatchdog() {
+	container.watchdogLock.Lock()
+	w := container.watchdog
+	container.watchdog = nil
+	container.watchdogLock.Unlock()
+
+	if w != nil {
+		w.Lock()
+		w.timer.Stop()
+		w.timer = nil
+		w.Unlock()
+	}
+}
+
+// pingWatchdog postpones the watchdog action of the container by an interval.
+func (container *Container) pingWatchdog() {
+	container.watchdogLock.Lock()
+	w := container.watchdog
+	container.watchdogLock.Unlock()
+
+	if w != nil {
+		w.Lock()
+		if w.timer != nil {
+			w.timer.Reset(w.interval)
+		}
+		w.Unlock()
+	}
+}
+
+func (w *watchdog) expire() {
+	container := w.container
+	log.Infof("Container %s did not ping its watchdog for %s, running %s", container.ID, w.interval, w.action)
+	container.LogEvent("watchdog")
+
+	switch w.action {
+	case "restart":
+		if err := container.Restart(10); err != nil {
+			log.Errorf("Error restarting %s after its watchdog expired: %s", container.ID, err)
+		}
+		return
+	case "event":
+	default:
+		sig, err := runconfig.ParseWatchdogAction(w.action)
+		if err != nil {
+			log.Errorf("%s: %s", container.ID, err)
+			break
+		}
+		if err := container.daemon.Kill(container, sig); err != nil {
+			log.Errorf("Error sending signal %d to %s after its watchdog expired: %s", sig, container.ID, err)
+		}
+	}
+
+	// keep watching the container, the action is taken again if it still
+	// does not ping
+	w.Lock()
+	if w.timer != nil {
+		w.timer.Reset(w.interval)
+	}
+	w.Unlock()
+}
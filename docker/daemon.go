@@ -4,6 +4,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/docker/docker/builtins"
 	"github.com/docker/docker/daemon"
@@ -30,6 +31,16 @@ func mainDaemon() {
 		flag.Usage()
 		return
 	}
+	if daemonCfg.MigrateDryRun {
+		if err := daemon.CheckStateMigrations(daemonCfg.Root, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	daemonCfg.TlsVerify = *flTlsVerify
+	daemonCfg.TlsCa = *flCa
+	daemonCfg.TlsCert = *flCert
+	daemonCfg.TlsKey = *flKey
 	eng := engine.New()
 	signal.Trap(eng.Shutdown)
 	// Load builtins
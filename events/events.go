@@ -30,6 +30,7 @@ func (e *Events) Install(eng *engine.Engine) error {
 	// Here you should describe public interface
 	jobs := map[string]engine.Handler{
 		"events":            e.Get,
+		"events_webhook":    e.Webhook,
 		"log":               e.Log,
 		"subscribers_count": e.SubscribersCount,
 	}
@@ -45,26 +46,45 @@ func (e *Events) Get(job *engine.Job) engine.Status {
 	var (
 		since   = job.GetenvInt64("since")
 		until   = job.GetenvInt64("until")
+		offset  = job.GetenvInt("offset")
+		limit   = job.GetenvInt("limit")
 		timeout = time.NewTimer(time.Unix(until, 0).Sub(time.Now()))
 	)
+	if offset < 0 {
+		return job.Errorf("Bad parameter, invalid offset %d", offset)
+	}
 
 	// If no until, disable timeout
 	if until == 0 {
 		timeout.Stop()
 	}
 
+	// Write a newline every heartbeat seconds so that idle connections are
+	// not dropped by proxies, JSON stream decoders skip it.
+	var heartbeat <-chan time.Time
+	if interval := job.GetenvInt64("heartbeat"); interval > 0 {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
 	listener := make(chan *utils.JSONMessage)
 	e.subscribe(listener)
 	defer e.unsubscribe(listener)
 
 	job.Stdout.Write(nil)
 
-	// Resend every event in the [since, until] time interval.
-	if since != 0 {
-		if err := e.writeCurrent(job, since, until); err != nil {
+	// Resend every event in the [since, until] time interval. With a limit,
+	// only a page of them is, offset events after the first one, and no
+	// other events follow.
+	if since != 0 || limit > 0 {
+		if err := e.writeCurrent(job, since, until, offset, limit); err != nil {
 			return job.Error(err)
 		}
 	}
+	if limit > 0 {
+		return engine.StatusOK
+	}
 
 	for {
 		select {
@@ -75,6 +95,10 @@ func (e *Events) Get(job *engine.Job) engine.Status {
 			if err := writeEvent(job, event); err != nil {
 				return job.Error(err)
 			}
+		case <-heartbeat:
+			if _, err := job.Stdout.Write([]byte{'\n'}); err != nil {
+				return job.Error(err)
+			}
 		case <-timeout.C:
 			return engine.StatusOK
 		}
@@ -85,8 +109,15 @@ func (e *Events) Log(job *engine.Job) engine.Status {
 	if len(job.Args) != 3 {
 		return job.Errorf("usage: %s ACTION ID FROM", job.Name)
 	}
+	// the details of the event, such as the limits an update changed
+	var attributes map[string]string
+	if job.EnvExists("attributes") {
+		if err := job.GetenvJson("attributes", &attributes); err != nil {
+			return job.Error(err)
+		}
+	}
 	// not waiting for receivers
-	go e.log(job.Args[0], job.Args[1], job.Args[2])
+	go e.log(job.Args[0], job.Args[1], job.Args[2], attributes)
 	return engine.StatusOK
 }
 
@@ -108,17 +139,25 @@ func writeEvent(job *engine.Job, event *utils.JSONMessage) error {
 	return nil
 }
 
-func (e *Events) writeCurrent(job *engine.Job, since, until int64) error {
+func (e *Events) writeCurrent(job *engine.Job, since, until int64, offset, limit int) error {
 	e.mu.RLock()
+	defer e.mu.RUnlock()
+	written := 0
 	for _, event := range e.events {
+		if limit > 0 && written == limit {
+			break
+		}
 		if event.Time >= since && (event.Time <= until || until == 0) {
+			if offset > 0 {
+				offset--
+				continue
+			}
 			if err := writeEvent(job, event); err != nil {
-				e.mu.RUnlock()
 				return err
 			}
+			written++
 		}
 	}
-	e.mu.RUnlock()
 	return nil
 }
 
@@ -129,10 +168,10 @@ func (e *Events) subscribersCount() int {
 	return c
 }
 
-func (e *Events) log(action, id, from string) {
+func (e *Events) log(action, id, from string, attributes map[string]string) {
 	e.mu.Lock()
 	now := time.Now().UTC().Unix()
-	jm := &utils.JSONMessage{Status: action, ID: id, From: from, Time: now}
+	jm := &utils.JSONMessage{Status: action, ID: id, From: from, Time: now, Attributes: attributes}
 	if len(e.events) == cap(e.events) {
 		// discard oldest event
 		copy(e.events, e.events[1:])
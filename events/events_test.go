@@ -2,9 +2,16 @@ package events
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
@@ -22,7 +29,7 @@ func TestEventsPublish(t *testing.T) {
 	if count != 2 {
 		t.Fatalf("Must be 2 subscribers, got %d", count)
 	}
-	go e.log("test", "cont", "image")
+	go e.log("test", "cont", "image", nil)
 	select {
 	case msg := <-l1:
 		if len(e.events) != 1 {
@@ -66,7 +73,7 @@ func TestEventsPublishTimeout(t *testing.T) {
 
 	c := make(chan struct{})
 	go func() {
-		e.log("test", "cont", "image")
+		e.log("test", "cont", "image", nil)
 		close(c)
 	}()
 
@@ -132,6 +139,88 @@ func TestLogEvents(t *testing.T) {
 	}
 }
 
+func TestLogAttributes(t *testing.T) {
+	e := New()
+	eng := engine.New()
+	if err := e.Install(eng); err != nil {
+		t.Fatal(err)
+	}
+	l := make(chan *utils.JSONMessage)
+	e.subscribe(l)
+
+	job := eng.Job("log", "update", "cont", "image")
+	if err := job.SetenvJson("attributes", map[string]string{"cpu.shares": "1024->512"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-l:
+		if msg.Status != "update" || msg.Attributes["cpu.shares"] != "1024->512" {
+			t.Fatalf("Expected an update of cpu.shares from 1024 to 512, got %s %v", msg.Status, msg.Attributes)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the update event")
+	}
+}
+
+func TestEventsPages(t *testing.T) {
+	e := New()
+	eng := engine.New()
+	if err := e.Install(eng); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		e.log(fmt.Sprintf("action_%d", i), "cont", "image", nil)
+	}
+
+	// no until, the job returns once the page is written
+	job := eng.Job("events")
+	job.SetenvInt("offset", 4)
+	job.SetenvInt("limit", 3)
+	buf := bytes.NewBuffer(nil)
+	job.Stdout.Add(buf)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewBuffer(buf.Bytes()))
+	var actions []string
+	for {
+		var jm utils.JSONMessage
+		if err := dec.Decode(&jm); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		actions = append(actions, jm.Status)
+	}
+	if fmt.Sprint(actions) != "[action_4 action_5 action_6]" {
+		t.Fatalf("Expected the events 4 to 6, got %v", actions)
+	}
+}
+
+func TestEventsHeartbeat(t *testing.T) {
+	e := New()
+	eng := engine.New()
+	if err := e.Install(eng); err != nil {
+		t.Fatal(err)
+	}
+
+	job := eng.Job("events")
+	job.SetenvInt64("until", time.Now().Unix()+2)
+	job.SetenvInt64("heartbeat", 1)
+	buf := bytes.NewBuffer(nil)
+	job.Stdout.Add(buf)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() == 0 || len(bytes.Trim(buf.Bytes(), "\n")) != 0 {
+		t.Fatalf("Expected only heartbeats, got %q", buf.String())
+	}
+}
+
 func TestEventsCountJob(t *testing.T) {
 	e := New()
 	eng := engine.New()
@@ -152,3 +241,86 @@ func TestEventsCountJob(t *testing.T) {
 		t.Fatalf("There must be 2 subscribers, got %d", count)
 	}
 }
+
+func TestParseWebhook(t *testing.T) {
+	secretFile, err := ioutil.TempFile("", "docker-webhook-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(secretFile.Name())
+	secretFile.WriteString("s3cr3t\n")
+	secretFile.Close()
+
+	w, err := parseWebhook("https://hooks.example.com/docker,type=container,event=die,event=oom,secret-file=" + secretFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.url != "https://hooks.example.com/docker" || !w.types["container"] || !w.events["die"] || !w.events["oom"] || string(w.secret) != "s3cr3t" {
+		t.Fatalf("Unexpected webhook %+v", w)
+	}
+	for _, event := range []struct {
+		status, from string
+		match        bool
+	}{
+		{"die", "busybox:latest", true},
+		{"start", "busybox:latest", false},
+		{"die", "", false},
+	} {
+		if w.match(&utils.JSONMessage{Status: event.status, From: event.from}) != event.match {
+			t.Errorf("Expected %v matching %s from %q", event.match, event.status, event.from)
+		}
+	}
+
+	for _, spec := range []string{"", "hooks.example.com", "ftp://hooks.example.com", "http://hooks.example.com,type=volume", "http://hooks.example.com,event", "http://hooks.example.com,secret=s3cr3t"} {
+		if _, err := parseWebhook(spec); err == nil {
+			t.Errorf("Expected %q to be refused", spec)
+		}
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	var (
+		attempts int
+		received = make(chan *http.Request, 1)
+		body     []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+		received <- r
+	}))
+	defer server.Close()
+
+	w, err := parseWebhook(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.secret = []byte("s3cr3t")
+	event := &utils.JSONMessage{Status: "die", ID: "cont", From: "image", Time: 1405468560}
+	if err := w.deliver(event); err != nil {
+		t.Fatal(err)
+	}
+	r := <-received
+	if attempts != 2 {
+		t.Fatalf("Expected the event to be posted again, got %d attempts", attempts)
+	}
+	if r.Header.Get("X-Docker-Event") != "die" {
+		t.Fatalf("Unexpected event header %q", r.Header.Get("X-Docker-Event"))
+	}
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write(body)
+	if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Docker-Signature") != expected {
+		t.Fatalf("Expected the signature %s, got %s", expected, r.Header.Get("X-Docker-Signature"))
+	}
+	var posted utils.JSONMessage
+	if err := json.Unmarshal(body, &posted); err != nil {
+		t.Fatal(err)
+	}
+	if posted.Status != "die" || posted.ID != "cont" || posted.From != "image" {
+		t.Fatalf("Unexpected event %+v", posted)
+	}
+}
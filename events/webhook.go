@@ -0,0 +1,187 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
+)
+
+const (
+	// webhookRetries is how many times the delivery of an event is retried,
+	// waiting webhookRetryDelay and then twice as long each time.
+	webhookRetries    = 5
+	webhookRetryDelay = time.Second
+	webhookTimeout    = 10 * time.Second
+)
+
+// webhook posts the events matching its filters to an HTTP endpoint, signed
+// with the HMAC-SHA256 of its secret when it has one.
+type webhook struct {
+	url    string
+	types  map[string]bool
+	events map[string]bool
+	secret []byte
+	client *http.Client
+}
+
+// parseWebhook parses a webhook of the form URL[,type=TYPE][,event=EVENT]
+// [,secret-file=FILE]. type and event can be repeated, the events of any
+// type and status are posted when they are not given.
+func parseWebhook(spec string) (*webhook, error) {
+	parts := strings.Split(spec, ",")
+	u, err := url.Parse(parts[0])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Invalid webhook URL %q, must be http(s)://HOST[/PATH]", parts[0])
+	}
+	w := &webhook{
+		url:    parts[0],
+		types:  make(map[string]bool),
+		events: make(map[string]bool),
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+	for _, opt := range parts[1:] {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("Invalid webhook option %q, must be key=value", opt)
+		}
+		switch kv[0] {
+		case "type":
+			if kv[1] != "container" && kv[1] != "image" {
+				return nil, fmt.Errorf("Invalid webhook event type %s, must be container or image", kv[1])
+			}
+			w.types[kv[1]] = true
+		case "event":
+			w.events[kv[1]] = true
+		case "secret-file":
+			// the secret is not given on the command line, for ps not to show it
+			secret, err := ioutil.ReadFile(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("Cannot read the webhook secret: %s", err)
+			}
+			w.secret = bytes.TrimSpace(secret)
+		default:
+			return nil, fmt.Errorf("Unknown webhook option %s", kv[0])
+		}
+	}
+	return w, nil
+}
+
+// eventType returns the type of an event: the events of the images are
+// logged with no image they come from.
+func eventType(event *utils.JSONMessage) string {
+	if event.From == "" {
+		return "image"
+	}
+	return "container"
+}
+
+func (w *webhook) match(event *utils.JSONMessage) bool {
+	if len(w.types) > 0 && !w.types[eventType(event)] {
+		return false
+	}
+	return len(w.events) == 0 || w.events[event.Status]
+}
+
+// signature returns the hex encoded HMAC-SHA256 of body with the secret of
+// the webhook, which the endpoint computes again to authenticate the event.
+func (w *webhook) signature(body []byte) string {
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// post sends an event to the endpoint, the same JSON as on /events. It
+// returns whether the delivery is worth retrying along with the error.
+func (w *webhook) post(event *utils.JSONMessage) (bool, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Docker-Event", event.Status)
+	if w.secret != nil {
+		req.Header.Set("X-Docker-Signature", "sha256="+w.signature(body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// the endpoint refusing the event won't change its mind
+		retry := resp.StatusCode >= 500 || resp.StatusCode == 429
+		return retry, fmt.Errorf("%s answered %s", w.url, resp.Status)
+	}
+	return false, nil
+}
+
+// deliver posts an event, retrying with an exponential backoff while the
+// endpoint is unreachable or failing.
+func (w *webhook) deliver(event *utils.JSONMessage) error {
+	delay := webhookRetryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(event)
+		if err == nil || !retry || attempt == webhookRetries {
+			return err
+		}
+		log.Debugf("Retrying to post the %s event of %s to %s in %s: %s", event.Status, event.ID, w.url, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Webhook posts the events matching the filters of the webhook given as
+// argument to its endpoint, for the lifetime of the daemon. The events are
+// queued while the endpoint is retried, and dropped once the queue is full.
+func (e *Events) Webhook(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s URL[,type=TYPE][,event=EVENT][,secret-file=FILE]", job.Name)
+	}
+	w, err := parseWebhook(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+
+	var (
+		listener = make(chan *utils.JSONMessage)
+		queue    = make(chan *utils.JSONMessage, eventsLimit)
+	)
+	e.subscribe(listener)
+	go func() {
+		for event := range listener {
+			if !w.match(event) {
+				continue
+			}
+			select {
+			case queue <- event:
+			default:
+				log.Errorf("Dropping the %s event of %s, %s is too far behind", event.Status, event.ID, w.url)
+			}
+		}
+		close(queue)
+	}()
+	go func() {
+		for event := range queue {
+			if err := w.deliver(event); err != nil {
+				log.Errorf("Cannot post the %s event of %s: %s", event.Status, event.ID, err)
+			}
+		}
+	}()
+	return engine.StatusOK
+}
@@ -18,11 +18,19 @@ import (
 // uncompressed tar ball.
 // name is the set of tags to export.
 // out is the writer where the images are written to.
+// format is docker (the default) or oci for an OCI image layout.
 func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 	if len(job.Args) != 1 {
 		return job.Errorf("Usage: %s IMAGE\n", job.Name)
 	}
 	name := job.Args[0]
+	switch format := job.Getenv("format"); format {
+	case "", "docker":
+	case "oci":
+		return s.imageExportOCI(job, name)
+	default:
+		return job.Errorf("Invalid export format %s, must be docker or oci", format)
+	}
 	// get image json
 	tempdir, err := ioutil.TempDir("", "docker-export-")
 	if err != nil {
@@ -104,39 +112,12 @@ func (s *TagStore) exportImage(eng *engine.Engine, name, tempdir string) error {
 			}
 			return err
 		}
-
-		var version = "1.0"
-		var versionBuf = []byte(version)
-
-		if err := ioutil.WriteFile(path.Join(tmpImageDir, "VERSION"), versionBuf, os.FileMode(0644)); err != nil {
-			return err
-		}
-
-		// serialize json
-		json, err := os.Create(path.Join(tmpImageDir, "json"))
-		if err != nil {
-			return err
-		}
-		job := eng.Job("image_inspect", n)
-		job.SetenvBool("raw", true)
-		job.Stdout.Add(json)
-		if err := job.Run(); err != nil {
-			return err
-		}
-
-		// serialize filesystem
-		fsTar, err := os.Create(path.Join(tmpImageDir, "layer.tar"))
-		if err != nil {
-			return err
-		}
-		job = eng.Job("image_tarlayer", n)
-		job.Stdout.Add(fsTar)
-		if err := job.Run(); err != nil {
+		if err := exportLayer(eng, n, tmpImageDir); err != nil {
 			return err
 		}
 
 		// find parent
-		job = eng.Job("image_get", n)
+		job := eng.Job("image_get", n)
 		info, _ := job.Stdout.AddEnv()
 		if err := job.Run(); err != nil {
 			return err
@@ -145,3 +126,37 @@ func (s *TagStore) exportImage(eng *engine.Engine, name, tempdir string) error {
 	}
 	return nil
 }
+
+// exportLayer writes the json and the layer of the image name alone in
+// tmpImageDir, as docker save lays them out.
+func exportLayer(eng *engine.Engine, name, tmpImageDir string) error {
+	var version = "1.0"
+	var versionBuf = []byte(version)
+
+	if err := ioutil.WriteFile(path.Join(tmpImageDir, "VERSION"), versionBuf, os.FileMode(0644)); err != nil {
+		return err
+	}
+
+	// serialize json
+	json, err := os.Create(path.Join(tmpImageDir, "json"))
+	if err != nil {
+		return err
+	}
+	defer json.Close()
+	job := eng.Job("image_inspect", name)
+	job.SetenvBool("raw", true)
+	job.Stdout.Add(json)
+	if err := job.Run(); err != nil {
+		return err
+	}
+
+	// serialize filesystem
+	fsTar, err := os.Create(path.Join(tmpImageDir, "layer.tar"))
+	if err != nil {
+		return err
+	}
+	defer fsTar.Close()
+	job = eng.Job("image_tarlayer", name)
+	job.Stdout.Add(fsTar)
+	return job.Run()
+}
@@ -0,0 +1,315 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/parsers"
+)
+
+// Media types of the OCI image layout written by an export in the oci format.
+const (
+	ociLayoutVersion   = "1.0.0"
+	ociIndexMediaType  = "application/vnd.oci.image.index.v1+json"
+	ociManifestType    = "application/vnd.oci.image.manifest.v1+json"
+	ociConfigMediaType = "application/vnd.oci.image.config.v1+json"
+	ociLayerMediaType  = "application/vnd.oci.image.layer.v1.tar"
+	ociRefNameKey      = "org.opencontainers.image.ref.name"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociImageConfig struct {
+	Created      time.Time        `json:"created"`
+	Author       string           `json:"author,omitempty"`
+	Architecture string           `json:"architecture"`
+	OS           string           `json:"os"`
+	Config       ociRuntimeConfig `json:"config"`
+	RootFS       ociRootFS        `json:"rootfs"`
+	History      []ociHistory     `json:"history"`
+}
+
+type ociRuntimeConfig struct {
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+}
+
+type ociRootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+type ociHistory struct {
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// ociRef is an exported image along with the name it is tagged with, if any.
+type ociRef struct {
+	name string
+	id   string
+}
+
+// imageExportOCI is CmdImageExport for the oci format: the images are
+// written as an OCI image layout, with content-addressed blobs for the
+// layers, configs and manifests and an index of the tags.
+func (s *TagStore) imageExportOCI(job *engine.Job, name string) engine.Status {
+	tempdir, err := ioutil.TempDir("", "docker-export-")
+	if err != nil {
+		return job.Error(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	log.Debugf("Serializing %s in the oci format", name)
+	if err := s.exportOCI(name, tempdir); err != nil {
+		return job.Error(err)
+	}
+
+	fs, err := archive.Tar(tempdir, archive.Uncompressed)
+	if err != nil {
+		return job.Error(err)
+	}
+	defer fs.Close()
+
+	if _, err := io.Copy(job.Stdout, fs); err != nil {
+		return job.Error(err)
+	}
+	log.Debugf("End Serializing %s", name)
+	return engine.StatusOK
+}
+
+// exportOCI writes the OCI image layout of the images named name into dir.
+func (s *TagStore) exportOCI(name, dir string) error {
+	refs, err := s.ociRefs(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Join(dir, "blobs", "sha256"), 0755); err != nil {
+		return err
+	}
+
+	index := ociIndex{
+		SchemaVersion: 2,
+		MediaType:     ociIndexMediaType,
+		Manifests:     []ociDescriptor{},
+	}
+	manifests := make(map[string]ociDescriptor)
+	for _, ref := range refs {
+		desc, exists := manifests[ref.id]
+		if !exists {
+			img, err := s.graph.Get(ref.id)
+			if err != nil {
+				return err
+			}
+			if img == nil {
+				return fmt.Errorf("No such image: %s", ref.id)
+			}
+			if desc, err = exportOCIManifest(img, dir); err != nil {
+				return err
+			}
+			manifests[ref.id] = desc
+		}
+		if ref.name != "" {
+			desc.Annotations = map[string]string{ociRefNameKey: ref.name}
+		}
+		index.Manifests = append(index.Manifests, desc)
+	}
+
+	indexJson, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "index.json"), indexJson, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"`+ociLayoutVersion+`"}`), 0644)
+}
+
+// ociRefs resolves name the same way the docker format does: a repository
+// exports all its tags, a tag or an ID the image it points to.
+func (s *TagStore) ociRefs(name string) ([]ociRef, error) {
+	repo, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if repo != nil {
+		var tags []string
+		for tag := range repo {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		var refs []ociRef
+		for _, tag := range tags {
+			refs = append(refs, ociRef{name: name + ":" + tag, id: repo[tag]})
+		}
+		return refs, nil
+	}
+
+	img, err := s.LookupImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		return nil, fmt.Errorf("No such image: %s", name)
+	}
+	// a lookup by ID has no tag to name the image after
+	if _, tag := parsers.ParseRepositoryTag(name); tag != "" {
+		return []ociRef{{name: name, id: img.ID}}, nil
+	}
+	return []ociRef{{id: img.ID}}, nil
+}
+
+// exportOCIManifest writes the layers, the config and the manifest of img
+// into the blobs of dir and returns the descriptor of the manifest.
+func exportOCIManifest(img *image.Image, dir string) (ociDescriptor, error) {
+	history, err := img.History()
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+
+	config := ociImageConfig{
+		Created:      img.Created,
+		Author:       img.Author,
+		Architecture: img.Architecture,
+		OS:           img.OS,
+		RootFS:       ociRootFS{Type: "layers"},
+	}
+	if config.Architecture == "" {
+		config.Architecture = "amd64"
+	}
+	if config.OS == "" {
+		config.OS = "linux"
+	}
+	if c := img.Config; c != nil {
+		config.Config = ociRuntimeConfig{
+			User:       c.User,
+			Env:        c.Env,
+			Entrypoint: c.Entrypoint,
+			Cmd:        c.Cmd,
+			Volumes:    c.Volumes,
+			WorkingDir: c.WorkingDir,
+		}
+		if len(c.ExposedPorts) > 0 {
+			config.Config.ExposedPorts = make(map[string]struct{})
+			for port := range c.ExposedPorts {
+				config.Config.ExposedPorts[string(port)] = struct{}{}
+			}
+		}
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestType,
+		Layers:        []ociDescriptor{},
+	}
+	// the history goes from img to its base, the layers from the base up
+	for i := len(history) - 1; i >= 0; i-- {
+		layer := history[i]
+		layerData, err := layer.TarLayer()
+		if err != nil {
+			return ociDescriptor{}, err
+		}
+		desc, err := writeOCIBlob(dir, ociLayerMediaType, layerData)
+		layerData.Close()
+		if err != nil {
+			return ociDescriptor{}, err
+		}
+		// the layers are not compressed, their digest is their diff ID
+		manifest.Layers = append(manifest.Layers, desc)
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, desc.Digest)
+		config.History = append(config.History, ociHistory{
+			Created:   layer.Created,
+			CreatedBy: strings.Join(layer.ContainerConfig.Cmd, " "),
+			Author:    layer.Author,
+			Comment:   layer.Comment,
+		})
+	}
+
+	if manifest.Config, err = writeOCIJson(dir, ociConfigMediaType, config); err != nil {
+		return ociDescriptor{}, err
+	}
+	return writeOCIJson(dir, ociManifestType, manifest)
+}
+
+func writeOCIJson(dir, mediaType string, v interface{}) (ociDescriptor, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	return writeOCIBlob(dir, mediaType, bytes.NewReader(data))
+}
+
+// writeOCIBlob stores the content of r in the blobs of dir under its
+// digest. A blob which is already there, such as a layer shared by two
+// exported images, is replaced by the same content.
+func writeOCIBlob(dir, mediaType string, r io.Reader) (ociDescriptor, error) {
+	blobs := path.Join(dir, "blobs", "sha256")
+	f, err := ioutil.TempFile(blobs, ".tmp-")
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		f.Close()
+		return ociDescriptor{}, err
+	}
+	if err := f.Close(); err != nil {
+		return ociDescriptor{}, err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(f.Name(), path.Join(blobs, sum)); err != nil {
+		return ociDescriptor{}, err
+	}
+	if err := os.Chmod(path.Join(blobs, sum), 0644); err != nil {
+		return ociDescriptor{}, err
+	}
+	return ociDescriptor{
+		MediaType: mediaType,
+		Digest:    "sha256:" + sum,
+		Size:      size,
+	}, nil
+}
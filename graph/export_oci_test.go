@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/utils"
+)
+
+func readOCIBlob(t *testing.T, dir string, desc ociDescriptor) []byte {
+	data, err := ioutil.ReadFile(path.Join(dir, "blobs", "sha256", strings.TrimPrefix(desc.Digest, "sha256:")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if digest := "sha256:" + hex.EncodeToString(sum[:]); digest != desc.Digest || int64(len(data)) != desc.Size {
+		t.Fatalf("Expected blob %s of %d bytes, got %s of %d bytes", desc.Digest, desc.Size, digest, len(data))
+	}
+	return data
+}
+
+func TestExportOCI(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	dir := path.Join(tmp, "oci")
+	if err := store.exportOCI(testImageName, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path.Join(dir, "oci-layout")); err != nil {
+		t.Fatal(err)
+	}
+	indexJson, err := ioutil.ReadFile(path.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index ociIndex
+	if err := json.Unmarshal(indexJson, &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 1 {
+		t.Fatalf("Expected 1 manifest, got %d", len(index.Manifests))
+	}
+	if ref := index.Manifests[0].Annotations[ociRefNameKey]; ref != testImageName+":"+DEFAULTTAG {
+		t.Fatalf("Expected the manifest to be named %s:%s, got %s", testImageName, DEFAULTTAG, ref)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(readOCIBlob(t, dir, index.Manifests[0]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Layers) != 1 {
+		t.Fatalf("Expected 1 layer, got %d", len(manifest.Layers))
+	}
+	readOCIBlob(t, dir, manifest.Layers[0])
+
+	var config ociImageConfig
+	if err := json.Unmarshal(readOCIBlob(t, dir, manifest.Config), &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.RootFS.DiffIDs) != 1 || config.RootFS.DiffIDs[0] != manifest.Layers[0].Digest {
+		t.Fatalf("Expected the diff IDs to be the digests of the layers, got %v", config.RootFS.DiffIDs)
+	}
+
+	if err := store.exportOCI(testImageID, path.Join(tmp, "oci-id")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -248,6 +248,16 @@ func (graph *Graph) Mktemp(id string) (string, error) {
 	return dir, nil
 }
 
+// PartialLayerPath returns the path of the file an interrupted download of
+// the layer of id is kept in, so the next pull can resume it.
+func (graph *Graph) PartialLayerPath(id string) (string, error) {
+	dir := path.Join(graph.Root, "_partial")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return path.Join(dir, id), nil
+}
+
 // setupInitLayer populates a directory with mountpoints suitable
 // for bind-mounting dockerinit into the container. The mountpoint is simply an
 // empty file at /.dockerinit
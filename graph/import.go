@@ -6,6 +6,7 @@ import (
 
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/engine"
+	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
 )
 
@@ -20,10 +21,23 @@ func (s *TagStore) CmdImport(job *engine.Job) engine.Status {
 		sf      = utils.NewStreamFormatter(job.GetenvBool("json"))
 		archive archive.ArchiveReader
 		resp    *http.Response
+		config  *runconfig.Config
+		format  = job.Getenv("format")
 	)
 	if len(job.Args) > 2 {
 		tag = job.Args[2]
 	}
+	if format != "" && format != "rootfs" && format != "aci" {
+		return job.Errorf("Invalid import format %s, must be rootfs or aci", format)
+	}
+	// the config given with the image takes precedence over the
+	// one of the image manifest
+	if job.Getenv("config") != "" || format == "aci" {
+		config = &runconfig.Config{}
+		if err := job.GetenvJson("config", config); err != nil {
+			return job.Errorf("Invalid image config: %s", err)
+		}
+	}
 
 	if src == "-" {
 		archive = job.Stdin
@@ -46,7 +60,14 @@ func (s *TagStore) CmdImport(job *engine.Job) engine.Status {
 		defer progressReader.Close()
 		archive = progressReader
 	}
-	img, err := s.graph.Create(archive, "", "", "Imported from "+src, "", nil, nil)
+	if format == "aci" {
+		layer, err := aciLayer(archive, config)
+		if err != nil {
+			return job.Error(err)
+		}
+		archive = layer
+	}
+	img, err := s.graph.Create(archive, "", "", "Imported from "+src, "", nil, config)
 	if err != nil {
 		return job.Error(err)
 	}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
+)
+
+// aciManifest is the part of the image manifest of an App Container Image
+// which maps to the config of a docker image.
+type aciManifest struct {
+	ACKind string `json:"acKind"`
+	App    *struct {
+		Exec             []string `json:"exec"`
+		User             string   `json:"user"`
+		Group            string   `json:"group"`
+		WorkingDirectory string   `json:"workingDirectory"`
+		Environment      []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"environment"`
+		Ports []struct {
+			Name     string `json:"name"`
+			Protocol string `json:"protocol"`
+			Port     int    `json:"port"`
+		} `json:"ports"`
+		MountPoints []struct {
+			Path string `json:"path"`
+		} `json:"mountPoints"`
+	} `json:"app"`
+}
+
+// config returns the docker config of the app of the image.
+func (m *aciManifest) config() (*runconfig.Config, error) {
+	config := &runconfig.Config{}
+	if m.App == nil {
+		return config, nil
+	}
+	config.Cmd = m.App.Exec
+	config.User = m.App.User
+	if m.App.Group != "" {
+		config.User += ":" + m.App.Group
+	}
+	config.WorkingDir = m.App.WorkingDirectory
+	for _, env := range m.App.Environment {
+		config.Env = append(config.Env, env.Name+"="+env.Value)
+	}
+	for _, p := range m.App.Ports {
+		if p.Protocol != "tcp" && p.Protocol != "udp" {
+			return nil, fmt.Errorf("Invalid protocol %s of the port %s of the image manifest, must be tcp or udp", p.Protocol, p.Name)
+		}
+		if config.ExposedPorts == nil {
+			config.ExposedPorts = make(nat.PortSet)
+		}
+		config.ExposedPorts[nat.NewPort(p.Protocol, strconv.Itoa(p.Port))] = struct{}{}
+	}
+	for _, mount := range m.App.MountPoints {
+		if config.Volumes == nil {
+			config.Volumes = make(map[string]struct{})
+		}
+		config.Volumes[mount.Path] = struct{}{}
+	}
+	return config, nil
+}
+
+// aciLayer converts the App Container Image read from r into the layer of a
+// docker image: the files under rootfs/ are moved to the root of the layer
+// and the manifest is merged into config, the values already in config
+// taking precedence. config is complete once the layer is read to its end,
+// which is before the image is stored.
+func aciLayer(r io.Reader, config *runconfig.Config) (archive.ArchiveReader, error) {
+	decompressed, err := archive.DecompressStream(r)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer decompressed.Close()
+		pw.CloseWithError(convertACI(tar.NewReader(decompressed), tar.NewWriter(pw), config))
+	}()
+	return pr, nil
+}
+
+func convertACI(tr *tar.Reader, tw *tar.Writer, config *runconfig.Config) error {
+	var manifest *aciManifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		switch {
+		case name == "manifest":
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			manifest = &aciManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return fmt.Errorf("Invalid image manifest: %s", err)
+			}
+			if manifest.ACKind != "ImageManifest" {
+				return fmt.Errorf("Invalid image manifest kind %s, expected ImageManifest", manifest.ACKind)
+			}
+		case strings.HasPrefix(name, "rootfs/"):
+			hdr.Name = strings.TrimPrefix(name, "rootfs/")
+			if hdr.Typeflag == tar.TypeLink {
+				// hard links point to another file of the rootfs
+				hdr.Linkname = strings.TrimPrefix(path.Clean(strings.TrimPrefix(hdr.Linkname, "./")), "rootfs/")
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+		}
+		// the rootfs directory itself and any other file are not part of
+		// the image filesystem
+	}
+	if manifest == nil {
+		return fmt.Errorf("Invalid App Container Image, it has no manifest")
+	}
+
+	aciConfig, err := manifest.config()
+	if err != nil {
+		return err
+	}
+	if err := runconfig.Merge(config, aciConfig); err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
+)
+
+const testACIManifest = `{
+	"acKind": "ImageManifest",
+	"acVersion": "0.1.1",
+	"name": "example.com/hello",
+	"app": {
+		"exec": ["/bin/hello", "--port", "80"],
+		"user": "0",
+		"group": "0",
+		"environment": [{"name": "GREETING", "value": "hello"}, {"name": "NAME", "value": "aci"}],
+		"ports": [{"name": "http", "protocol": "tcp", "port": 80}],
+		"mountPoints": [{"name": "data", "path": "/data"}]
+	}
+}`
+
+func fakeACI(t *testing.T) io.Reader {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, file := range []struct {
+		name     string
+		typeflag byte
+		content  string
+	}{
+		{"manifest", tar.TypeReg, testACIManifest},
+		{"rootfs/", tar.TypeDir, ""},
+		{"rootfs/bin/", tar.TypeDir, ""},
+		{"rootfs/bin/hello", tar.TypeReg, "#!/bin/sh\n"},
+	} {
+		hdr := &tar.Header{Name: file.name, Typeflag: file.typeflag, Mode: 0755, Size: int64(len(file.content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestACILayer(t *testing.T) {
+	config := &runconfig.Config{Env: []string{"NAME=docker"}}
+	layer, err := aciLayer(fakeACI(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	tr := tar.NewReader(layer)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if len(names) != 2 || names[0] != "bin" || names[1] != "bin/hello" {
+		t.Fatalf("Expected the rootfs at the root of the layer, got %v", names)
+	}
+
+	if len(config.Cmd) != 3 || config.Cmd[0] != "/bin/hello" {
+		t.Fatalf("Expected the exec of the manifest as Cmd, got %v", config.Cmd)
+	}
+	if config.User != "0:0" {
+		t.Fatalf("Expected the user 0:0, got %s", config.User)
+	}
+	if _, exists := config.ExposedPorts[nat.NewPort("tcp", "80")]; !exists {
+		t.Fatalf("Expected port 80/tcp to be exposed, got %v", config.ExposedPorts)
+	}
+	if _, exists := config.Volumes["/data"]; !exists {
+		t.Fatalf("Expected a volume on /data, got %v", config.Volumes)
+	}
+	if len(config.Env) != 2 || config.Env[0] != "NAME=docker" || config.Env[1] != "GREETING=hello" {
+		t.Fatalf("Expected the given env to take precedence over the manifest, got %v", config.Env)
+	}
+}
+
+func TestACILayerWithoutManifest(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	layer, err := aciLayer(buf, &runconfig.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(new(bytes.Buffer), layer); err == nil {
+		t.Fatal("Expected an image without manifest to be rejected")
+	}
+}
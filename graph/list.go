@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"path"
+	"sort"
 	"strings"
 
 	"github.com/docker/docker/engine"
@@ -16,7 +17,12 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 		allImages   map[string]*image.Image
 		err         error
 		filt_tagged = true
+		offset      = job.GetenvInt("offset")
+		limit       = job.GetenvInt("limit")
 	)
+	if offset < 0 {
+		return job.Errorf("Bad parameter, invalid offset %d", offset)
+	}
 
 	imageFilters, err := filters.FromParam(job.Getenv("filters"))
 	if err != nil {
@@ -95,9 +101,32 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 		}
 	}
 
-	outs.ReverseSort()
+	sort.Sort(sort.Reverse(imagesByCreated{outs}))
+	// a page of the listing is limit images, offset images after the newest
+	if offset > len(outs.Data) {
+		offset = len(outs.Data)
+	}
+	outs.Data = outs.Data[offset:]
+	if limit > 0 && limit < len(outs.Data) {
+		outs.Data = outs.Data[:limit]
+	}
 	if _, err := outs.WriteListTo(job.Stdout); err != nil {
 		return job.Error(err)
 	}
 	return engine.StatusOK
 }
+
+// imagesByCreated sorts the images by their creation date, and by their id
+// when created in the same second, for the pages of a listing to be the same
+// from a request to the next.
+type imagesByCreated struct {
+	*engine.Table
+}
+
+func (images imagesByCreated) Less(a, b int) bool {
+	createdA, createdB := images.Data[a].GetInt64("Created"), images.Data[b].GetInt64("Created")
+	if createdA != createdB {
+		return createdA < createdB
+	}
+	return images.Data[a].Get("Id") < images.Data[b].Get("Id")
+}
@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/dockerversion"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+// CmdOnBuild lists the ONBUILD triggers of an image, the instructions run
+// by the builds starting FROM it.
+func (s *TagStore) CmdOnBuild(job *engine.Job) engine.Status {
+	if n := len(job.Args); n != 1 {
+		return job.Errorf("Usage: %s IMAGE", job.Name)
+	}
+	name := job.Args[0]
+	img, err := s.LookupImage(name)
+	if err != nil {
+		return job.Error(err)
+	}
+	if img == nil {
+		return job.Errorf("No such image: %s", name)
+	}
+
+	outs := engine.NewTable("", 0)
+	if img.Config != nil {
+		for _, trigger := range img.Config.OnBuild {
+			out := &engine.Env{}
+			out.Set("Trigger", trigger)
+			outs.Add(out)
+		}
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// CmdOnBuildSet changes the ONBUILD triggers of an image. Images can't be
+// modified, so a new image is created on top of it with an empty layer and
+// the changed triggers, and optionally tagged as REPO:TAG.
+//
+// Syntax: image_onbuild_set IMAGE [REPO [TAG]]
+// Input: 'strip' removes the triggers the image already has, 'add' is a list
+// of triggers appended after them.
+func (s *TagStore) CmdOnBuildSet(job *engine.Job) engine.Status {
+	if n := len(job.Args); n < 1 || n > 3 {
+		return job.Errorf("Usage: %s IMAGE [REPO [TAG]]", job.Name)
+	}
+	var (
+		name = job.Args[0]
+		repo string
+		tag  string
+	)
+	if len(job.Args) > 1 {
+		repo = job.Args[1]
+	}
+	if len(job.Args) > 2 {
+		tag = job.Args[2]
+	}
+
+	img, err := s.setOnBuild(name, job.GetenvBool("strip"), job.GetenvList("add"))
+	if err != nil {
+		return job.Error(err)
+	}
+	if repo != "" {
+		if err := s.Set(repo, tag, img.ID, true); err != nil {
+			return job.Error(err)
+		}
+	}
+	job.Printf("%s\n", img.ID)
+	return engine.StatusOK
+}
+
+// setOnBuild creates a child of the image name with its triggers stripped
+// if strip is set and the triggers add appended.
+func (s *TagStore) setOnBuild(name string, strip bool, add []string) (*image.Image, error) {
+	for _, trigger := range add {
+		if err := validateOnBuildTrigger(trigger); err != nil {
+			return nil, err
+		}
+	}
+	if !strip && len(add) == 0 {
+		return nil, fmt.Errorf("Nothing to change, no trigger to add nor to strip")
+	}
+
+	parent, err := s.LookupImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("No such image: %s", name)
+	}
+
+	config := &runconfig.Config{}
+	if parent.Config != nil {
+		*config = *parent.Config
+	}
+	triggers := []string{}
+	if !strip {
+		triggers = append(triggers, config.OnBuild...)
+	}
+	config.OnBuild = append(triggers, add...)
+
+	// the history shows the change the same way as a build step
+	var changes []string
+	if strip {
+		changes = append(changes, "strip")
+	}
+	changes = append(changes, add...)
+	containerConfig := *config
+	containerConfig.Cmd = []string{"/bin/sh", "-c", "#(nop) ONBUILD " + strings.Join(changes, "; ")}
+
+	img := &image.Image{
+		ID:              utils.GenerateRandomID(),
+		Parent:          parent.ID,
+		Comment:         "Changed the ONBUILD triggers",
+		Created:         time.Now().UTC(),
+		DockerVersion:   dockerversion.VERSION,
+		Author:          parent.Author,
+		ContainerConfig: containerConfig,
+		Config:          config,
+		Architecture:    runtime.GOARCH,
+		OS:              runtime.GOOS,
+	}
+	if err := s.graph.Register(nil, nil, img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// validateOnBuildTrigger applies the rules of the ONBUILD instruction of the
+// builder to trigger.
+func validateOnBuildTrigger(trigger string) error {
+	instruction := strings.ToUpper(strings.SplitN(strings.TrimSpace(trigger), " ", 2)[0])
+	switch instruction {
+	case "":
+		return fmt.Errorf("Invalid empty ONBUILD trigger")
+	case "ONBUILD":
+		return fmt.Errorf("Chaining ONBUILD via `ONBUILD ONBUILD` isn't allowed")
+	case "MAINTAINER", "FROM":
+		return fmt.Errorf("%s isn't allowed as an ONBUILD trigger", instruction)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/utils"
+)
+
+func TestSetOnBuild(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	img, err := store.setOnBuild(testImageName, false, []string{"ADD . /app", "RUN make"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Parent != testImageID {
+		t.Fatalf("Expected the image to be a child of %s, got %s", testImageID, img.Parent)
+	}
+	if expected := []string{"ADD . /app", "RUN make"}; !reflect.DeepEqual(img.Config.OnBuild, expected) {
+		t.Fatalf("Expected the triggers %v, got %v", expected, img.Config.OnBuild)
+	}
+
+	child, err := store.setOnBuild(img.ID, false, []string{"RUN make test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"ADD . /app", "RUN make", "RUN make test"}; !reflect.DeepEqual(child.Config.OnBuild, expected) {
+		t.Fatalf("Expected the triggers %v, got %v", expected, child.Config.OnBuild)
+	}
+	// the image the new one is created from is left as it was
+	if parent, err := store.graph.Get(img.ID); err != nil {
+		t.Fatal(err)
+	} else if len(parent.Config.OnBuild) != 2 {
+		t.Fatalf("Expected the parent image to keep its 2 triggers, got %v", parent.Config.OnBuild)
+	}
+
+	stripped, err := store.setOnBuild(child.ID, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stripped.Config.OnBuild) != 0 {
+		t.Fatalf("Expected no trigger, got %v", stripped.Config.OnBuild)
+	}
+
+	if _, err := store.setOnBuild(testImageName, false, nil); err == nil {
+		t.Fatal("Expected an error when there is nothing to change")
+	}
+	if _, err := store.setOnBuild("fail", false, []string{"RUN make"}); err == nil {
+		t.Fatal("Expected an error for a missing image")
+	}
+}
+
+func TestValidateOnBuildTrigger(t *testing.T) {
+	for _, trigger := range []string{"RUN make", "add . /app", "ENV A=1"} {
+		if err := validateOnBuildTrigger(trigger); err != nil {
+			t.Errorf("Expected %q to be valid: %s", trigger, err)
+		}
+	}
+	for _, trigger := range []string{"", "  ", "ONBUILD RUN make", "from busybox", "MAINTAINER me"} {
+		if err := validateOnBuildTrigger(trigger); err == nil {
+			t.Errorf("Expected %q to be invalid", trigger)
+		}
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"io"
 	"net"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/registry"
 	"github.com/docker/docker/utils"
 )
@@ -267,7 +269,7 @@ func (s *TagStore) pullImage(r *registry.Session, out io.Writer, imgID, endpoint
 					status = fmt.Sprintf("Pulling fs layer [retries: %d]", j)
 				}
 				out.Write(sf.FormatProgress(utils.TruncateID(id), status, nil))
-				layer, err := r.GetRemoteImageLayer(img.ID, endpoint, token, int64(imgSize))
+				layer, err := s.downloadLayer(r, out, sf, img.ID, endpoint, token, imgSize)
 				if uerr, ok := err.(*url.Error); ok {
 					err = uerr.Err
 				}
@@ -278,20 +280,17 @@ func (s *TagStore) pullImage(r *registry.Session, out io.Writer, imgID, endpoint
 					out.Write(sf.FormatProgress(utils.TruncateID(id), "Error pulling dependent layers", nil))
 					return err
 				}
-				defer layer.Close()
 
-				err = s.graph.Register(imgJSON,
-					utils.ProgressReader(layer, imgSize, out, sf, false, utils.TruncateID(id), "Downloading"),
-					img)
-				if terr, ok := err.(net.Error); ok && terr.Timeout() && j < retries {
-					time.Sleep(time.Duration(j) * 500 * time.Millisecond)
-					continue
-				} else if err != nil {
+				err = s.graph.Register(imgJSON, layer, img)
+				layer.Close()
+				// The download is complete, it will not be resumed even if
+				// it turned out to be corrupted
+				os.Remove(layer.Name())
+				if err != nil {
 					out.Write(sf.FormatProgress(utils.TruncateID(id), "Error downloading dependent layers", nil))
 					return err
-				} else {
-					break
 				}
+				break
 			}
 		}
 		out.Write(sf.FormatProgress(utils.TruncateID(id), "Download complete", nil))
@@ -299,3 +298,60 @@ func (s *TagStore) pullImage(r *registry.Session, out io.Writer, imgID, endpoint
 	}
 	return nil
 }
+
+// downloadLayer fetches the layer of imgID into a partial file of the graph
+// and returns it ready to be read. The file is kept when the download is
+// interrupted, and the next pull of the layer only fetches the missing part
+// if the registry supports range requests.
+func (s *TagStore) downloadLayer(r *registry.Session, out io.Writer, sf *utils.StreamFormatter, imgID, endpoint string, token []string, imgSize int) (*os.File, error) {
+	partial, err := s.graph.PartialLayerPath(imgID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Only resume when the size is known, a bigger file can't be a part
+	// of the layer either
+	offset := fi.Size()
+	if imgSize <= 0 || offset > int64(imgSize) {
+		offset = 0
+	}
+	if imgSize <= 0 || offset < int64(imgSize) {
+		layer, start, err := r.GetRemoteImageLayerFrom(imgID, endpoint, token, int64(imgSize), offset)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		defer layer.Close()
+
+		if start > 0 {
+			out.Write(sf.FormatProgress(utils.TruncateID(imgID), fmt.Sprintf("Resuming download at %s", units.HumanSize(start)), nil))
+		}
+		if err := f.Truncate(start); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if _, err := f.Seek(start, 0); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if _, err := io.Copy(f, utils.ProgressReader(layer, imgSize-int(start), out, sf, false, utils.TruncateID(imgID), "Downloading")); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	if _, err := f.Seek(0, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,270 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
+)
+
+// validDigest is the form of the digests of the blobs served by the
+// registry, which are also their file names.
+var validDigest = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// RegistryServer serves the images of a TagStore read-only over the v2
+// registry protocol, for the other hosts to pull them without a registry.
+// The images are served as OCI manifests with uncompressed layers, written
+// as docker save --format oci does into the blobs of dir the first time
+// their manifest is asked for, and pruned once the image is deleted.
+type RegistryServer struct {
+	store *TagStore
+	dir   string
+	sync.Mutex
+	// the manifests written or being written, by image ID
+	manifests map[string]*registryManifest
+	// the manifests are written into the blobs concurrently, a prune
+	// removes blobs alone
+	blobs sync.RWMutex
+}
+
+// registryManifest is the manifest of an image, written once while it is
+// locked, along with the digests of the blobs it refers to.
+type registryManifest struct {
+	sync.Mutex
+	desc    ociDescriptor
+	written bool
+	// guarded by the server, for a prune not to wait on the writes
+	blobs []string
+}
+
+// RegistryHandler returns the handler of the v2 registry protocol serving
+// the images of the store, with dir to keep their blobs in. The blobs left
+// in dir by a previous daemon are removed.
+func (s *TagStore) RegistryHandler(dir string) (*RegistryServer, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(path.Join(dir, "blobs", "sha256"), 0700); err != nil {
+		return nil, err
+	}
+	return &RegistryServer{
+		store:     s,
+		dir:       dir,
+		manifests: make(map[string]*registryManifest),
+	}, nil
+}
+
+// registryError is an error answered in the format of the v2 registry
+// protocol.
+type registryError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r *RegistryServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	if req.Method != "GET" && req.Method != "HEAD" {
+		r.error(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "The registry of the daemon is read-only")
+		return
+	}
+	p := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if p == req.URL.Path {
+		r.error(w, http.StatusNotFound, "NAME_UNKNOWN", "Not a v2 registry path")
+		return
+	}
+	switch {
+	case p == "":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	case p == "_catalog":
+		r.serveCatalog(w)
+	case strings.HasSuffix(p, "/tags/list"):
+		r.serveTags(w, strings.TrimSuffix(p, "/tags/list"))
+	case strings.Contains(p, "/manifests/"):
+		i := strings.LastIndex(p, "/manifests/")
+		r.serveManifest(w, req, p[:i], p[i+len("/manifests/"):])
+	case strings.Contains(p, "/blobs/"):
+		r.serveBlob(w, req, p[strings.LastIndex(p, "/blobs/")+len("/blobs/"):], "application/octet-stream")
+	default:
+		r.error(w, http.StatusNotFound, "NAME_UNKNOWN", "Not a v2 registry path")
+	}
+}
+
+func (r *RegistryServer) error(w http.ResponseWriter, code int, errorCode, format string, a ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string][]registryError{
+		"errors": {{Code: errorCode, Message: fmt.Sprintf(format, a...)}},
+	})
+}
+
+func (r *RegistryServer) serveCatalog(w http.ResponseWriter) {
+	r.store.Lock()
+	names := []string{}
+	for name := range r.store.Repositories {
+		names = append(names, name)
+	}
+	r.store.Unlock()
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"repositories": names})
+}
+
+func (r *RegistryServer) serveTags(w http.ResponseWriter, name string) {
+	repo, err := r.store.Get(name)
+	if err != nil || repo == nil {
+		r.error(w, http.StatusNotFound, "NAME_UNKNOWN", "No such repository: %s", name)
+		return
+	}
+	tags := []string{}
+	for tag := range repo {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}{name, tags})
+}
+
+// serveManifest serves the manifest of the image tagged reference in the
+// repository name, or the manifest of digest reference once written.
+func (r *RegistryServer) serveManifest(w http.ResponseWriter, req *http.Request, name, reference string) {
+	if validDigest.MatchString(reference) {
+		r.serveBlob(w, req, reference, ociManifestType)
+		return
+	}
+	img, err := r.store.GetImage(name, reference)
+	if err != nil || img == nil {
+		r.error(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "No such image: %s:%s", name, reference)
+		return
+	}
+
+	desc, err := r.writeManifest(img)
+	if err != nil {
+		r.error(w, http.StatusInternalServerError, "UNKNOWN", "Error writing the manifest of %s:%s: %s", name, reference, err)
+		return
+	}
+	r.serveBlob(w, req, desc.Digest, ociManifestType)
+}
+
+// writeManifest returns the descriptor of the manifest of img, writing it and
+// its blobs the first time. The manifests of different images are written
+// concurrently.
+func (r *RegistryServer) writeManifest(img *image.Image) (ociDescriptor, error) {
+	r.Lock()
+	m, exists := r.manifests[img.ID]
+	if !exists {
+		m = &registryManifest{}
+		r.manifests[img.ID] = m
+	}
+	r.Unlock()
+
+	m.Lock()
+	defer m.Unlock()
+	if m.written {
+		return m.desc, nil
+	}
+	r.blobs.RLock()
+	defer r.blobs.RUnlock()
+	log.Debugf("Writing the manifest of %s for the registry", img.ID)
+	desc, err := exportOCIManifest(img, r.dir)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	blobs, err := r.manifestBlobs(desc)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	m.desc, m.written = desc, true
+	r.Lock()
+	m.blobs = blobs
+	r.Unlock()
+	return desc, nil
+}
+
+// manifestBlobs returns the digests of the manifest desc, of its config and
+// of its layers.
+func (r *RegistryServer) manifestBlobs(desc ociDescriptor) ([]string, error) {
+	f, err := os.Open(r.blobPath(desc.Digest))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var manifest ociManifest
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	blobs := []string{desc.Digest, manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	return blobs, nil
+}
+
+// Prune drops the manifest of the deleted image id, along with the blobs no
+// other manifest refers to, such as its top layers.
+func (r *RegistryServer) Prune(id string) {
+	r.blobs.Lock()
+	defer r.blobs.Unlock()
+	r.Lock()
+	m, exists := r.manifests[id]
+	delete(r.manifests, id)
+	kept := make(map[string]bool)
+	for _, other := range r.manifests {
+		for _, digest := range other.blobs {
+			kept[digest] = true
+		}
+	}
+	var blobs []string
+	if exists {
+		blobs = m.blobs
+	}
+	r.Unlock()
+	for _, digest := range blobs {
+		if kept[digest] {
+			continue
+		}
+		if err := os.Remove(r.blobPath(digest)); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Error pruning the blob %s of %s from the registry: %s", digest, id, err)
+		}
+	}
+}
+
+func (r *RegistryServer) blobPath(digest string) string {
+	return path.Join(r.dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+// serveBlob serves the blob digest, written to the blobs of the registry
+// along with the manifest of an image.
+func (r *RegistryServer) serveBlob(w http.ResponseWriter, req *http.Request, digest, mediaType string) {
+	if !validDigest.MatchString(digest) {
+		r.error(w, http.StatusBadRequest, "DIGEST_INVALID", "Invalid digest %s", digest)
+		return
+	}
+	f, err := os.Open(r.blobPath(digest))
+	if err != nil {
+		r.error(w, http.StatusNotFound, "BLOB_UNKNOWN", "No such blob: %s", digest)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		r.error(w, http.StatusInternalServerError, "UNKNOWN", "%s", err)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Etag", `"`+digest+`"`)
+	// the blobs are immutable, ServeContent answers their ranges and HEAD
+	http.ServeContent(w, req, "", fi.ModTime(), f)
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/utils"
+)
+
+func registryRequest(t *testing.T, handler http.Handler, method, url string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRecorder()
+	handler.ServeHTTP(r, req)
+	return r
+}
+
+func TestRegistryHandler(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+	handler, err := store.RegistryHandler(path.Join(tmp, "registry"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := registryRequest(t, handler, "GET", "/v2/")
+	if r.Code != http.StatusOK || r.Header().Get("Docker-Distribution-API-Version") != "registry/2.0" {
+		t.Fatalf("Expected a v2 registry, got %d %v", r.Code, r.Header())
+	}
+
+	r = registryRequest(t, handler, "GET", "/v2/"+testImageName+"/tags/list")
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags.Tags) != 1 || tags.Tags[0] != DEFAULTTAG {
+		t.Fatalf("Expected the tag %s, got %v", DEFAULTTAG, tags.Tags)
+	}
+
+	// the blobs are only there once the manifest was asked for
+	r = registryRequest(t, handler, "GET", "/v2/"+testImageName+"/manifests/"+DEFAULTTAG)
+	if r.Code != http.StatusOK || r.Header().Get("Content-Type") != ociManifestType {
+		t.Fatalf("Expected a manifest, got %d %v", r.Code, r.Header())
+	}
+	digest := r.Header().Get("Docker-Content-Digest")
+	var manifest ociManifest
+	if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Layers) != 1 {
+		t.Fatalf("Expected 1 layer, got %d", len(manifest.Layers))
+	}
+	for _, blob := range []string{manifest.Config.Digest, manifest.Layers[0].Digest} {
+		if r = registryRequest(t, handler, "HEAD", "/v2/"+testImageName+"/blobs/"+blob); r.Code != http.StatusOK {
+			t.Fatalf("Expected the blob %s, got %d", blob, r.Code)
+		}
+	}
+	if r = registryRequest(t, handler, "GET", "/v2/"+testImageName+"/manifests/"+digest); r.Code != http.StatusOK {
+		t.Fatalf("Expected the manifest by its digest %s, got %d", digest, r.Code)
+	}
+
+	if r = registryRequest(t, handler, "GET", "/v2/"+testImageName+"/manifests/unknown"); r.Code != http.StatusNotFound {
+		t.Fatalf("Expected an unknown tag to be %d, got %d", http.StatusNotFound, r.Code)
+	}
+	if r = registryRequest(t, handler, "GET", "/v2/"+testImageName+"/blobs/sha256:../../tags"); r.Code != http.StatusBadRequest {
+		t.Fatalf("Expected an invalid digest to be %d, got %d", http.StatusBadRequest, r.Code)
+	}
+	if r = registryRequest(t, handler, "PUT", "/v2/"+testImageName+"/manifests/other"); r.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected a push to be %d, got %d", http.StatusMethodNotAllowed, r.Code)
+	}
+}
+
+func TestRegistryPrune(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+	archive, err := fakeTar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the child has the layer of its parent along with its own, which is
+	// the same content
+	if err := store.graph.Register(nil, archive, &image.Image{ID: "bar", Parent: testImageID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(testImageName, "child", "bar", false); err != nil {
+		t.Fatal(err)
+	}
+	handler, err := store.RegistryHandler(path.Join(tmp, "registry"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifests := make(map[string]ociManifest)
+	digests := make(map[string]string)
+	for _, tag := range []string{DEFAULTTAG, "child"} {
+		r := registryRequest(t, handler, "GET", "/v2/"+testImageName+"/manifests/"+tag)
+		if r.Code != http.StatusOK {
+			t.Fatalf("Expected the manifest of %s, got %d", tag, r.Code)
+		}
+		var manifest ociManifest
+		if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil {
+			t.Fatal(err)
+		}
+		manifests[tag], digests[tag] = manifest, r.Header().Get("Docker-Content-Digest")
+	}
+	blobExists := func(digest string) bool {
+		return registryRequest(t, handler, "HEAD", "/v2/"+testImageName+"/blobs/"+digest).Code == http.StatusOK
+	}
+
+	handler.Prune("bar")
+	if blobExists(digests["child"]) || blobExists(manifests["child"].Config.Digest) {
+		t.Fatal("Expected the manifest and the config of the deleted image to be pruned")
+	}
+	if !blobExists(digests[DEFAULTTAG]) || !blobExists(manifests[DEFAULTTAG].Layers[0].Digest) {
+		t.Fatal("Expected the blobs of the image left to be kept")
+	}
+	handler.Prune(testImageID)
+	if blobExists(manifests[DEFAULTTAG].Layers[0].Digest) {
+		t.Fatal("Expected the layer no manifest refers to anymore to be pruned")
+	}
+}
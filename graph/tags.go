@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -26,6 +27,9 @@ type TagStore struct {
 	// to a helper type
 	pullingPool map[string]chan struct{}
 	pushingPool map[string]chan struct{}
+	// TransferTLS authenticates the daemon to the others it transfers
+	// images to, nil when it isn't started with --tlsverify
+	TransferTLS *tls.Config
 }
 
 type Repository map[string]string
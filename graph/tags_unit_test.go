@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"github.com/docker/docker/daemon/graphdriver"
 	_ "github.com/docker/docker/daemon/graphdriver/vfs" // import the vfs driver so it is used in the tests
+	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/utils"
 	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
@@ -114,3 +115,97 @@ func TestLookupImage(t *testing.T) {
 		t.Errorf("Expected 1 image, none found")
 	}
 }
+
+func TestImagesPages(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+	// created at the same time as foo, they are ordered by id
+	for _, id := range []string{"bar", "baz", "qux"} {
+		archive, err := fakeTar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.graph.Register(nil, archive, &image.Image{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Set(id, "", id, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eng := engine.New()
+	if err := eng.Register("images", store.CmdImages); err != nil {
+		t.Fatal(err)
+	}
+	job := eng.Job("images")
+	job.SetenvInt("offset", 1)
+	job.SetenvInt("limit", 2)
+	outs, err := job.Stdout.AddListTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, out := range outs.Data {
+		ids = append(ids, out.Get("Id"))
+	}
+	if len(ids) != 2 || ids[0] != "foo" || ids[1] != "baz" {
+		t.Fatalf("Expected the page foo, baz, got %v", ids)
+	}
+}
+
+func TestMissingLayers(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+	// bar on top of foo, baz on top of bar
+	for _, img := range []*image.Image{{ID: "bar", Parent: testImageID}, {ID: "baz", Parent: "bar"}} {
+		archive, err := fakeTar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.graph.Register(nil, archive, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	img, err := store.graph.Get("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probed []string
+	missing, err := store.missingLayers(img, func(id string) (bool, error) {
+		probed = append(probed, id)
+		return id == testImageID, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 2 || missing[0] != "baz" || missing[1] != "bar" {
+		t.Fatalf("Expected baz and bar to be missing, got %v", missing)
+	}
+	if len(probed) != 3 {
+		t.Fatalf("Expected the probes to stop at %s, probed %v", testImageID, probed)
+	}
+
+	missing, err = store.missingLayers(img, func(id string) (bool, error) {
+		return id == "baz", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("Expected nothing to be missing, got %v", missing)
+	}
+}
@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/utils"
+)
+
+// CmdImageTransfer sends an image to another daemon without going through a
+// registry. Only the layers the other daemon doesn't have are sent: the
+// history of the image is walked from its top until a layer the other daemon
+// has, since it then has all the parents of that layer too. The layers above
+// are streamed to the other daemon as an archive of docker save, with the tag
+// of the image, and loaded there.
+// The daemons authenticate each other with the certificates of --tlsverify,
+// the daemon sending the image presenting its own to the other.
+// name is the image to send.
+// host is the other daemon, as tcp://host:port.
+func (s *TagStore) CmdImageTransfer(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s IMAGE HOST\n", job.Name)
+	}
+	name, host := job.Args[0], job.Args[1]
+	if s.TransferTLS == nil {
+		return job.Errorf("Forbidden, images are only transferred between daemons started with --tlsverify")
+	}
+	img, err := s.LookupImage(name)
+	if err != nil {
+		return job.Error(err)
+	}
+	if img == nil {
+		return job.Errorf("No such image: %s", name)
+	}
+	remote, err := newTransferRemote(host, s.TransferTLS)
+	if err != nil {
+		return job.Error(err)
+	}
+	sf := utils.NewStreamFormatter(job.GetenvBool("json"))
+
+	missing, err := s.missingLayers(img, remote.has)
+	if err != nil {
+		return job.Error(err)
+	}
+	job.Stdout.Write(sf.FormatStatus("", "The transfer of %s to %s sends %d layer(s)", name, host, len(missing)))
+
+	tempdir, err := ioutil.TempDir("", "docker-transfer-")
+	if err != nil {
+		return job.Error(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	for _, id := range missing {
+		layerDir := path.Join(tempdir, id)
+		if err := os.Mkdir(layerDir, os.FileMode(0755)); err != nil {
+			return job.Error(err)
+		}
+		if err := exportLayer(job.Eng, id, layerDir); err != nil {
+			return job.Error(err)
+		}
+	}
+	// a name which isn't an ID is tagged on the other daemon too
+	if !strings.HasPrefix(img.ID, name) {
+		repoName, repoTag := parsers.ParseRepositoryTag(name)
+		if repoTag == "" {
+			repoTag = DEFAULTTAG
+		}
+		rootRepoJson, _ := json.Marshal(map[string]Repository{repoName: {repoTag: img.ID}})
+		if err := ioutil.WriteFile(path.Join(tempdir, "repositories"), rootRepoJson, os.FileMode(0644)); err != nil {
+			return job.Error(err)
+		}
+	}
+
+	fs, err := archive.Tar(tempdir, archive.Uncompressed)
+	if err != nil {
+		return job.Error(err)
+	}
+	defer fs.Close()
+
+	log.Debugf("Transferring %s to %s", name, host)
+	if err := remote.load(fs); err != nil {
+		return job.Error(err)
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		job.Stdout.Write(sf.FormatStatus(utils.TruncateID(missing[i]), "Transferred"))
+	}
+	if len(missing) == 0 {
+		job.Stdout.Write(sf.FormatStatus(utils.TruncateID(img.ID), "Already exists"))
+	}
+	return engine.StatusOK
+}
+
+// missingLayers returns the layers of img the function has says another
+// daemon doesn't have, from the top of img down.
+func (s *TagStore) missingLayers(img *image.Image, has func(id string) (bool, error)) ([]string, error) {
+	var missing []string
+	for id := img.ID; id != ""; {
+		exists, err := has(id)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			break
+		}
+		missing = append(missing, id)
+		layer, err := s.graph.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		id = layer.Parent
+	}
+	return missing, nil
+}
+
+// NewTransferTLS returns the configuration authenticating a daemon to the
+// others it transfers images to: it trusts only the daemons with a
+// certificate signed by the CA in the file ca, and presents the certificate
+// in the file cert with the key in the file key.
+func NewTransferTLS(ca, cert, key string) (*tls.Config, error) {
+	file, err := ioutil.ReadFile(ca)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read ca cert %s: %s", ca, err)
+	}
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(file)
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't load X509 key pair: %s. Key encrypted?", err)
+	}
+	return &tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{pair},
+	}, nil
+}
+
+// transferRemote is the API of a daemon images are transferred to.
+type transferRemote struct {
+	addr   string
+	client *http.Client
+}
+
+func newTransferRemote(host string, tlsConfig *tls.Config) (*transferRemote, error) {
+	var (
+		proto = "tcp"
+		addr  = host
+	)
+	if parts := strings.SplitN(host, "://", 2); len(parts) == 2 {
+		proto, addr = parts[0], parts[1]
+	}
+	if proto != "tcp" || addr == "" {
+		return nil, fmt.Errorf("Bad parameter, invalid host %s, must be tcp://host:port", host)
+	}
+	return &transferRemote{
+		addr:   addr,
+		client: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+	}, nil
+}
+
+func (r *transferRemote) url(path string) string {
+	return "https://" + r.addr + path
+}
+
+// has returns whether the remote daemon has the image id.
+func (r *transferRemote) has(id string) (bool, error) {
+	resp, err := r.client.Get(r.url("/images/" + id + "/json"))
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("Error looking up the image %s on %s: %s", utils.TruncateID(id), r.addr, resp.Status)
+}
+
+// load loads the archive of docker save on the remote daemon.
+func (r *transferRemote) load(tarball io.Reader) error {
+	resp, err := r.client.Post(r.url("/images/load"), "application/x-tar", tarball)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Error loading the image on %s: %s", r.addr, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -6,11 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/pkg/units"
 )
 
 func ListVar(values *[]string, names []string, usage string) {
@@ -153,6 +155,51 @@ func ValidatePath(val string) (string, error) {
 	return val, nil
 }
 
+// ValidateThrottleIOps validates a per-device IO/s limit in the form
+// /path/to/device:rate
+func ValidateThrottleIOps(val string) (string, error) {
+	i := strings.LastIndex(val, ":")
+	if i == -1 {
+		return val, fmt.Errorf("bad format for device throttling: %s (expected <device-path>:<number>)", val)
+	}
+	if !filepath.IsAbs(val[:i]) {
+		return val, fmt.Errorf("%s is not an absolute device path", val[:i])
+	}
+	if _, err := strconv.ParseUint(val[i+1:], 10, 64); err != nil {
+		return val, fmt.Errorf("invalid rate for device throttling: %s (expected an unsigned integer)", val[i+1:])
+	}
+	return val, nil
+}
+
+// ValidateHugetlbLimit validates a huge pages limit in the form
+// pagesize:limit, i.e. 2MB:64m
+func ValidateHugetlbLimit(val string) (string, error) {
+	parts := strings.Split(val, ":")
+	if len(parts) != 2 {
+		return val, fmt.Errorf("bad format for hugetlb limit: %s (expected <page-size>:<limit>)", val)
+	}
+	if size, err := units.RAMInBytes(parts[0]); err != nil || size <= 0 {
+		return val, fmt.Errorf("invalid huge page size: %s", parts[0])
+	}
+	if _, err := units.RAMInBytes(parts[1]); err != nil {
+		return val, fmt.Errorf("invalid hugetlb limit: %s", parts[1])
+	}
+	return val, nil
+}
+
+// ValidateNetPrio validates a network priority in the form interface:priority,
+// i.e. eth0:5
+func ValidateNetPrio(val string) (string, error) {
+	i := strings.LastIndex(val, ":")
+	if i <= 0 {
+		return val, fmt.Errorf("bad format for network priority: %s (expected <interface>:<priority>)", val)
+	}
+	if _, err := strconv.ParseUint(val[i+1:], 10, 32); err != nil {
+		return val, fmt.Errorf("invalid network priority: %s", val[i+1:])
+	}
+	return val, nil
+}
+
 func ValidateEnv(val string) (string, error) {
 	arr := strings.Split(val, "=")
 	if len(arr) > 1 {
@@ -27,6 +27,81 @@ func TestValidateIPAddress(t *testing.T) {
 
 }
 
+func TestValidateThrottleIOps(t *testing.T) {
+	valid := []string{
+		"/dev/sda:1000",
+		"/dev/disk/by-id/wwn-0x5000:0",
+	}
+	invalid := []string{
+		"/dev/sda",
+		"/dev/sda:",
+		"/dev/sda:-1",
+		"/dev/sda:fast",
+		"sda:1000",
+	}
+	for _, v := range valid {
+		if _, err := ValidateThrottleIOps(v); err != nil {
+			t.Fatalf("ValidateThrottleIOps(`%s`) should succeed: %s", v, err)
+		}
+	}
+	for _, v := range invalid {
+		if _, err := ValidateThrottleIOps(v); err == nil {
+			t.Fatalf("ValidateThrottleIOps(`%s`) should have failed validation", v)
+		}
+	}
+}
+
+func TestValidateHugetlbLimit(t *testing.T) {
+	valid := []string{
+		"2MB:64m",
+		"1g:2g",
+		"2MB:0",
+	}
+	invalid := []string{
+		"2MB",
+		"2MB:",
+		":64m",
+		"0:64m",
+		"huge:64m",
+		"2MB:lots",
+	}
+	for _, v := range valid {
+		if _, err := ValidateHugetlbLimit(v); err != nil {
+			t.Fatalf("ValidateHugetlbLimit(`%s`) should succeed: %s", v, err)
+		}
+	}
+	for _, v := range invalid {
+		if _, err := ValidateHugetlbLimit(v); err == nil {
+			t.Fatalf("ValidateHugetlbLimit(`%s`) should have failed validation", v)
+		}
+	}
+}
+
+func TestValidateNetPrio(t *testing.T) {
+	valid := []string{
+		"eth0:5",
+		"eth0:1:0",
+		"docker0:0",
+	}
+	invalid := []string{
+		"eth0",
+		"eth0:",
+		":5",
+		"eth0:-1",
+		"eth0:high",
+	}
+	for _, v := range valid {
+		if _, err := ValidateNetPrio(v); err != nil {
+			t.Fatalf("ValidateNetPrio(`%s`) should succeed: %s", v, err)
+		}
+	}
+	for _, v := range invalid {
+		if _, err := ValidateNetPrio(v); err == nil {
+			t.Fatalf("ValidateNetPrio(`%s`) should have failed validation", v)
+		}
+	}
+}
+
 func TestListOpts(t *testing.T) {
 	o := NewListOpts(nil)
 	o.Set("foo")
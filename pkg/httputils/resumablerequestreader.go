@@ -30,6 +30,13 @@ func ResumableRequestReaderWithInitialResponse(c *http.Client, r *http.Request,
 	return &resumableRequestReader{client: c, request: r, maxFailures: maxfail, totalSize: totalsize, currentResponse: initialResponse}
 }
 
+// ResumableRequestReaderFromOffset is like ResumableRequestReaderWithInitialResponse
+// for an initial response whose body starts at offset, i.e. the answer to a
+// range request. Resumed requests then keep asking for the right range.
+func ResumableRequestReaderFromOffset(c *http.Client, r *http.Request, maxfail uint32, totalsize, offset int64, initialResponse *http.Response) io.ReadCloser {
+	return &resumableRequestReader{client: c, request: r, maxFailures: maxfail, totalSize: totalsize, lastRange: offset, currentResponse: initialResponse}
+}
+
 func (r *resumableRequestReader) Read(p []byte) (n int, err error) {
 	if r.client == nil || r.request == nil {
 		return 0, fmt.Errorf("client and request can't be nil\n")
@@ -0,0 +1,58 @@
+package namesgenerator
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"text/template"
+)
+
+// Generator generates names from a template, which can use:
+//
+//	{{.Random}}  a random name, as returned by GetRandomName
+//	{{.Seq}}     a number incremented for every generated name
+//	{{.ID}}      the first 12 characters of the ID being named
+//
+// i.e. "web-{{.Seq}}" or "myproject_{{.Random}}".
+type Generator struct {
+	tmpl *template.Template
+	seq  uint64
+}
+
+type nameFields struct {
+	Random string
+	Seq    uint64
+	ID     string
+}
+
+// NewGenerator returns a Generator for the template format.
+func NewGenerator(format string) (*Generator, error) {
+	tmpl, err := template.New("name").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid name template %s: %s", format, err)
+	}
+	// Catch references to unknown fields now rather than for every name
+	if err := tmpl.Execute(&bytes.Buffer{}, nameFields{}); err != nil {
+		return nil, fmt.Errorf("Invalid name template %s: %s", format, err)
+	}
+	return &Generator{tmpl: tmpl}, nil
+}
+
+// Name returns a new name for id. retry is the number of names already
+// refused for id, a template using only {{.Random}} gets the same digit
+// suffix as GetRandomName does.
+func (g *Generator) Name(id string, retry int) (string, error) {
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	fields := nameFields{
+		Random: GetRandomName(retry),
+		Seq:    atomic.AddUint64(&g.seq, 1),
+		ID:     id,
+	}
+	buf := &bytes.Buffer{}
+	if err := g.tmpl.Execute(buf, fields); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
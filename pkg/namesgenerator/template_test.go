@@ -0,0 +1,44 @@
+package namesgenerator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratorSequence(t *testing.T) {
+	g, err := NewGenerator("web-{{.Seq}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"web-1", "web-2", "web-3"} {
+		name, err := g.Name("abcdef", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != expected {
+			t.Fatalf("Expected %s, got %s", expected, name)
+		}
+	}
+}
+
+func TestGeneratorFields(t *testing.T) {
+	g, err := NewGenerator("proj_{{.ID}}_{{.Random}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := g.Name("0123456789abcdef", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(name, "proj_0123456789ab_") || len(name) <= len("proj_0123456789ab_") {
+		t.Fatalf("Unexpected name %s", name)
+	}
+}
+
+func TestGeneratorInvalidTemplate(t *testing.T) {
+	for _, format := range []string{"web-{{.Seq", "web-{{.Unknown}}"} {
+		if _, err := NewGenerator(format); err == nil {
+			t.Fatalf("Expected %s to be refused", format)
+		}
+	}
+}
@@ -28,6 +28,12 @@ func ParseHost(defaultHost string, defaultUnix, addr string) (string, error) {
 		addr = strings.TrimPrefix(addr, "tcp://")
 	case strings.HasPrefix(addr, "fd://"):
 		return addr, nil
+	case strings.HasPrefix(addr, "ssh://"):
+		// ssh is a client-only transport, the address is handed to ssh as is
+		if strings.TrimPrefix(addr, "ssh://") == "" {
+			return "", fmt.Errorf("Invalid ssh address format: %s", addr)
+		}
+		return addr, nil
 	case addr == "":
 		proto = "unix"
 		addr = defaultUnix
@@ -33,6 +33,12 @@ func TestParseHost(t *testing.T) {
 	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "unix://"); err != nil || addr != "unix:///var/run/docker.sock" {
 		t.Errorf("unix:///var/run/docker.sock -> expected unix:///var/run/docker.sock, got %s", addr)
 	}
+	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "ssh://user@example.com:2222"); err != nil || addr != "ssh://user@example.com:2222" {
+		t.Errorf("ssh://user@example.com:2222 -> expected ssh://user@example.com:2222, got %s", addr)
+	}
+	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "ssh://"); err == nil {
+		t.Errorf("empty ssh:// address expected error return, but err == nil, got %s", addr)
+	}
 	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "udp://127.0.0.1"); err == nil {
 		t.Errorf("udp protocol address expected error return, but err == nil. Got %s", addr)
 	}
@@ -1,10 +1,13 @@
 package sysinfo
 
 import (
+	"bufio"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/docker/libcontainer/cgroups"
 )
@@ -12,8 +15,11 @@ import (
 type SysInfo struct {
 	MemoryLimit            bool
 	SwapLimit              bool
+	CpuCfsBurst            bool
 	IPv4ForwardingDisabled bool
 	AppArmor               bool
+	// MemTotal is the memory of the host in bytes, 0 when unknown
+	MemTotal int64
 }
 
 func New(quiet bool) *SysInfo {
@@ -37,11 +43,41 @@ func New(quiet bool) *SysInfo {
 		}
 	}
 
+	// CFS burst came with Linux 5.14, it is not worth a warning
+	if cgroupCpuMountpoint, err := cgroups.FindCgroupMountpoint("cpu"); err == nil {
+		_, err := os.Stat(path.Join(cgroupCpuMountpoint, "cpu.cfs_burst_us"))
+		sysInfo.CpuCfsBurst = err == nil
+	}
+
 	// Check if AppArmor seems to be enabled on this system.
 	if _, err := os.Stat("/sys/kernel/security/apparmor"); os.IsNotExist(err) {
 		sysInfo.AppArmor = false
 	} else {
 		sysInfo.AppArmor = true
 	}
+
+	sysInfo.MemTotal = readMemTotal("/proc/meminfo")
 	return sysInfo
 }
+
+// readMemTotal returns the MemTotal of the meminfo file in bytes, 0 when it
+// can't be read.
+func readMemTotal(meminfo string) int64 {
+	f, err := os.Open(meminfo)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
@@ -65,6 +65,18 @@ func RAMInBytes(size string) (int64, error) {
 	return parseSize(size, binaryMap)
 }
 
+// RAMLimitInBytes parses a memory limit as RAMInBytes does, the empty
+// string being 0, for a limit left unchanged, and -1 unlimited.
+func RAMLimitInBytes(size string) (int64, error) {
+	switch size {
+	case "":
+		return 0, nil
+	case "-1":
+		return -1, nil
+	}
+	return RAMInBytes(size)
+}
+
 // Parses the human-readable size string into the amount it represents
 func parseSize(sizeStr string, uMap unitMap) (int64, error) {
 	matches := sizeRegex.FindStringSubmatch(sizeStr)
@@ -68,6 +68,17 @@ func TestRAMInBytes(t *testing.T) {
 	assertError(t, RAMInBytes, "32bm")
 }
 
+func TestRAMLimitInBytes(t *testing.T) {
+	assertSuccessEquals(t, 0, RAMLimitInBytes, "")
+	assertSuccessEquals(t, -1, RAMLimitInBytes, "-1")
+	assertSuccessEquals(t, 536870912, RAMLimitInBytes, "536870912")
+	assertSuccessEquals(t, 512*MiB, RAMLimitInBytes, "512m")
+	assertSuccessEquals(t, 2*GiB, RAMLimitInBytes, "2g")
+
+	assertError(t, RAMLimitInBytes, "-2")
+	assertError(t, RAMLimitInBytes, "512 m")
+}
+
 func assertEquals(t *testing.T, expected, actual interface{}) {
 	if expected != actual {
 		t.Errorf("Expected '%v' but got '%v'", expected, actual)
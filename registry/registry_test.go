@@ -2,6 +2,7 @@ package registry
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -86,6 +87,26 @@ func TestGetRemoteImageLayer(t *testing.T) {
 	}
 }
 
+func TestGetRemoteImageLayerFromIgnoredRange(t *testing.T) {
+	r := spawnTestRegistrySession(t)
+	// The mock registry ignores ranges and always sends the whole layer
+	data, offset, err := r.GetRemoteImageLayerFrom(IMAGE_ID, makeURL("/v1/"), TOKEN, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if offset != 0 {
+		t.Fatalf("Expected the layer to start at 0, got %d", offset)
+	}
+	layer, err := ioutil.ReadAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer) != len(testLayers[IMAGE_ID]["layer"]) {
+		t.Fatalf("Expected the whole layer, got %d bytes", len(layer))
+	}
+}
+
 func TestGetRemoteTags(t *testing.T) {
 	r := spawnTestRegistrySession(t)
 	tags, err := r.GetRemoteTags([]string{makeURL("/v1/")}, REPO, TOKEN)
@@ -152,6 +152,14 @@ func (r *Session) GetRemoteImageJSON(imgID, registry string, token []string) ([]
 }
 
 func (r *Session) GetRemoteImageLayer(imgID, registry string, token []string, imgSize int64) (io.ReadCloser, error) {
+	layer, _, err := r.GetRemoteImageLayerFrom(imgID, registry, token, imgSize, 0)
+	return layer, err
+}
+
+// GetRemoteImageLayerFrom fetches the layer of imgID starting at offset. The
+// registry may ignore the range and send the whole layer, the returned offset
+// is where the content of the reader actually starts.
+func (r *Session) GetRemoteImageLayerFrom(imgID, registry string, token []string, imgSize, offset int64) (io.ReadCloser, int64, error) {
 	var (
 		retries  = 5
 		client   *http.Client
@@ -161,16 +169,20 @@ func (r *Session) GetRemoteImageLayer(imgID, registry string, token []string, im
 
 	req, err := r.reqFactory.NewRequest("GET", imageURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Error while getting from the server: %s\n", err)
+		return nil, 0, fmt.Errorf("Error while getting from the server: %s\n", err)
 	}
 	setTokenAuth(req, token)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 	for i := 1; i <= retries; i++ {
 		res, client, err = r.doRequest(req)
 		if err != nil {
-			res.Body.Close()
+			if res != nil {
+				res.Body.Close()
+			}
 			if i == retries {
-				return nil, fmt.Errorf("Server error: Status %d while fetching image layer (%s)",
-					res.StatusCode, imgID)
+				return nil, 0, fmt.Errorf("Server error: %s while fetching image layer (%s)", err, imgID)
 			}
 			time.Sleep(time.Duration(i) * 5 * time.Second)
 			continue
@@ -178,18 +190,23 @@ func (r *Session) GetRemoteImageLayer(imgID, registry string, token []string, im
 		break
 	}
 
-	if res.StatusCode != 200 {
+	switch res.StatusCode {
+	case 200:
+		offset = 0
+	case 206:
+		log.Debugf("resuming download of %s at %d", imgID, offset)
+	default:
 		res.Body.Close()
-		return nil, fmt.Errorf("Server error: Status %d while fetching image layer (%s)",
+		return nil, 0, fmt.Errorf("Server error: Status %d while fetching image layer (%s)",
 			res.StatusCode, imgID)
 	}
 
-	if res.Header.Get("Accept-Ranges") == "bytes" && imgSize > 0 {
+	if (res.Header.Get("Accept-Ranges") == "bytes" || res.StatusCode == 206) && imgSize > 0 {
 		log.Debugf("server supports resume")
-		return httputils.ResumableRequestReaderWithInitialResponse(client, req, 5, imgSize, res), nil
+		return httputils.ResumableRequestReaderFromOffset(client, req, 5, imgSize, offset, res), offset, nil
 	}
 	log.Debugf("server doesn't support resume")
-	return res.Body, nil
+	return res.Body, offset, nil
 }
 
 func (r *Session) GetRemoteTags(registries []string, repository string, token []string) (map[string]string, error) {
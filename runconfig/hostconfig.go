@@ -30,6 +30,37 @@ type RestartPolicy struct {
 	MaximumRetryCount int
 }
 
+// WatchdogPolicy is the action the daemon takes when the container does not
+// send WATCHDOG=1 on its notify socket for Interval seconds.
+type WatchdogPolicy struct {
+	Interval int
+	Action   string
+}
+
+// VolumesPolicy is what happens to the anonymous volumes of a container when
+// it is removed: Name is remove or keep. The volumes kept are removed once
+// they have been orphaned for KeepDays, 0 keeps them until removed by hand.
+type VolumesPolicy struct {
+	Name     string
+	KeepDays int
+}
+
+// Hooks are commands run on the host by the daemon at the lifecycle points
+// of a container.
+type Hooks struct {
+	Prestart  []string
+	Poststart []string
+	Poststop  []string
+}
+
+// HostService is a service of the host started with a container and stopped
+// with it: Kind is unit for a systemd unit, Name being the unit, or script,
+// Name being the path of a script run with start or stop as argument.
+type HostService struct {
+	Kind string
+	Name string
+}
+
 type HostConfig struct {
 	Binds           []string
 	ContainerIDFile string
@@ -46,20 +77,75 @@ type HostConfig struct {
 	CapAdd          []string
 	CapDrop         []string
 	RestartPolicy   RestartPolicy
+	VolumesPolicy   VolumesPolicy // Empty for the policy of the daemon
+	Hooks           Hooks
+	HostServices    []HostService
+	Notify          bool // Mount a notify socket for the container to signal it is ready
+	Watchdog        WatchdogPolicy
+	// Per-device IO/s limits in the form /path/to/device:rate, the
+	// device paths are resolved to major:minor by the daemon.
+	BlkioDeviceReadIOps  []string
+	BlkioDeviceWriteIOps []string
+	BlkioWeight          int64  // Relative weight of the block IO (10-1000), 0 for the default
+	PidsLimit            int64  // Maximum number of tasks, -1 for unlimited
+	CpuQuota             int64  // CPU time of the container per CFS period in microseconds, -1 for unlimited
+	CpuPeriod            int64  // Length of the CFS period in microseconds
+	CpuBurst             int64  // Unused CPU quota the container accumulates for bursts in microseconds, at most the quota
+	CpuRtRuntime         int64  // Real-time CPU time of the container per period in microseconds
+	CpuRtPeriod          int64  // Length of the real-time period in microseconds
+	CpusetMems           string // Memory nodes in which to allow allocations (0-3, 0,1)
+	CpusetCpuExclusive   bool   // Keep the cpus of the cpuset from the other containers
+	CpusetMemExclusive   bool   // Keep the memory nodes of the cpuset from the other containers
+	MemoryReservation    int64  // Memory soft limit in bytes, defaults to the memory limit
+	KernelMemory         int64  // Kernel memory limit in bytes
+	KernelMemoryTCP      int64  // Limit of the kernel memory used by TCP buffers in bytes
+	MemorySwappiness     *int64 // Tendency to swap out the memory of the container (0-100), nil for the host default
+	OomKillDisable       bool   // Pause the container instead of killing its processes when it runs out of memory
+	// Huge pages limits in the form pagesize:limit
+	HugetlbLimits []string
+	NetClsClassid string // tc class of the container's traffic, i.e. 10:1
+	// Priority of the container's traffic in the form interface:priority
+	NetPrioIfpriomap []string
+	// Containers whose NUMA nodes the container is placed on, and
+	// containers whose cpus it never shares
+	Affinity     []string
+	AntiAffinity []string
 }
 
 func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	hostConfig := &HostConfig{
-		ContainerIDFile: job.Getenv("ContainerIDFile"),
-		Privileged:      job.GetenvBool("Privileged"),
-		PublishAllPorts: job.GetenvBool("PublishAllPorts"),
-		NetworkMode:     NetworkMode(job.Getenv("NetworkMode")),
+		ContainerIDFile:   job.Getenv("ContainerIDFile"),
+		Privileged:        job.GetenvBool("Privileged"),
+		PublishAllPorts:   job.GetenvBool("PublishAllPorts"),
+		Notify:            job.GetenvBool("Notify"),
+		OomKillDisable:    job.GetenvBool("OomKillDisable"),
+		NetworkMode:       NetworkMode(job.Getenv("NetworkMode")),
+		BlkioWeight:       job.GetenvInt64("BlkioWeight"),
+		PidsLimit:         job.GetenvInt64("PidsLimit"),
+		CpuQuota:          job.GetenvInt64("CpuQuota"),
+		CpuPeriod:         job.GetenvInt64("CpuPeriod"),
+		CpuBurst:          job.GetenvInt64("CpuBurst"),
+		CpuRtRuntime:      job.GetenvInt64("CpuRtRuntime"),
+		CpuRtPeriod:       job.GetenvInt64("CpuRtPeriod"),
+		CpusetMems:        job.Getenv("CpusetMems"),
+		MemoryReservation: job.GetenvInt64("MemoryReservation"),
+		KernelMemory:      job.GetenvInt64("KernelMemory"),
+		KernelMemoryTCP:   job.GetenvInt64("KernelMemoryTCP"),
+		NetClsClassid:     job.Getenv("NetClsClassid"),
+
+		CpusetCpuExclusive: job.GetenvBool("CpusetCpuExclusive"),
+		CpusetMemExclusive: job.GetenvBool("CpusetMemExclusive"),
 	}
 
 	job.GetenvJson("LxcConf", &hostConfig.LxcConf)
 	job.GetenvJson("PortBindings", &hostConfig.PortBindings)
 	job.GetenvJson("Devices", &hostConfig.Devices)
 	job.GetenvJson("RestartPolicy", &hostConfig.RestartPolicy)
+	job.GetenvJson("VolumesPolicy", &hostConfig.VolumesPolicy)
+	job.GetenvJson("Hooks", &hostConfig.Hooks)
+	job.GetenvJson("HostServices", &hostConfig.HostServices)
+	job.GetenvJson("Watchdog", &hostConfig.Watchdog)
+	job.GetenvJson("MemorySwappiness", &hostConfig.MemorySwappiness)
 	if Binds := job.GetenvList("Binds"); Binds != nil {
 		hostConfig.Binds = Binds
 	}
@@ -81,6 +167,24 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	if CapDrop := job.GetenvList("CapDrop"); CapDrop != nil {
 		hostConfig.CapDrop = CapDrop
 	}
+	if BlkioDeviceReadIOps := job.GetenvList("BlkioDeviceReadIOps"); BlkioDeviceReadIOps != nil {
+		hostConfig.BlkioDeviceReadIOps = BlkioDeviceReadIOps
+	}
+	if BlkioDeviceWriteIOps := job.GetenvList("BlkioDeviceWriteIOps"); BlkioDeviceWriteIOps != nil {
+		hostConfig.BlkioDeviceWriteIOps = BlkioDeviceWriteIOps
+	}
+	if HugetlbLimits := job.GetenvList("HugetlbLimits"); HugetlbLimits != nil {
+		hostConfig.HugetlbLimits = HugetlbLimits
+	}
+	if NetPrioIfpriomap := job.GetenvList("NetPrioIfpriomap"); NetPrioIfpriomap != nil {
+		hostConfig.NetPrioIfpriomap = NetPrioIfpriomap
+	}
+	if Affinity := job.GetenvList("Affinity"); Affinity != nil {
+		hostConfig.Affinity = Affinity
+	}
+	if AntiAffinity := job.GetenvList("AntiAffinity"); AntiAffinity != nil {
+		hostConfig.AntiAffinity = AntiAffinity
+	}
 
 	return hostConfig
 }
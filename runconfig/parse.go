@@ -11,6 +11,7 @@ import (
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/utils"
@@ -23,6 +24,7 @@ var (
 	ErrConflictNetworkHostname            = fmt.Errorf("Conflicting options: -h and the network mode (--net)")
 	ErrConflictHostNetworkAndLinks        = fmt.Errorf("Conflicting options: --net=host can't be used with links. This would result in undefined behavior.")
 	ErrConflictRestartPolicyAndAutoRemove = fmt.Errorf("Conflicting options: --restart and --rm")
+	ErrInvalidMemorySwappiness            = fmt.Errorf("Invalid value for --memory-swappiness: must be between 0 and 100")
 )
 
 //FIXME Only used in tests
@@ -56,10 +58,24 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flEnvFile     = opts.NewListOpts(nil)
 		flCapAdd      = opts.NewListOpts(nil)
 		flCapDrop     = opts.NewListOpts(nil)
+		flPrestart    = opts.NewListOpts(nil)
+		flPoststart   = opts.NewListOpts(nil)
+		flPoststop    = opts.NewListOpts(nil)
+		flHostService = opts.NewListOpts(nil)
+
+		flDeviceReadIOps  = opts.NewListOpts(opts.ValidateThrottleIOps)
+		flDeviceWriteIOps = opts.NewListOpts(opts.ValidateThrottleIOps)
+		flHugetlbLimits   = opts.NewListOpts(opts.ValidateHugetlbLimit)
+		flNetPrio         = opts.NewListOpts(opts.ValidateNetPrio)
+		flAffinity        = opts.NewListOpts(nil)
+		flAntiAffinity    = opts.NewListOpts(nil)
 
 		flAutoRemove      = cmd.Bool([]string{"#rm", "-rm"}, false, "Automatically remove the container when it exits (incompatible with -d)")
 		flDetach          = cmd.Bool([]string{"d", "-detach"}, false, "Detached mode: run container in the background and print new container ID")
 		flNetwork         = cmd.Bool([]string{"#n", "#-networking"}, true, "Enable networking for this container")
+		flWatchdog        = cmd.Int([]string{"-watchdog"}, 0, "Seconds within which the container must send WATCHDOG=1 on its notify socket, 0 to disable")
+		flWatchdogAction  = cmd.String([]string{"-watchdog-action"}, "restart", "Action when the watchdog expires (restart, event, signal:SIGNAL)")
+		flNotify          = cmd.Bool([]string{"-notify"}, false, "Mount a notify socket in the container, given in NOTIFY_SOCKET, on which it sends READY=1 once it is ready")
 		flPrivileged      = cmd.Bool([]string{"#privileged", "-privileged"}, false, "Give extended privileges to this container")
 		flPublishAll      = cmd.Bool([]string{"P", "-publish-all"}, false, "Publish all exposed ports to the host interfaces")
 		flStdin           = cmd.Bool([]string{"i", "-interactive"}, false, "Keep STDIN open even if not attached")
@@ -68,15 +84,31 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flEntrypoint      = cmd.String([]string{"#entrypoint", "-entrypoint"}, "", "Overwrite the default ENTRYPOINT of the image")
 		flHostname        = cmd.String([]string{"h", "-hostname"}, "", "Container host name")
 		flMemoryString    = cmd.String([]string{"m", "-memory"}, "", "Memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+		flKernelMemory    = cmd.String([]string{"-kernel-memory"}, "", "Kernel memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+		flKernelMemoryTCP = cmd.String([]string{"-kernel-memory-tcp"}, "", "Kernel memory limit for TCP buffers (format: <number><optional unit>, where unit = b, k, m or g)")
+		flOomKillDisable  = cmd.Bool([]string{"-oom-kill-disable"}, false, "Pause the container instead of killing its processes when it runs out of memory")
 		flUser            = cmd.String([]string{"u", "-user"}, "", "Username or UID")
 		flWorkingDir      = cmd.String([]string{"w", "-workdir"}, "", "Working directory inside the container")
 		flCpuShares       = cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
+		flCpuQuota        = cmd.Int64([]string{"-cpu-quota"}, 0, "CPU time the container gets per CFS period in microseconds, -1 for unlimited")
+		flCpuPeriod       = cmd.Int64([]string{"-cpu-period"}, 0, "Length of the CFS period in microseconds (1000-1000000)")
+		flCpuBurst        = cmd.Int64([]string{"-cpu-burst"}, 0, "Unused CPU quota the container accumulates for bursts in microseconds, at most --cpu-quota")
+		flCpuRtRuntime    = cmd.Int64([]string{"-cpu-rt-runtime"}, 0, "(native exec-driver only) Real-time CPU time the container gets per period in microseconds")
+		flCpuRtPeriod     = cmd.Int64([]string{"-cpu-rt-period"}, 0, "(native exec-driver only) Length of the real-time period in microseconds (1000-1000000)")
+		flSwappiness      = cmd.Int64([]string{"-memory-swappiness"}, -1, "Tendency to swap out the memory of the container (0-100), -1 for the host default")
 		flCpuset          = cmd.String([]string{"-cpuset"}, "", "CPUs in which to allow execution (0-3, 0,1)")
+		flCpusetMems      = cmd.String([]string{"-cpuset-mems"}, "", "Memory nodes in which to allow allocations (0-3, 0,1)")
+		flCpuExclusive    = cmd.Bool([]string{"-cpuset-cpu-exclusive"}, false, "(native exec-driver only) Keep the CPUs of --cpuset from the other containers")
+		flMemExclusive    = cmd.Bool([]string{"-cpuset-mem-exclusive"}, false, "(native exec-driver only) Keep the memory nodes of --cpuset-mems from the other containers")
+		flBlkioWeight     = cmd.Int64([]string{"-blkio-weight"}, 0, "Relative weight of the block IO of the container (10-1000)")
 		flNetMode         = cmd.String([]string{"-net"}, "bridge", "Set the Network mode for the container\n'bridge': creates a new network stack for the container on the docker bridge\n'none': no networking for this container\n'container:<name|id>': reuses another container network stack\n'host': use the host network stack inside the container.  Note: the host mode gives the container full access to local system services such as D-bus and is therefore considered insecure.")
 		flRestartPolicy   = cmd.String([]string{"-restart"}, "", "Restart policy to apply when a container exits (no, on-failure, always)")
+		flVolumesPolicy   = cmd.String([]string{"-volumes-policy"}, "", "What happens to the anonymous volumes when the container is removed (remove, keep, keep:DAYS), default to the policy of the daemon")
 		// For documentation purpose
 		_ = cmd.Bool([]string{"#sig-proxy", "-sig-proxy"}, true, "Proxy received signals to the process (even in non-TTY mode). SIGCHLD, SIGSTOP, and SIGKILL are not proxied.")
 		_ = cmd.String([]string{"#name", "-name"}, "", "Assign a name to the container")
+		_ = cmd.String([]string{"-result-file"}, "", "Write the ID, name, IP address and published ports of the container as JSON to the file")
+		_ = cmd.String([]string{"-format"}, "", "Print the ID, name, IP address and published ports of the container as JSON (json) or with the given go template, in detached mode")
 	)
 
 	cmd.Var(&flAttach, []string{"a", "-attach"}, "Attach to STDIN, STDOUT or STDERR.")
@@ -96,6 +128,18 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 	cmd.Var(&flCapAdd, []string{"-cap-add"}, "Add Linux capabilities")
 	cmd.Var(&flCapDrop, []string{"-cap-drop"}, "Drop Linux capabilities")
 
+	cmd.Var(&flPrestart, []string{"-prestart-hook"}, "Run a command on the host before the container starts, a failure aborts the start")
+	cmd.Var(&flPoststart, []string{"-poststart-hook"}, "Run a command on the host after the container started")
+	cmd.Var(&flPoststop, []string{"-poststop-hook"}, "Run a command on the host after the container stopped")
+	cmd.Var(&flHostService, []string{"-host-service"}, "Start a service of the host with the container and stop it with it (unit:NAME for a systemd unit, script:PATH for a script run with start or stop)")
+
+	cmd.Var(&flDeviceReadIOps, []string{"-device-read-iops"}, "Limit read rate (IO per second) from a device (e.g. --device-read-iops=/dev/sda:1000)")
+	cmd.Var(&flDeviceWriteIOps, []string{"-device-write-iops"}, "Limit write rate (IO per second) to a device (e.g. --device-write-iops=/dev/sda:1000)")
+	cmd.Var(&flHugetlbLimits, []string{"-hugetlb-limit"}, "Limit huge pages usage for a page size (e.g. --hugetlb-limit=2MB:64m)")
+	cmd.Var(&flNetPrio, []string{"-net-prio"}, "Set the priority of the container's traffic on a host network interface (e.g. --net-prio=eth0:5)")
+	cmd.Var(&flAffinity, []string{"-affinity"}, "Place the container on the NUMA nodes of a running container")
+	cmd.Var(&flAntiAffinity, []string{"-anti-affinity"}, "Never share cpus with a running container")
+
 	if err := cmd.Parse(args); err != nil {
 		return nil, nil, cmd, err
 	}
@@ -144,6 +188,39 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flMemory = parsedMemory
 	}
 
+	var kernelMemory int64
+	if *flKernelMemory != "" {
+		parsedKernelMemory, err := units.RAMInBytes(*flKernelMemory)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		kernelMemory = parsedKernelMemory
+	}
+
+	var kernelMemoryTCP int64
+	if *flKernelMemoryTCP != "" {
+		parsedKernelMemoryTCP, err := units.RAMInBytes(*flKernelMemoryTCP)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		kernelMemoryTCP = parsedKernelMemoryTCP
+	}
+
+	if *flWatchdog < 0 {
+		return nil, nil, cmd, fmt.Errorf("Invalid watchdog interval: %d", *flWatchdog)
+	}
+	if _, err := ParseWatchdogAction(*flWatchdogAction); err != nil {
+		return nil, nil, cmd, err
+	}
+
+	var swappiness *int64
+	if *flSwappiness != -1 {
+		if *flSwappiness < 0 || *flSwappiness > 100 {
+			return nil, nil, cmd, ErrInvalidMemorySwappiness
+		}
+		swappiness = flSwappiness
+	}
+
 	var binds []string
 	// add any bind targets to the list of container volumes
 	for bind := range flVolumes.GetMap() {
@@ -217,6 +294,15 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		deviceMappings = append(deviceMappings, deviceMapping)
 	}
 
+	hostServices := []HostService{}
+	for _, spec := range flHostService.GetAll() {
+		service, err := ParseHostService(spec)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		hostServices = append(hostServices, service)
+	}
+
 	// collect all the environment variables for the container
 	envVariables := []string{}
 	for _, ef := range flEnvFile.GetAll() {
@@ -239,6 +325,11 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		return nil, nil, cmd, err
 	}
 
+	volumesPolicy, err := ParseVolumesPolicy(*flVolumesPolicy)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	if *flAutoRemove && (restartPolicy.Name == "always" || restartPolicy.Name == "on-failure") {
 		return nil, nil, cmd, ErrConflictRestartPolicyAndAutoRemove
 	}
@@ -271,6 +362,8 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		ContainerIDFile: *flContainerIDFile,
 		LxcConf:         lxcConf,
 		Privileged:      *flPrivileged,
+		Notify:          *flNotify,
+		Watchdog:        WatchdogPolicy{Interval: *flWatchdog, Action: *flWatchdogAction},
 		PortBindings:    portBindings,
 		Links:           flLinks.GetAll(),
 		PublishAllPorts: *flPublishAll,
@@ -282,6 +375,33 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		CapAdd:          flCapAdd.GetAll(),
 		CapDrop:         flCapDrop.GetAll(),
 		RestartPolicy:   restartPolicy,
+		VolumesPolicy:   volumesPolicy,
+		Hooks: Hooks{
+			Prestart:  flPrestart.GetAll(),
+			Poststart: flPoststart.GetAll(),
+			Poststop:  flPoststop.GetAll(),
+		},
+		HostServices: hostServices,
+
+		BlkioWeight:          *flBlkioWeight,
+		BlkioDeviceReadIOps:  flDeviceReadIOps.GetAll(),
+		BlkioDeviceWriteIOps: flDeviceWriteIOps.GetAll(),
+		HugetlbLimits:        flHugetlbLimits.GetAll(),
+		CpuQuota:             *flCpuQuota,
+		CpuPeriod:            *flCpuPeriod,
+		CpuBurst:             *flCpuBurst,
+		CpuRtRuntime:         *flCpuRtRuntime,
+		CpuRtPeriod:          *flCpuRtPeriod,
+		CpusetMems:           *flCpusetMems,
+		CpusetCpuExclusive:   *flCpuExclusive,
+		CpusetMemExclusive:   *flMemExclusive,
+		KernelMemory:         kernelMemory,
+		KernelMemoryTCP:      kernelMemoryTCP,
+		MemorySwappiness:     swappiness,
+		OomKillDisable:       *flOomKillDisable,
+		NetPrioIfpriomap:     flNetPrio.GetAll(),
+		Affinity:             flAffinity.GetAll(),
+		AntiAffinity:         flAntiAffinity.GetAll(),
 	}
 
 	if sysInfo != nil && flMemory > 0 && !sysInfo.SwapLimit {
@@ -296,6 +416,26 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 	return config, hostConfig, cmd, nil
 }
 
+// ParseWatchdogAction validates a watchdog action: restart, event or
+// signal:SIGNAL, and returns the signal to send for the latter
+func ParseWatchdogAction(action string) (int, error) {
+	switch {
+	case action == "restart" || action == "event":
+		return 0, nil
+	case strings.HasPrefix(action, "signal:"):
+		name := strings.TrimPrefix(action, "signal:")
+		// The largest legal signal is 31, so let's parse on 5 bits
+		if sig, err := strconv.ParseUint(name, 10, 5); err == nil && sig != 0 {
+			return int(sig), nil
+		}
+		if sig := signal.SignalMap[strings.TrimPrefix(name, "SIG")]; sig != 0 {
+			return int(sig), nil
+		}
+		return 0, fmt.Errorf("Invalid watchdog signal: %s", name)
+	}
+	return 0, fmt.Errorf("Invalid watchdog action %s, must be restart, event or signal:SIGNAL", action)
+}
+
 // parseRestartPolicy returns the parsed policy or an error indicating what is incorrect
 func parseRestartPolicy(policy string) (RestartPolicy, error) {
 	p := RestartPolicy{}
@@ -336,6 +476,65 @@ func parseRestartPolicy(policy string) (RestartPolicy, error) {
 	return p, nil
 }
 
+// ParseVolumesPolicy parses a volumes policy: remove, keep or keep:DAYS.
+func ParseVolumesPolicy(policy string) (VolumesPolicy, error) {
+	var (
+		p     = VolumesPolicy{}
+		parts = strings.SplitN(policy, ":", 2)
+	)
+	switch parts[0] {
+	case "":
+		if policy != "" {
+			return p, fmt.Errorf("invalid volumes policy %s", policy)
+		}
+		return p, nil
+	case "remove":
+		if len(parts) == 2 {
+			return p, fmt.Errorf("a number of days is not valid with the volumes policy \"remove\"")
+		}
+	case "keep":
+		if len(parts) == 2 {
+			days, err := strconv.Atoi(parts[1])
+			if err != nil || days <= 0 {
+				return p, fmt.Errorf("invalid number of days to keep the volumes: %s", parts[1])
+			}
+			p.KeepDays = days
+		}
+	default:
+		return p, fmt.Errorf("invalid volumes policy %s", policy)
+	}
+	p.Name = parts[0]
+	return p, nil
+}
+
+// ParseHostService parses a host service: unit:NAME or script:PATH, the
+// path of a script being absolute.
+func ParseHostService(spec string) (HostService, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return HostService{}, fmt.Errorf("Invalid host service %s, must be unit:NAME or script:PATH", spec)
+	}
+	service := HostService{Kind: parts[0], Name: parts[1]}
+	return service, ValidateHostService(service)
+}
+
+// ValidateHostService checks the kind and the name of a host service.
+func ValidateHostService(service HostService) error {
+	switch service.Kind {
+	case "unit":
+		if service.Name == "" || strings.HasPrefix(service.Name, "-") {
+			return fmt.Errorf("Invalid host service unit %q", service.Name)
+		}
+	case "script":
+		if !path.IsAbs(service.Name) {
+			return fmt.Errorf("Invalid host service script %q, must be an absolute path", service.Name)
+		}
+	default:
+		return fmt.Errorf("Invalid host service kind %s, must be unit or script", service.Kind)
+	}
+	return nil
+}
+
 // options will come in the format of name.key=value or name.option
 func parseDriverOpts(opts opts.ListOpts) (map[string][]string, error) {
 	out := make(map[string][]string, len(opts.GetAll()))
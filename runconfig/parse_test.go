@@ -48,3 +48,91 @@ func TestNetHostname(t *testing.T) {
 		t.Fatalf("Expected error ErrConflictNetworkHostname, got: %s", err)
 	}
 }
+
+func TestParseMemorySwappiness(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--memory-swappiness=10", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.MemorySwappiness == nil || *hostConfig.MemorySwappiness != 10 {
+		t.Fatalf("Expected a memory swappiness of 10, got %v", hostConfig.MemorySwappiness)
+	}
+
+	if _, hostConfig, _, _ = Parse([]string{"img", "cmd"}, nil); hostConfig.MemorySwappiness != nil {
+		t.Fatal("Expected the memory swappiness to be left to the host default")
+	}
+
+	for _, swappiness := range []string{"101", "-2"} {
+		if _, _, _, err := Parse([]string{"--memory-swappiness=" + swappiness, "img", "cmd"}, nil); err != ErrInvalidMemorySwappiness {
+			t.Fatalf("Expected %s to be an invalid memory swappiness, got %v", swappiness, err)
+		}
+	}
+}
+
+func TestParseWatchdogAction(t *testing.T) {
+	for action, expected := range map[string]int{
+		"restart":        0,
+		"event":          0,
+		"signal:SIGUSR1": 10,
+		"signal:HUP":     1,
+		"signal:15":      15,
+	} {
+		sig, err := ParseWatchdogAction(action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig != expected {
+			t.Fatalf("Expected %s to send signal %d, got %d", action, expected, sig)
+		}
+	}
+
+	for _, action := range []string{"", "stop", "signal:", "signal:NOPE", "signal:0", "signal:32"} {
+		if _, err := ParseWatchdogAction(action); err == nil {
+			t.Fatalf("Expected %q to be an invalid watchdog action", action)
+		}
+	}
+}
+
+func TestParseVolumesPolicy(t *testing.T) {
+	for policy, expected := range map[string]VolumesPolicy{
+		"":        {},
+		"remove":  {Name: "remove"},
+		"keep":    {Name: "keep"},
+		"keep:30": {Name: "keep", KeepDays: 30},
+	} {
+		p, err := ParseVolumesPolicy(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != expected {
+			t.Fatalf("Expected %q to be %+v, got %+v", policy, expected, p)
+		}
+	}
+
+	for _, policy := range []string{":", "delete", "remove:3", "keep:", "keep:0", "keep:-1", "keep:1d"} {
+		if _, err := ParseVolumesPolicy(policy); err == nil {
+			t.Fatalf("Expected %q to be an invalid volumes policy", policy)
+		}
+	}
+}
+
+func TestParseHostService(t *testing.T) {
+	for spec, expected := range map[string]HostService{
+		"unit:firewall-port@8080.service": {Kind: "unit", Name: "firewall-port@8080.service"},
+		"script:/usr/local/bin/route":     {Kind: "script", Name: "/usr/local/bin/route"},
+	} {
+		service, err := ParseHostService(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if service != expected {
+			t.Fatalf("Expected %q to be %+v, got %+v", spec, expected, service)
+		}
+	}
+
+	for _, spec := range []string{"", "firewall.service", "unit:", "unit:--all", "script:route", "timer:backup.timer"} {
+		if _, err := ParseHostService(spec); err == nil {
+			t.Fatalf("Expected %q to be an invalid host service", spec)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -82,6 +83,9 @@ type JSONMessage struct {
 	Time            int64         `json:"time,omitempty"`
 	Error           *JSONError    `json:"errorDetail,omitempty"`
 	ErrorMessage    string        `json:"error,omitempty"` //deprecated
+	// Attributes are the details of an event, such as the old and new
+	// values of the limits an update changed
+	Attributes map[string]string `json:"attributes,omitempty"`
 }
 
 func (jm *JSONMessage) Display(out io.Writer, isTerminal bool) error {
@@ -114,12 +118,27 @@ func (jm *JSONMessage) Display(out io.Writer, isTerminal bool) error {
 		fmt.Fprintf(out, "%s %s%s", jm.Status, jm.ProgressMessage, endl)
 	} else if jm.Stream != "" {
 		fmt.Fprintf(out, "%s%s", jm.Stream, endl)
+	} else if len(jm.Attributes) != 0 {
+		fmt.Fprintf(out, "%s (%s)%s\n", jm.Status, jm.attributes(), endl)
 	} else {
 		fmt.Fprintf(out, "%s%s\n", jm.Status, endl)
 	}
 	return nil
 }
 
+// attributes returns the attributes of jm as KEY=VALUE, sorted by key.
+func (jm *JSONMessage) attributes() string {
+	keys := make([]string, 0, len(jm.Attributes))
+	for key := range jm.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = key + "=" + jm.Attributes[key]
+	}
+	return strings.Join(keys, ", ")
+}
+
 func DisplayJSONMessagesStream(in io.Reader, out io.Writer, terminalFd uintptr, isTerminal bool) error {
 	var (
 		dec  = json.NewDecoder(in)
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -36,3 +37,23 @@ func TestProgress(t *testing.T) {
 		t.Fatalf("Expected %q, got %q", expected, jp4.String())
 	}
 }
+
+func TestDisplayAttributes(t *testing.T) {
+	jm := JSONMessage{
+		Status: "update",
+		ID:     "4386fb97867d",
+		From:   "busybox",
+		Attributes: map[string]string{
+			"memory.limit_in_bytes": "67108864->134217728",
+			"cpu.shares":            "1024->512",
+		},
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := jm.Display(buf, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := "4386fb97867d: (from busybox) update (cpu.shares=1024->512, memory.limit_in_bytes=67108864->134217728)\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+}